@@ -3,6 +3,7 @@ package adapter
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/Ning0612/Syncrules/internal/domain"
 )
@@ -10,9 +11,14 @@ import (
 // Adapter defines the interface for storage backends
 // All implementations must handle path normalization internally
 // and return domain-level errors for consistent error handling
+// Paths are slash-separated and relative to the adapter's root; paths that
+// escape the root return domain.ErrPermissionDenied
+// Operations on a cancelled context return an error wrapping ctx.Err()
+// The adaptertest package checks implementations against this contract
 type Adapter interface {
 	// List returns all files and directories under the given path
-	// Path should be relative to the adapter's root
+	// Path should be relative to the adapter's root; returned paths are
+	// cleaned and include it (List("a") yields "a/b", not "b")
 	// Returns domain.ErrNotFound if path doesn't exist
 	// Returns domain.ErrNotDirectory if path is a file
 	List(ctx context.Context, path string) ([]domain.FileInfo, error)
@@ -25,12 +31,14 @@ type Adapter interface {
 
 	// Write creates or overwrites a file
 	// Parent directories should be created automatically
+	// The file is replaced atomically: if r fails, the old content remains
 	// Returns domain.ErrPermissionDenied if write not allowed
 	Write(ctx context.Context, path string, r io.Reader) error
 
 	// Delete removes a file or empty directory
 	// Returns domain.ErrNotFound if path doesn't exist
-	// For non-empty directories, caller must delete contents first
+	// For non-empty directories, caller must delete contents first;
+	// they return domain.ErrDirectoryNotEmpty and nothing is removed
 	Delete(ctx context.Context, path string) error
 
 	// Stat returns metadata for a single path
@@ -42,12 +50,22 @@ type Adapter interface {
 	Mkdir(ctx context.Context, path string) error
 
 	// Exists checks if a path exists
+	// A missing path is (false, nil), not an error
 	Exists(ctx context.Context, path string) (bool, error)
 
 	// Close releases any resources held by the adapter
 	Close() error
 }
 
+// ModTimeSetter is implemented by adapters that can set modification times
+// The sync service uses it to give each copy its source's mtime, so later
+// comparisons see the files as identical
+type ModTimeSetter interface {
+	// SetModTime sets the modification time of a file or directory
+	// Returns domain.ErrNotFound if path doesn't exist
+	SetModTime(ctx context.Context, path string, modTime time.Time) error
+}
+
 // AdapterFactory creates adapters for a given transport configuration
 type AdapterFactory interface {
 	// Create returns an adapter for the given transport and root path
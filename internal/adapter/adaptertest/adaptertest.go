@@ -0,0 +1,481 @@
+// Package adaptertest provides a conformance suite for adapter.Adapter
+// implementations
+//
+// Backend tests call Run with a constructor for empty adapters; every backend
+// that passes behaves the same way towards the sync engine
+package adaptertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// NewAdapter returns an adapter rooted at a fresh, empty directory
+// Cleanup should be registered with t.Cleanup
+type NewAdapter func(t *testing.T) adapter.Adapter
+
+// Run runs the conformance suite against adapters created by newAdapter
+func Run(t *testing.T, newAdapter NewAdapter) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, a adapter.Adapter)
+	}{
+		{"PathNormalization", testPathNormalization},
+		{"PathEscape", testPathEscape},
+		{"ErrorContract", testErrorContract},
+		{"WriteCreatesParents", testWriteCreatesParents},
+		{"AtomicOverwrite", testAtomicOverwrite},
+		{"NestedMkdir", testNestedMkdir},
+		{"Delete", testDelete},
+		{"Exists", testExists},
+		{"SetModTime", testSetModTime},
+		{"Rename", testRename},
+		{"Capabilities", testCapabilities},
+		{"ContextCanceled", testContextCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAdapter(t)
+			defer a.Close()
+			tt.fn(t, a)
+		})
+	}
+}
+
+// write stores content at path, failing the test on error
+func write(t *testing.T, a adapter.Adapter, path, content string) {
+	t.Helper()
+	if err := a.Write(context.Background(), path, strings.NewReader(content)); err != nil {
+		t.Fatalf("Write(%q) failed: %v", path, err)
+	}
+}
+
+// read returns the content at path, failing the test on error
+func read(t *testing.T, a adapter.Adapter, path string) string {
+	t.Helper()
+	r, err := a.Read(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Read(%q) failed: %v", path, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Read(%q) content failed: %v", path, err)
+	}
+	return string(data)
+}
+
+// listPaths returns the sorted paths listed under path
+func listPaths(t *testing.T, a adapter.Adapter, path string) []string {
+	t.Helper()
+	entries, err := a.List(context.Background(), path)
+	if err != nil {
+		t.Fatalf("List(%q) failed: %v", path, err)
+	}
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func testPathNormalization(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	write(t, a, "dir/sub/file.txt", "content")
+
+	// Listed paths are slash-separated, relative to the adapter root and
+	// include the listed directory, however that directory was spelled
+	for _, root := range []string{"", "."} {
+		if got := listPaths(t, a, root); len(got) != 1 || got[0] != "dir" {
+			t.Errorf("List(%q) = %v, want [dir]", root, got)
+		}
+	}
+	for _, dir := range []string{"dir/sub", "dir/sub/", "./dir/sub", "dir//sub", "dir/./sub", "dir/x/../sub"} {
+		if got := listPaths(t, a, dir); len(got) != 1 || got[0] != "dir/sub/file.txt" {
+			t.Errorf("List(%q) = %v, want [dir/sub/file.txt]", dir, got)
+		}
+	}
+
+	for _, p := range []string{"dir/sub/file.txt", "./dir/sub/file.txt", "dir//sub/file.txt", "dir/x/../sub/file.txt"} {
+		info, err := a.Stat(ctx, p)
+		if err != nil {
+			t.Errorf("Stat(%q) failed: %v", p, err)
+			continue
+		}
+		if info.Path != "dir/sub/file.txt" || !info.IsFile() || info.Size != int64(len("content")) {
+			t.Errorf("Stat(%q) = %+v, want file dir/sub/file.txt of size %d", p, info, len("content"))
+		}
+	}
+
+	info, err := a.Stat(ctx, "dir/sub/")
+	if err != nil {
+		t.Fatalf("Stat(dir/sub/) failed: %v", err)
+	}
+	if info.Path != "dir/sub" || !info.IsDir() {
+		t.Errorf("Stat(dir/sub/) = %+v, want directory dir/sub", info)
+	}
+}
+
+func testPathEscape(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	for _, p := range []string{"..", "../outside.txt", "dir/../../outside.txt", "/absolute.txt"} {
+		if err := a.Write(ctx, p, strings.NewReader("x")); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Errorf("Write(%q) error = %v, want ErrPermissionDenied", p, err)
+		}
+		if _, err := a.Stat(ctx, p); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Errorf("Stat(%q) error = %v, want ErrPermissionDenied", p, err)
+		}
+		if _, err := a.List(ctx, p); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Errorf("List(%q) error = %v, want ErrPermissionDenied", p, err)
+		}
+		if _, err := a.Exists(ctx, p); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Errorf("Exists(%q) error = %v, want ErrPermissionDenied", p, err)
+		}
+	}
+}
+
+func testErrorContract(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	write(t, a, "dir/file.txt", "content")
+
+	if _, err := a.List(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("List(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := a.List(ctx, "dir/file.txt"); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("List(file) error = %v, want ErrNotDirectory", err)
+	}
+	if _, err := a.Read(ctx, "missing.txt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Read(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := a.Read(ctx, "dir"); !errors.Is(err, domain.ErrNotFile) {
+		t.Errorf("Read(dir) error = %v, want ErrNotFile", err)
+	}
+	if _, err := a.Stat(ctx, "missing.txt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Stat(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := a.Delete(ctx, "missing.txt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func testWriteCreatesParents(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	write(t, a, "a/b/c/file.txt", "deep")
+
+	for _, dir := range []string{"a", "a/b", "a/b/c"} {
+		info, err := a.Stat(ctx, dir)
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(%q) = %+v, %v, want directory", dir, info, err)
+		}
+	}
+	if got := read(t, a, "a/b/c/file.txt"); got != "deep" {
+		t.Errorf("Read = %q, want deep", got)
+	}
+}
+
+// failingReader returns some data and then an error
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func testAtomicOverwrite(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	write(t, a, "dir/file.txt", "original content")
+
+	// A successful overwrite replaces content and size
+	write(t, a, "dir/file.txt", "new")
+	if got := read(t, a, "dir/file.txt"); got != "new" {
+		t.Errorf("content after overwrite = %q, want new", got)
+	}
+	if info, err := a.Stat(ctx, "dir/file.txt"); err != nil || info.Size != 3 {
+		t.Errorf("Stat after overwrite = %+v, %v, want size 3", info, err)
+	}
+
+	// A failed overwrite leaves the previous content untouched
+	broken := errors.New("source vanished")
+	r := &failingReader{data: bytes.Repeat([]byte("partial"), 1024), err: broken}
+	if err := a.Write(ctx, "dir/file.txt", r); err == nil {
+		t.Fatal("Write with a failing reader should fail")
+	}
+	if got := read(t, a, "dir/file.txt"); got != "new" {
+		t.Errorf("content after failed overwrite = %.40q, want new", got)
+	}
+
+	// Neither write leaves temporary files behind
+	if got := listPaths(t, a, "dir"); len(got) != 1 || got[0] != "dir/file.txt" {
+		t.Errorf("List(dir) = %v, want only dir/file.txt", got)
+	}
+}
+
+func testNestedMkdir(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	if err := a.Mkdir(ctx, "x/y/z"); err != nil {
+		t.Fatalf("Mkdir(x/y/z) failed: %v", err)
+	}
+	for _, dir := range []string{"x", "x/y", "x/y/z"} {
+		info, err := a.Stat(ctx, dir)
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(%q) = %+v, %v, want directory", dir, info, err)
+		}
+	}
+	if got := listPaths(t, a, "x/y/z"); len(got) != 0 {
+		t.Errorf("List(x/y/z) = %v, want empty", got)
+	}
+
+	// Existing directories are not an error
+	if err := a.Mkdir(ctx, "x/y/z"); err != nil {
+		t.Errorf("Mkdir on existing directory failed: %v", err)
+	}
+	if err := a.Mkdir(ctx, "x/y"); err != nil {
+		t.Errorf("Mkdir on existing parent failed: %v", err)
+	}
+}
+
+func testDelete(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	write(t, a, "dir/file.txt", "content")
+
+	// Non-empty directories are never removed recursively
+	if err := a.Delete(ctx, "dir"); !errors.Is(err, domain.ErrDirectoryNotEmpty) {
+		t.Errorf("Delete(non-empty dir) error = %v, want ErrDirectoryNotEmpty", err)
+	}
+	if got := read(t, a, "dir/file.txt"); got != "content" {
+		t.Errorf("content after refused delete = %q, want content", got)
+	}
+
+	if err := a.Delete(ctx, "dir/file.txt"); err != nil {
+		t.Fatalf("Delete(file) failed: %v", err)
+	}
+	if _, err := a.Stat(ctx, "dir/file.txt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Stat(deleted file) error = %v, want ErrNotFound", err)
+	}
+	if err := a.Delete(ctx, "dir"); err != nil {
+		t.Fatalf("Delete(empty dir) failed: %v", err)
+	}
+	if got := listPaths(t, a, ""); len(got) != 0 {
+		t.Errorf("List after deletes = %v, want empty", got)
+	}
+}
+
+func testExists(t *testing.T, a adapter.Adapter) {
+	ctx := context.Background()
+	write(t, a, "dir/file.txt", "content")
+
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{"", true},
+		{"dir", true},
+		{"dir/", true},
+		{"dir/file.txt", true},
+		{"./dir/file.txt", true},
+		{"missing", false},
+		{"dir/missing.txt", false},
+		{"missing/deeper/file.txt", false},
+	} {
+		got, err := a.Exists(ctx, tt.path)
+		if err != nil {
+			t.Errorf("Exists(%q) failed: %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Exists(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func testSetModTime(t *testing.T, a adapter.Adapter) {
+	setter, ok := a.(adapter.ModTimeSetter)
+	if !ok {
+		t.Skip("adapter does not implement ModTimeSetter")
+	}
+	ctx := context.Background()
+	write(t, a, "dir/file.txt", "content")
+
+	// Whole seconds, which every backend can represent
+	modTime := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, p := range []string{"dir/file.txt", "dir"} {
+		if err := setter.SetModTime(ctx, p, modTime); err != nil {
+			t.Fatalf("SetModTime(%q) failed: %v", p, err)
+		}
+		info, err := a.Stat(ctx, p)
+		if err != nil {
+			t.Fatalf("Stat(%q) failed: %v", p, err)
+		}
+		if !info.ModTime.Equal(modTime) {
+			t.Errorf("Stat(%q).ModTime = %v, want %v", p, info.ModTime, modTime)
+		}
+	}
+	if got := read(t, a, "dir/file.txt"); got != "content" {
+		t.Errorf("content after SetModTime = %q, want content", got)
+	}
+
+	if err := setter.SetModTime(ctx, "missing.txt", modTime); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("SetModTime(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := setter.SetModTime(ctx, "../outside.txt", modTime); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("SetModTime(escape) error = %v, want ErrPermissionDenied", err)
+	}
+}
+
+func testRename(t *testing.T, a adapter.Adapter) {
+	renamer, ok := a.(adapter.Renamer)
+	if !ok {
+		t.Skip("adapter does not implement Renamer")
+	}
+	ctx := context.Background()
+	write(t, a, "a/file.txt", "content")
+	write(t, a, "d/sub/x.txt", "x")
+	write(t, a, "taken.txt", "taken")
+
+	// Files move to new parents, which are created
+	if err := renamer.Rename(ctx, "a/file.txt", "b/c/moved.txt"); err != nil {
+		t.Fatalf("Rename(file) failed: %v", err)
+	}
+	if got := read(t, a, "b/c/moved.txt"); got != "content" {
+		t.Errorf("moved content = %q, want content", got)
+	}
+	if exists, _ := a.Exists(ctx, "a/file.txt"); exists {
+		t.Error("old path should be gone after Rename")
+	}
+
+	// Directories move with their contents
+	if err := renamer.Rename(ctx, "d", "e"); err != nil {
+		t.Fatalf("Rename(dir) failed: %v", err)
+	}
+	if got := read(t, a, "e/sub/x.txt"); got != "x" {
+		t.Errorf("moved dir content = %q, want x", got)
+	}
+	if exists, _ := a.Exists(ctx, "d"); exists {
+		t.Error("old directory should be gone after Rename")
+	}
+
+	// Existing targets are never replaced
+	if err := renamer.Rename(ctx, "b/c/moved.txt", "taken.txt"); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Errorf("Rename onto existing error = %v, want ErrAlreadyExists", err)
+	}
+	if got := read(t, a, "taken.txt"); got != "taken" {
+		t.Errorf("target content after refused Rename = %q, want taken", got)
+	}
+	if got := read(t, a, "b/c/moved.txt"); got != "content" {
+		t.Errorf("source content after refused Rename = %q, want content", got)
+	}
+
+	if err := renamer.Rename(ctx, "missing.txt", "other.txt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Rename(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := renamer.Rename(ctx, "taken.txt", "../outside.txt"); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("Rename(escape) error = %v, want ErrPermissionDenied", err)
+	}
+}
+
+// checksumConfigurable is implemented by adapters whose checksum algorithm can be chosen
+type checksumConfigurable interface {
+	SetChecksumAlgorithm(algo checksum.Algorithm) error
+}
+
+func testCapabilities(t *testing.T, a adapter.Adapter) {
+	caps := adapter.CapabilitiesOf(a)
+	if _, ok := a.(adapter.ModTimeSetter); ok != caps.SetModTime {
+		t.Errorf("Capabilities().SetModTime = %v, but ModTimeSetter implemented = %v", caps.SetModTime, ok)
+	}
+	if _, ok := a.(adapter.Renamer); ok != caps.Rename {
+		t.Errorf("Capabilities().Rename = %v, but Renamer implemented = %v", caps.Rename, ok)
+	}
+
+	write(t, a, "dir/file.txt", "content")
+
+	// Every advertised checksum algorithm shows up in listings
+	for _, algo := range caps.Checksums {
+		if cs, ok := a.(checksumConfigurable); ok {
+			if err := cs.SetChecksumAlgorithm(algo); err != nil {
+				t.Fatalf("SetChecksumAlgorithm(%s) failed: %v", algo, err)
+			}
+		}
+		entries, err := a.List(context.Background(), "dir")
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("List(dir) = %+v, %v", entries, err)
+		}
+		if entries[0].Checksum == "" || entries[0].ChecksumAlgorithm != string(algo) {
+			t.Errorf("listed checksum with %s = %q (%s), want a %s checksum",
+				algo, entries[0].Checksum, entries[0].ChecksumAlgorithm, algo)
+		}
+	}
+
+	if caps.ETags {
+		info, err := a.Stat(context.Background(), "dir/file.txt")
+		if err != nil || info.ETag == "" {
+			t.Errorf("Stat = %+v, %v, want an ETag", info, err)
+		}
+	}
+}
+
+func testContextCanceled(t *testing.T, a adapter.Adapter) {
+	write(t, a, "dir/file.txt", "content")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	check := func(op string, err error) {
+		t.Helper()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s with canceled context error = %v, want context.Canceled", op, err)
+		}
+	}
+
+	_, err := a.List(ctx, "dir")
+	check("List", err)
+	r, err := a.Read(ctx, "dir/file.txt")
+	if err == nil {
+		r.Close()
+	}
+	check("Read", err)
+	_, err = a.Stat(ctx, "dir/file.txt")
+	check("Stat", err)
+	_, err = a.Exists(ctx, "dir/file.txt")
+	check("Exists", err)
+	check("Write", a.Write(ctx, "dir/file.txt", strings.NewReader("changed")))
+	check("Write new", a.Write(ctx, "dir/new.txt", strings.NewReader("new")))
+	check("Mkdir", a.Mkdir(ctx, "newdir"))
+	check("Delete", a.Delete(ctx, "dir/file.txt"))
+	if setter, ok := a.(adapter.ModTimeSetter); ok {
+		check("SetModTime", setter.SetModTime(ctx, "dir/file.txt", time.Unix(0, 0)))
+	}
+	if renamer, ok := a.(adapter.Renamer); ok {
+		check("Rename", renamer.Rename(ctx, "dir/file.txt", "renamed.txt"))
+	}
+
+	// Canceled operations have no effect
+	if got := read(t, a, "dir/file.txt"); got != "content" {
+		t.Errorf("content after canceled operations = %q, want content", got)
+	}
+	if got := listPaths(t, a, ""); len(got) != 1 || got[0] != "dir" {
+		t.Errorf("List after canceled operations = %v, want [dir]", got)
+	}
+	if got := listPaths(t, a, "dir"); len(got) != 1 || got[0] != "dir/file.txt" {
+		t.Errorf("List(dir) after canceled operations = %v, want [dir/file.txt]", got)
+	}
+}
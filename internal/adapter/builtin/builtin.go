@@ -0,0 +1,13 @@
+package builtin
+
+// Importing this package registers every storage backend shipped with
+// Syncrules in adapter.Default(). Additional backends only need their own
+// package that calls adapter.Register from init and is imported alongside
+import (
+	_ "github.com/Ning0612/Syncrules/internal/adapter/gdrive"
+	_ "github.com/Ning0612/Syncrules/internal/adapter/local"
+	_ "github.com/Ning0612/Syncrules/internal/adapter/memory"
+	_ "github.com/Ning0612/Syncrules/internal/adapter/s3"
+	_ "github.com/Ning0612/Syncrules/internal/adapter/sftp"
+	_ "github.com/Ning0612/Syncrules/internal/adapter/webdav"
+)
@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// Capabilities describes what a backend supports beyond the Adapter interface
+// The planner and executor use it to pick a strategy for each pair of endpoints
+type Capabilities struct {
+	// Checksums lists the algorithms the backend can fill into FileInfo.Checksum,
+	// in order of preference
+	Checksums []checksum.Algorithm
+
+	// ServerChecksums is true if checksums come from the server, without
+	// reading file content on this side
+	ServerChecksums bool
+
+	// SetModTime is true if the adapter implements ModTimeSetter
+	SetModTime bool
+
+	// Symlinks is true if listings report symlinks as domain.FileTypeSymlink
+	Symlinks bool
+
+	// Rename is true if the adapter implements Renamer
+	Rename bool
+
+	// AtomicWrite is true if Write never leaves a partially written file
+	AtomicWrite bool
+
+	// ETags is true if FileInfo.ETag is filled
+	ETags bool
+}
+
+// SupportsChecksum returns true if the backend can produce checksums with algo
+func (c Capabilities) SupportsChecksum(algo checksum.Algorithm) bool {
+	return slices.Contains(c.Checksums, algo)
+}
+
+// CapabilityReporter is implemented by adapters that describe their capabilities
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// Renamer is implemented by adapters that can move a path without copying it
+type Renamer interface {
+	// Rename moves a file or directory to newPath, creating its parents
+	// Returns domain.ErrNotFound if oldPath doesn't exist
+	// Returns domain.ErrAlreadyExists if newPath exists; it is never replaced
+	Rename(ctx context.Context, oldPath, newPath string) error
+}
+
+// ChecksumStater is implemented by adapters that can compute a checksum for
+// a single file on demand, e.g., one skipped while listing
+type ChecksumStater interface {
+	// StatWithChecksum returns metadata including the checksum of a file
+	StatWithChecksum(ctx context.Context, path string) (domain.FileInfo, error)
+}
+
+// LazyChecksumLister is implemented by adapters that read file contents to
+// fill checksums while listing
+type LazyChecksumLister interface {
+	// ListWithoutChecksums lists like List but leaves checksums empty, for
+	// callers that compute the ones they need with StatWithChecksum
+	ListWithoutChecksums(ctx context.Context, path string) ([]domain.FileInfo, error)
+}
+
+// CapabilitiesOf returns the capabilities of an adapter
+// Capabilities backed by an optional interface are only reported if the
+// adapter implements it; adapters without a CapabilityReporter get those alone
+func CapabilitiesOf(a Adapter) Capabilities {
+	var caps Capabilities
+	reporter, reported := a.(CapabilityReporter)
+	if reported {
+		caps = reporter.Capabilities()
+	}
+
+	_, setter := a.(ModTimeSetter)
+	_, renamer := a.(Renamer)
+	caps.SetModTime = setter && (caps.SetModTime || !reported)
+	caps.Rename = renamer && (caps.Rename || !reported)
+	return caps
+}
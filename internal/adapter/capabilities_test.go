@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// bareAdapter implements only the Adapter interface
+type bareAdapter struct{}
+
+func (bareAdapter) List(context.Context, string) ([]domain.FileInfo, error) { return nil, nil }
+func (bareAdapter) Read(context.Context, string) (io.ReadCloser, error)     { return nil, nil }
+func (bareAdapter) Write(context.Context, string, io.Reader) error          { return nil }
+func (bareAdapter) Delete(context.Context, string) error                    { return nil }
+func (bareAdapter) Stat(context.Context, string) (domain.FileInfo, error) {
+	return domain.FileInfo{}, nil
+}
+func (bareAdapter) Mkdir(context.Context, string) error          { return nil }
+func (bareAdapter) Exists(context.Context, string) (bool, error) { return false, nil }
+func (bareAdapter) Close() error                                 { return nil }
+
+// setterAdapter adds ModTimeSetter without reporting capabilities
+type setterAdapter struct{ bareAdapter }
+
+func (setterAdapter) SetModTime(context.Context, string, time.Time) error { return nil }
+
+// reportingAdapter claims more than it implements
+type reportingAdapter struct{ setterAdapter }
+
+func (reportingAdapter) Capabilities() Capabilities {
+	return Capabilities{SetModTime: false, Rename: true, ETags: true}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	if caps := CapabilitiesOf(bareAdapter{}); caps.SetModTime || caps.Rename || caps.ETags {
+		t.Errorf("bare adapter capabilities = %+v, want none", caps)
+	}
+
+	// Without a report, optional interfaces are detected
+	if caps := CapabilitiesOf(setterAdapter{}); !caps.SetModTime || caps.Rename {
+		t.Errorf("setter adapter capabilities = %+v, want SetModTime only", caps)
+	}
+
+	// A report can turn an interface off but cannot claim a missing one
+	caps := CapabilitiesOf(reportingAdapter{})
+	if caps.SetModTime || caps.Rename || !caps.ETags {
+		t.Errorf("reporting adapter capabilities = %+v, want ETags only", caps)
+	}
+}
@@ -0,0 +1,40 @@
+package gdrive
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+func init() {
+	adapter.Register(Factory{})
+}
+
+// Factory creates Google Drive adapters
+// Transport config keys: client_id, client_secret, token_path
+type Factory struct{}
+
+// Supports returns true for the gdrive transport type
+func (Factory) Supports(transportType domain.TransportType) bool {
+	return transportType == domain.TransportGDrive
+}
+
+// Create returns a Drive adapter rooted at the given folder path
+func (Factory) Create(transport domain.Transport, root string) (adapter.Adapter, error) {
+	// Get OAuth credentials from transport config
+	clientID := transport.Config["client_id"]
+	clientSecret := transport.Config["client_secret"]
+	tokenPath := transport.Config["token_path"]
+
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("gdrive transport requires client_id and client_secret in config")
+	}
+
+	a, err := New(context.Background(), clientID, clientSecret, tokenPath, root)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -16,6 +16,8 @@ import (
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
 	"github.com/Ning0612/Syncrules/internal/domain"
 )
 
@@ -24,6 +26,8 @@ const (
 	MimeTypeFolder = "application/vnd.google-apps.folder"
 	// PageSize is the number of files to fetch per request
 	PageSize = 100
+	// fileFields lists the Drive metadata fields needed to build domain.FileInfo
+	fileFields = "id, name, mimeType, size, modifiedTime, md5Checksum, sha256Checksum"
 )
 
 // Adapter implements the adapter.Adapter interface for Google Drive
@@ -32,6 +36,9 @@ type Adapter struct {
 	root    string   // Root folder path in Drive (e.g., "/SyncRules/backup")
 	rootID  string   // Cached root folder ID
 	cache   *idCache // Cache for path -> ID mapping
+
+	// checksumAlgorithm selects which Drive-provided hash fills FileInfo.Checksum
+	checksumAlgorithm checksum.Algorithm
 }
 
 // idCache caches folder ID lookups with thread-safe access
@@ -65,6 +72,17 @@ func (c *idCache) delete(path string) {
 	delete(c.paths, path)
 }
 
+// deleteTree removes path and everything cached below it
+func (c *idCache) deleteTree(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := range c.paths {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(c.paths, p)
+		}
+	}
+}
+
 // New creates a new Google Drive adapter
 func New(ctx context.Context, clientID, clientSecret, tokenPath, root string) (*Adapter, error) {
 	auth := NewAuthenticator(clientID, clientSecret, tokenPath)
@@ -83,21 +101,7 @@ func New(ctx context.Context, clientID, clientSecret, tokenPath, root string) (*
 		return nil, fmt.Errorf("failed to create Drive service: %w", err)
 	}
 
-	adapter := &Adapter{
-		service: service,
-		root:    normalizeRoot(root),
-		cache:   newIDCache(),
-	}
-
-	// Resolve root folder ID
-	rootID, err := adapter.resolveOrCreatePath(ctx, adapter.root)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve root folder: %w", err)
-	}
-	adapter.rootID = rootID
-	adapter.cache.set(adapter.root, rootID)
-
-	return adapter, nil
+	return newWithService(ctx, service, root)
 }
 
 // NewWithToken creates a new adapter with an existing token
@@ -109,12 +113,20 @@ func NewWithToken(ctx context.Context, token *oauth2.Token, oauthConfig *oauth2.
 		return nil, fmt.Errorf("failed to create Drive service: %w", err)
 	}
 
+	return newWithService(ctx, service, root)
+}
+
+// newWithService creates an adapter on a Drive service, creating the root
+// folder if it does not exist yet
+func newWithService(ctx context.Context, service *drive.Service, root string) (*Adapter, error) {
 	adapter := &Adapter{
-		service: service,
-		root:    normalizeRoot(root),
-		cache:   newIDCache(),
+		service:           service,
+		root:              normalizeRoot(root),
+		cache:             newIDCache(),
+		checksumAlgorithm: checksum.MD5,
 	}
 
+	// Resolve root folder ID
 	rootID, err := adapter.resolveOrCreatePath(ctx, adapter.root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve root folder: %w", err)
@@ -125,6 +137,16 @@ func NewWithToken(ctx context.Context, token *oauth2.Token, oauthConfig *oauth2.
 	return adapter, nil
 }
 
+// SetChecksumAlgorithm selects which server-side hash is exposed as the checksum
+// Drive computes both MD5 and SHA-256, so no content is downloaded (default: md5)
+func (a *Adapter) SetChecksumAlgorithm(algo checksum.Algorithm) error {
+	if !checksum.IsSupported(algo) {
+		return fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+	a.checksumAlgorithm = algo
+	return nil
+}
+
 // normalizeRoot normalizes the root path
 func normalizeRoot(root string) string {
 	root = strings.TrimSpace(root)
@@ -158,7 +180,7 @@ func (a *Adapter) List(ctx context.Context, relPath string) ([]domain.FileInfo,
 		call := a.service.Files.List().
 			Q(query).
 			PageSize(PageSize).
-			Fields("nextPageToken, files(id, name, mimeType, size, modifiedTime, md5Checksum)")
+			Fields("nextPageToken, files(" + fileFields + ")")
 
 		if pageToken != "" {
 			call = call.PageToken(pageToken)
@@ -180,6 +202,17 @@ func (a *Adapter) List(ctx context.Context, relPath string) ([]domain.FileInfo,
 		}
 	}
 
+	// Files have no children either; tell them apart from empty folders
+	if len(result) == 0 {
+		folder, err := a.isFolder(ctx, folderID)
+		if err != nil {
+			return nil, err
+		}
+		if !folder {
+			return nil, domain.ErrNotDirectory
+		}
+	}
+
 	return result, nil
 }
 
@@ -196,6 +229,10 @@ func (a *Adapter) Read(ctx context.Context, relPath string) (io.ReadCloser, erro
 
 	resp, err := a.service.Files.Get(fileID).Context(ctx).Download()
 	if err != nil {
+		// Folders cannot be downloaded
+		if folder, _ := a.isFolder(ctx, fileID); folder {
+			return nil, domain.ErrNotFile
+		}
 		return nil, a.mapError(err)
 	}
 
@@ -260,6 +297,20 @@ func (a *Adapter) Delete(ctx context.Context, relPath string) error {
 		return err
 	}
 
+	// Drive deletes folders recursively; keep the local semantics of only
+	// removing empty directories
+	children, err := a.service.Files.List().
+		Q(fmt.Sprintf("'%s' in parents and trashed = false", fileID)).
+		PageSize(1).
+		Fields("files(id)").
+		Context(ctx).Do()
+	if err != nil {
+		return a.mapError(err)
+	}
+	if len(children.Files) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrDirectoryNotEmpty, relPath)
+	}
+
 	err = a.service.Files.Delete(fileID).Context(ctx).Do()
 	if err != nil {
 		return a.mapError(err)
@@ -282,18 +333,18 @@ func (a *Adapter) Stat(ctx context.Context, relPath string) (domain.FileInfo, er
 	}
 
 	file, err := a.service.Files.Get(fileID).
-		Fields("id, name, mimeType, size, modifiedTime, md5Checksum").
+		Fields(fileFields).
 		Context(ctx).Do()
 	if err != nil {
 		return domain.FileInfo{}, a.mapError(err)
 	}
 
-	return a.fileInfoFromDrive(path.Dir(relPath), file), nil
+	return a.fileInfoFromDrive(path.Dir(path.Clean(relPath)), file), nil
 }
 
 // StatWithChecksum returns metadata including checksum for a file
 func (a *Adapter) StatWithChecksum(ctx context.Context, relPath string) (domain.FileInfo, error) {
-	// Google Drive provides MD5 checksum in file metadata
+	// Google Drive provides MD5 and SHA-256 checksums in file metadata
 	return a.Stat(ctx, relPath)
 }
 
@@ -307,6 +358,80 @@ func (a *Adapter) Mkdir(ctx context.Context, relPath string) error {
 	return err
 }
 
+// SetModTime sets the modification time of a file or folder
+func (a *Adapter) SetModTime(ctx context.Context, relPath string, modTime time.Time) error {
+	fullPath, err := a.joinPath(relPath)
+	if err != nil {
+		return err
+	}
+	fileID, err := a.getFileID(ctx, fullPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.service.Files.Update(fileID, &drive.File{
+		ModifiedTime: modTime.UTC().Format(time.RFC3339Nano),
+	}).Context(ctx).Do()
+	return a.mapError(err)
+}
+
+// Rename moves a file or folder by changing its name and parent
+func (a *Adapter) Rename(ctx context.Context, oldPath, newPath string) error {
+	from, err := a.joinPath(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := a.joinPath(newPath)
+	if err != nil {
+		return err
+	}
+	if from == a.root || to == a.root {
+		return domain.ErrPermissionDenied
+	}
+
+	fileID, err := a.getFileID(ctx, from)
+	if err != nil {
+		return err
+	}
+	if _, err := a.getFileID(ctx, to); err == nil {
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyExists, newPath)
+	} else if err != domain.ErrNotFound {
+		return err
+	}
+
+	oldParentID, err := a.getFileID(ctx, parentPath(from))
+	if err != nil {
+		return err
+	}
+	newParentID, err := a.getOrCreateFolderID(ctx, parentPath(to))
+	if err != nil {
+		return err
+	}
+
+	call := a.service.Files.Update(fileID, &drive.File{Name: path.Base(to)})
+	if newParentID != oldParentID {
+		call = call.AddParents(newParentID).RemoveParents(oldParentID)
+	}
+	if _, err := call.Context(ctx).Do(); err != nil {
+		return a.mapError(err)
+	}
+
+	a.cache.deleteTree(from)
+	return nil
+}
+
+// Capabilities describes the Google Drive backend
+// Drive computes MD5 and SHA-256 checksums server-side
+func (a *Adapter) Capabilities() adapter.Capabilities {
+	return adapter.Capabilities{
+		Checksums:       []checksum.Algorithm{checksum.MD5, checksum.SHA256},
+		ServerChecksums: true,
+		SetModTime:      true,
+		Rename:          true,
+		AtomicWrite:     true,
+	}
+}
+
 // Exists checks if a path exists
 func (a *Adapter) Exists(ctx context.Context, relPath string) (bool, error) {
 	fullPath, err := a.joinPath(relPath)
@@ -362,6 +487,15 @@ func (a *Adapter) joinPath(relPath string) (string, error) {
 	return fullPath, nil
 }
 
+// parentPath returns the parent of a full Drive path ("" for top-level items)
+func parentPath(fullPath string) string {
+	dir := path.Dir(fullPath)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
 // escapeQueryString escapes special characters in Drive query strings
 func escapeQueryString(s string) string {
 	// Escape backslash first, then single quote
@@ -417,6 +551,17 @@ func (a *Adapter) getFileID(ctx context.Context, fullPath string) (string, error
 	return currentID, nil
 }
 
+// isFolder reports whether the file with the given ID is a folder
+func (a *Adapter) isFolder(ctx context.Context, fileID string) (bool, error) {
+	file, err := a.service.Files.Get(fileID).
+		Fields("mimeType").
+		Context(ctx).Do()
+	if err != nil {
+		return false, a.mapError(err)
+	}
+	return file.MimeType == MimeTypeFolder, nil
+}
+
 // getOrCreateFolderID returns the ID of a folder, creating it if necessary
 func (a *Adapter) getOrCreateFolderID(ctx context.Context, fullPath string) (string, error) {
 	if fullPath == "" {
@@ -504,13 +649,25 @@ func (a *Adapter) fileInfoFromDrive(parentPath string, file *drive.File) domain.
 		filePath = path.Join(parentPath, file.Name)
 	}
 
-	return domain.FileInfo{
-		Path:     filePath,
-		Type:     fileType,
-		Size:     file.Size,
-		ModTime:  modTime,
-		Checksum: file.Md5Checksum, // Drive provides MD5
+	info := domain.FileInfo{
+		Path:    filePath,
+		Type:    fileType,
+		Size:    file.Size,
+		ModTime: modTime,
+	}
+
+	// Drive computes hashes server-side; expose the configured one
+	switch a.checksumAlgorithm {
+	case checksum.SHA256:
+		info.Checksum = file.Sha256Checksum
+	default:
+		info.Checksum = file.Md5Checksum
 	}
+	if info.Checksum != "" {
+		info.ChecksumAlgorithm = string(a.checksumAlgorithm)
+	}
+
+	return info
 }
 
 // mapError converts Google API errors to domain errors
@@ -1,9 +1,30 @@
 package gdrive
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/adaptertest"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
 )
 
 // TestNormalizeRoot tests path normalization
@@ -188,6 +209,25 @@ func TestIDCache_Delete(t *testing.T) {
 	}
 }
 
+// TestIDCache_DeleteTree tests that a moved folder forgets its descendants
+func TestIDCache_DeleteTree(t *testing.T) {
+	cache := newIDCache()
+
+	cache.set("/docs", "id-1")
+	cache.set("/docs/a.txt", "id-2")
+	cache.set("/docs2", "id-3")
+	cache.deleteTree("/docs")
+
+	for _, p := range []string{"/docs", "/docs/a.txt"} {
+		if _, ok := cache.get(p); ok {
+			t.Errorf("expected cache miss for %s after deleteTree", p)
+		}
+	}
+	if _, ok := cache.get("/docs2"); !ok {
+		t.Error("deleteTree should keep siblings sharing the prefix")
+	}
+}
+
 // TestSecurity_CacheConcurrency tests cache thread safety
 func TestSecurity_CacheConcurrency(t *testing.T) {
 	cache := newIDCache()
@@ -247,3 +287,292 @@ func BenchmarkCacheConcurrent(b *testing.B) {
 		}
 	})
 }
+
+// TestFileInfoFromDrive_ChecksumAlgorithm tests that the configured server-side hash is exposed
+func TestFileInfoFromDrive_ChecksumAlgorithm(t *testing.T) {
+	file := &drive.File{
+		Name:           "notes.md",
+		MimeType:       "text/markdown",
+		Size:           42,
+		ModifiedTime:   "2026-01-02T03:04:05Z",
+		Md5Checksum:    "md5-hash",
+		Sha256Checksum: "sha256-hash",
+	}
+
+	tests := []struct {
+		algo     checksum.Algorithm
+		expected string
+	}{
+		{checksum.MD5, "md5-hash"},
+		{checksum.SHA256, "sha256-hash"},
+	}
+
+	for _, tt := range tests {
+		a := &Adapter{checksumAlgorithm: tt.algo}
+		info := a.fileInfoFromDrive("docs", file)
+		if info.Path != "docs/notes.md" {
+			t.Errorf("Path = %q, want %q", info.Path, "docs/notes.md")
+		}
+		if info.Checksum != tt.expected {
+			t.Errorf("%s: Checksum = %q, want %q", tt.algo, info.Checksum, tt.expected)
+		}
+		if info.ChecksumAlgorithm != string(tt.algo) {
+			t.Errorf("%s: ChecksumAlgorithm = %q", tt.algo, info.ChecksumAlgorithm)
+		}
+	}
+
+	// Folders carry no checksum and no algorithm
+	folder := &drive.File{Name: "dir", MimeType: MimeTypeFolder}
+	info := (&Adapter{checksumAlgorithm: checksum.SHA256}).fileInfoFromDrive("", folder)
+	if info.Checksum != "" || info.ChecksumAlgorithm != "" {
+		t.Errorf("Expected no checksum for folder, got %+v", info)
+	}
+}
+
+// TestSetChecksumAlgorithm tests algorithm validation
+func TestSetChecksumAlgorithm(t *testing.T) {
+	a := &Adapter{}
+	if err := a.SetChecksumAlgorithm(checksum.SHA256); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := a.SetChecksumAlgorithm("crc32"); err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+}
+
+// fakeDrive is a minimal in-process Drive v3 server
+// It supports what the adapter uses: files.list with the adapter's queries,
+// files.get (metadata and media), files.create and files.update (metadata or
+// multipart media, with addParents/removeParents) and files.delete
+type fakeDrive struct {
+	mu     sync.Mutex
+	files  map[string]*fakeFile
+	nextID int
+}
+
+type fakeFile struct {
+	name     string
+	mimeType string
+	parent   string
+	data     []byte
+	modTime  time.Time
+}
+
+func newFakeDrive() *fakeDrive {
+	return &fakeDrive{
+		files: map[string]*fakeFile{"root": {name: "My Drive", mimeType: MimeTypeFolder}},
+	}
+}
+
+var (
+	queryName   = regexp.MustCompile(`name = '((?:[^'\\]|\\.)*)'`)
+	queryParent = regexp.MustCompile(`'([^']+)' in parents`)
+	queryMime   = regexp.MustCompile(`mimeType = '([^']+)'`)
+)
+
+func (f *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upload := strings.HasPrefix(r.URL.Path, "/upload/drive/v3/")
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/upload/drive/v3"), "/")
+	id, hasID := strings.CutPrefix(rest, "files/")
+	if rest != "files" && !hasID {
+		writeDriveError(w, http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case !hasID && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query().Get("q"))
+	case !hasID && r.Method == http.MethodPost:
+		meta, data, err := readUpload(r, upload)
+		if err != nil {
+			writeDriveError(w, http.StatusBadRequest)
+			return
+		}
+		if len(meta.Parents) != 1 || f.files[meta.Parents[0]] == nil {
+			writeDriveError(w, http.StatusNotFound)
+			return
+		}
+		f.nextID++
+		id := fmt.Sprintf("id%d", f.nextID)
+		f.files[id] = &fakeFile{name: meta.Name, mimeType: meta.MimeType, parent: meta.Parents[0], data: data, modTime: time.Now().UTC()}
+		f.writeFile(w, id)
+	case f.files[id] == nil:
+		writeDriveError(w, http.StatusNotFound)
+	case r.Method == http.MethodGet && r.URL.Query().Get("alt") == "media":
+		if f.files[id].mimeType == MimeTypeFolder {
+			writeDriveError(w, http.StatusForbidden)
+			return
+		}
+		w.Write(f.files[id].data)
+	case r.Method == http.MethodGet:
+		f.writeFile(w, id)
+	case r.Method == http.MethodPatch:
+		meta, data, err := readUpload(r, upload)
+		if err != nil {
+			writeDriveError(w, http.StatusBadRequest)
+			return
+		}
+		file := f.files[id]
+		if add := r.URL.Query().Get("addParents"); add != "" {
+			if f.files[add] == nil || r.URL.Query().Get("removeParents") != file.parent {
+				writeDriveError(w, http.StatusBadRequest)
+				return
+			}
+			file.parent = add
+		}
+		if meta.Name != "" {
+			file.name = meta.Name
+		}
+		if upload {
+			file.data = data
+			file.modTime = time.Now().UTC()
+		}
+		if meta.ModifiedTime != "" {
+			file.modTime, _ = time.Parse(time.RFC3339Nano, meta.ModifiedTime)
+		}
+		f.writeFile(w, id)
+	case r.Method == http.MethodDelete:
+		// Drive removes folders with everything in them
+		f.deleteTree(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeDriveError(w, http.StatusNotImplemented)
+	}
+}
+
+// list answers the queries the adapter sends, in name order
+func (f *fakeDrive) list(w http.ResponseWriter, q string) {
+	parent := queryParent.FindStringSubmatch(q)
+	if parent == nil {
+		writeDriveError(w, http.StatusBadRequest)
+		return
+	}
+	name := queryName.FindStringSubmatch(q)
+	mime := queryMime.FindStringSubmatch(q)
+
+	var ids []string
+	for id, file := range f.files {
+		if file.parent != parent[1] || id == "root" {
+			continue
+		}
+		if name != nil && file.name != unescapeQuery(name[1]) {
+			continue
+		}
+		if mime != nil && file.mimeType != mime[1] {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return f.files[ids[i]].name < f.files[ids[j]].name })
+
+	list := &drive.FileList{Files: []*drive.File{}}
+	for _, id := range ids {
+		list.Files = append(list.Files, f.driveFile(id))
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+func (f *fakeDrive) driveFile(id string) *drive.File {
+	file := f.files[id]
+	result := &drive.File{
+		Id:           id,
+		Name:         file.name,
+		MimeType:     file.mimeType,
+		Parents:      []string{file.parent},
+		ModifiedTime: file.modTime.Format(time.RFC3339Nano),
+	}
+	// Folders have neither size nor checksums
+	if file.mimeType != MimeTypeFolder {
+		md5Sum, sha256Sum := md5.Sum(file.data), sha256.Sum256(file.data)
+		result.Size = int64(len(file.data))
+		result.Md5Checksum = hex.EncodeToString(md5Sum[:])
+		result.Sha256Checksum = hex.EncodeToString(sha256Sum[:])
+	}
+	return result
+}
+
+func (f *fakeDrive) writeFile(w http.ResponseWriter, id string) {
+	json.NewEncoder(w).Encode(f.driveFile(id))
+}
+
+func (f *fakeDrive) deleteTree(id string) {
+	for child, file := range f.files {
+		if file.parent == id {
+			f.deleteTree(child)
+		}
+	}
+	delete(f.files, id)
+}
+
+// readUpload reads the metadata and, for media uploads, the content of a request
+// The whole body is read before anything changes, like an interrupted upload on Drive
+func readUpload(r *http.Request, upload bool) (*drive.File, []byte, error) {
+	meta := &drive.File{}
+	if !upload {
+		return meta, nil, json.NewDecoder(r.Body).Decode(meta)
+	}
+
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.NewDecoder(part).Decode(meta); err != nil {
+		return nil, nil, err
+	}
+	if part, err = mr.NextPart(); err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		return nil, nil, fmt.Errorf("incomplete upload: %v", err)
+	}
+	return meta, data, nil
+}
+
+func unescapeQuery(s string) string {
+	return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(s)
+}
+
+func writeDriveError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":"%s"}}`, status, http.StatusText(status))
+}
+
+// newTestAdapter serves a fake Drive and returns an adapter rooted at root
+func newTestAdapter(t *testing.T, root string) (*Adapter, *fakeDrive) {
+	t.Helper()
+
+	fake := newFakeDrive()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	service, err := drive.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatalf("Failed to create Drive service: %v", err)
+	}
+	a, err := newWithService(ctx, service, root)
+	if err != nil {
+		t.Fatalf("newWithService failed: %v", err)
+	}
+	return a, fake
+}
+
+func TestAdapter_Conformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) adapter.Adapter {
+		a, _ := newTestAdapter(t, "/Syncrules/conformance")
+		return a
+	})
+}
@@ -0,0 +1,27 @@
+package local
+
+import (
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+func init() {
+	adapter.Register(Factory{})
+}
+
+// Factory creates local filesystem adapters
+type Factory struct{}
+
+// Supports returns true for the local transport type
+func (Factory) Supports(transportType domain.TransportType) bool {
+	return transportType == domain.TransportLocal
+}
+
+// Create returns a local adapter rooted at root
+func (Factory) Create(transport domain.Transport, root string) (adapter.Adapter, error) {
+	a, err := New(root)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
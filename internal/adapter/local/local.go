@@ -2,20 +2,24 @@ package local
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
 	"github.com/Ning0612/Syncrules/internal/domain"
 )
 
 // Adapter implements the adapter.Adapter interface for local filesystem
 type Adapter struct {
-	root string
+	root       string
+	algorithm  checksum.Algorithm
+	calculator checksum.Calculator
 }
 
 // New creates a new local filesystem adapter
@@ -39,7 +43,24 @@ func New(root string) (*Adapter, error) {
 		return nil, domain.ErrNotDirectory
 	}
 
-	return &Adapter{root: absRoot}, nil
+	// Size limits are enforced by List, so the calculator itself is unlimited
+	opts := checksum.DefaultOptions()
+	opts.MaxSize = 0
+
+	return &Adapter{
+		root:       absRoot,
+		algorithm:  checksum.SHA256,
+		calculator: checksum.NewCalculator(opts),
+	}, nil
+}
+
+// SetChecksumAlgorithm sets the algorithm used for file checksums (default: sha256)
+func (a *Adapter) SetChecksumAlgorithm(algo checksum.Algorithm) error {
+	if !checksum.IsSupported(algo) {
+		return fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+	a.algorithm = algo
+	return nil
 }
 
 // resolvePath safely resolves a relative path to absolute path within root
@@ -81,6 +102,21 @@ func (a *Adapter) resolvePath(relPath string) (string, error) {
 
 // List returns all files and directories under the given path
 func (a *Adapter) List(ctx context.Context, path string) ([]domain.FileInfo, error) {
+	return a.list(ctx, path, true)
+}
+
+// ListWithoutChecksums lists like List without reading file contents
+func (a *Adapter) ListWithoutChecksums(ctx context.Context, path string) ([]domain.FileInfo, error) {
+	return a.list(ctx, path, false)
+}
+
+// list returns the entries under path, with checksums of files up to 100MB
+// if withChecksums is set
+func (a *Adapter) list(ctx context.Context, path string, withChecksums bool) ([]domain.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return nil, err
@@ -109,10 +145,11 @@ func (a *Adapter) List(ctx context.Context, path string) ([]domain.FileInfo, err
 
 		// Phase 3: Calculate checksum for regular files
 		// Only compute checksum for files <= 100MB to avoid performance issues
-		if fileInfo.IsFile() && fileInfo.Size <= 100*1024*1024 {
-			checksum, err := a.computeChecksum(ctx, entryPath)
+		if withChecksums && fileInfo.IsFile() && fileInfo.Size <= 100*1024*1024 {
+			sum, err := a.computeChecksum(ctx, entryPath)
 			if err == nil {
-				fileInfo.Checksum = checksum
+				fileInfo.Checksum = sum
+				fileInfo.ChecksumAlgorithm = string(a.algorithm)
 			}
 			// If checksum calculation fails, continue with empty checksum
 			// This allows the system to fall back to time-based comparison
@@ -126,6 +163,10 @@ func (a *Adapter) List(ctx context.Context, path string) ([]domain.FileInfo, err
 
 // Read opens a file for reading
 func (a *Adapter) Read(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return nil, err
@@ -149,6 +190,10 @@ func (a *Adapter) Read(ctx context.Context, path string) (io.ReadCloser, error)
 
 // Write creates or overwrites a file
 func (a *Adapter) Write(ctx context.Context, path string, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return err
@@ -178,6 +223,10 @@ func (a *Adapter) Write(ctx context.Context, path string, r io.Reader) error {
 		os.Remove(tempPath)
 		return closeErr
 	}
+	if err := ctx.Err(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
 
 	// Atomic rename
 	if err := os.Rename(tempPath, fullPath); err != nil {
@@ -190,6 +239,10 @@ func (a *Adapter) Write(ctx context.Context, path string, r io.Reader) error {
 
 // Delete removes a file or empty directory
 func (a *Adapter) Delete(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return err
@@ -201,6 +254,10 @@ func (a *Adapter) Delete(ctx context.Context, path string) error {
 
 // Stat returns metadata for a single path
 func (a *Adapter) Stat(ctx context.Context, path string) (domain.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.FileInfo{}, err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return domain.FileInfo{}, err
@@ -211,7 +268,11 @@ func (a *Adapter) Stat(ctx context.Context, path string) (domain.FileInfo, error
 		return domain.FileInfo{}, a.mapError(err)
 	}
 
-	return a.fileInfoFromOS(path, info), nil
+	rel, err := filepath.Rel(a.root, fullPath)
+	if err != nil || rel == "." {
+		rel = ""
+	}
+	return a.fileInfoFromOS(rel, info), nil
 }
 
 // StatWithChecksum returns metadata including checksum for a file
@@ -222,11 +283,12 @@ func (a *Adapter) StatWithChecksum(ctx context.Context, path string) (domain.Fil
 	}
 
 	if info.IsFile() {
-		checksum, err := a.computeChecksum(ctx, path)
+		sum, err := a.computeChecksum(ctx, path)
 		if err != nil {
 			return info, err
 		}
-		info.Checksum = checksum
+		info.Checksum = sum
+		info.ChecksumAlgorithm = string(a.algorithm)
 	}
 
 	return info, nil
@@ -234,6 +296,10 @@ func (a *Adapter) StatWithChecksum(ctx context.Context, path string) (domain.Fil
 
 // Mkdir creates a directory and any necessary parents
 func (a *Adapter) Mkdir(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return err
@@ -242,8 +308,61 @@ func (a *Adapter) Mkdir(ctx context.Context, path string) error {
 	return os.MkdirAll(fullPath, 0755)
 }
 
+// SetModTime sets the modification time of a file or directory
+// The access time is left unchanged
+func (a *Adapter) SetModTime(ctx context.Context, path string, modTime time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fullPath, err := a.resolvePath(path)
+	if err != nil {
+		return err
+	}
+
+	return a.mapError(os.Chtimes(fullPath, time.Time{}, modTime))
+}
+
+// Rename moves a file or directory, creating the parents of newPath
+func (a *Adapter) Rename(ctx context.Context, oldPath, newPath string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	from, err := a.resolvePath(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := a.resolvePath(newPath)
+	if err != nil {
+		return err
+	}
+	if from == a.root || to == a.root {
+		return domain.ErrPermissionDenied
+	}
+
+	if _, err := os.Lstat(from); err != nil {
+		return a.mapError(err)
+	}
+	// os.Rename replaces existing files, which Rename must not do
+	if _, err := os.Lstat(to); err == nil {
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyExists, newPath)
+	} else if !os.IsNotExist(err) {
+		return a.mapError(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return a.mapError(err)
+	}
+	return a.mapError(os.Rename(from, to))
+}
+
 // Exists checks if a path exists
 func (a *Adapter) Exists(ctx context.Context, path string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
 	fullPath, err := a.resolvePath(path)
 	if err != nil {
 		return false, err
@@ -259,6 +378,18 @@ func (a *Adapter) Exists(ctx context.Context, path string) (bool, error) {
 	return false, err
 }
 
+// Capabilities describes the local filesystem
+// Checksums are computed by reading the files
+func (a *Adapter) Capabilities() adapter.Capabilities {
+	return adapter.Capabilities{
+		Checksums:   []checksum.Algorithm{checksum.SHA256, checksum.MD5},
+		SetModTime:  true,
+		Symlinks:    true,
+		Rename:      true,
+		AtomicWrite: true,
+	}
+}
+
 // Close releases any resources (no-op for local adapter)
 func (a *Adapter) Close() error {
 	return nil
@@ -286,7 +417,7 @@ func (a *Adapter) fileInfoFromOS(path string, info os.FileInfo) domain.FileInfo
 	}
 }
 
-// computeChecksum calculates SHA256 checksum of a file
+// computeChecksum calculates the checksum of a file with the configured algorithm
 func (a *Adapter) computeChecksum(ctx context.Context, path string) (string, error) {
 	reader, err := a.Read(ctx, path)
 	if err != nil {
@@ -294,12 +425,7 @@ func (a *Adapter) computeChecksum(ctx context.Context, path string) (string, err
 	}
 	defer reader.Close()
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, reader); err != nil {
-		return "", err
-	}
-
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return a.calculator.Calculate(ctx, reader, a.algorithm)
 }
 
 // mapError converts OS errors to domain errors
@@ -314,18 +440,23 @@ func (a *Adapter) mapError(err error) error {
 	if os.IsPermission(err) {
 		return domain.ErrPermissionDenied
 	}
-	if os.IsExist(err) {
-		return domain.ErrAlreadyExists
-	}
 
-	// Check for directory not empty (platform specific)
+	// Check for directory not empty and file used as a directory (platform
+	// specific); before IsExist, which also matches ENOTEMPTY
 	var pathErr *os.PathError
 	if errors.As(err, &pathErr) {
-		if strings.Contains(pathErr.Err.Error(), "not empty") ||
-			strings.Contains(pathErr.Err.Error(), "directory not empty") {
+		if strings.Contains(pathErr.Err.Error(), "not empty") {
+			return domain.ErrDirectoryNotEmpty
+		}
+		if strings.Contains(pathErr.Err.Error(), "not a directory") ||
+			strings.Contains(pathErr.Err.Error(), "directory name is invalid") {
 			return domain.ErrNotDirectory
 		}
 	}
 
+	if os.IsExist(err) {
+		return domain.ErrAlreadyExists
+	}
+
 	return err
 }
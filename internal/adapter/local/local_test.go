@@ -0,0 +1,18 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/adaptertest"
+)
+
+func TestAdapter_Conformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) adapter.Adapter {
+		a, err := New(t.TempDir())
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		return a
+	})
+}
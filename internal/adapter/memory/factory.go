@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// TransportType is the transport type handled by this package
+const TransportType domain.TransportType = "memory"
+
+func init() {
+	adapter.Register(Factory{})
+}
+
+// stores holds the named trees used by memory transports, so that every
+// endpoint of a transport (and every sync in the process) sees the same data
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*FS)
+)
+
+// Store returns the named tree, creating it if needed
+func Store(name string) *FS {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	s, ok := stores[name]
+	if !ok {
+		s = NewFS()
+		stores[name] = s
+	}
+	return s
+}
+
+// DropStore discards the named tree
+func DropStore(name string) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	delete(stores, name)
+}
+
+// Factory creates memory adapters
+// Transport config keys:
+//   - store: name of the shared tree (default: the transport name)
+//   - seed: local directory copied into an endpoint root when it is created empty
+type Factory struct{}
+
+// Supports returns true for the memory transport type
+func (Factory) Supports(transportType domain.TransportType) bool {
+	return transportType == TransportType
+}
+
+// ValidateTransport checks that a seed directory, if set, exists
+func (Factory) ValidateTransport(transport domain.Transport) error {
+	if seed := transport.Config["seed"]; seed != "" {
+		info, err := os.Stat(seed)
+		if err != nil {
+			return fmt.Errorf("memory seed: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("memory seed %s is not a directory", seed)
+		}
+	}
+	return nil
+}
+
+// Create returns an adapter rooted at root in the transport's shared tree
+func (Factory) Create(transport domain.Transport, root string) (adapter.Adapter, error) {
+	name := transport.Config["store"]
+	if name == "" {
+		name = transport.Name
+	}
+
+	a, err := Store(name).Adapter(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if seed := transport.Config["seed"]; seed != "" && len(a.Snapshot()) == 0 {
+		if err := a.Seed(seed); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Seed copies a local directory tree into the adapter root, keeping mtimes
+func (a *Adapter) Seed(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := a.mkdirAt(filepath.ToSlash(rel)); err != nil {
+				return err
+			}
+			return a.SetModTime(context.Background(), filepath.ToSlash(rel), info.ModTime())
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("memory seed: %w", err)
+		}
+		return a.WriteFile(filepath.ToSlash(rel), data, info.ModTime())
+	})
+}
+
+// mkdirAt creates a directory without error injection
+func (a *Adapter) mkdirAt(relPath string) error {
+	p, err := a.resolvePath(relPath)
+	if err != nil {
+		return err
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	return a.fs.mkdirAll(p)
+}
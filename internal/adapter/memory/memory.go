@@ -0,0 +1,563 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// Op identifies an adapter operation for error injection
+type Op string
+
+const (
+	OpList       Op = "list"
+	OpRead       Op = "read"
+	OpWrite      Op = "write"
+	OpDelete     Op = "delete"
+	OpStat       Op = "stat"
+	OpMkdir      Op = "mkdir"
+	OpExists     Op = "exists"
+	OpSetModTime Op = "setmodtime"
+	OpRename     Op = "rename"
+)
+
+// calculator computes checksums of any size, since content is already in memory
+var calculator = func() *checksum.DefaultCalculator {
+	opts := checksum.DefaultOptions()
+	opts.MaxSize = 0
+	return checksum.NewCalculator(opts)
+}()
+
+// entry is a file or directory held in memory
+type entry struct {
+	dir      bool
+	data     []byte
+	modTime  time.Time
+	checksum string // Overrides the computed checksum when set
+}
+
+// failureKey selects an injected error
+type failureKey struct {
+	op   Op
+	path string
+}
+
+// FS is an in-memory file tree shared by the adapters created from it
+// Paths are slash-separated and relative to the tree root ("" is the root)
+type FS struct {
+	mu       sync.RWMutex
+	entries  map[string]*entry
+	failures map[failureKey]error
+	now      func() time.Time
+}
+
+// NewFS creates an empty tree
+func NewFS() *FS {
+	return &FS{
+		entries:  map[string]*entry{"": {dir: true}},
+		failures: make(map[failureKey]error),
+		now:      time.Now,
+	}
+}
+
+// SetClock sets the time source used as mtime for writes and mkdirs
+func (fs *FS) SetClock(now func() time.Time) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.now = now
+}
+
+// Adapter returns an adapter rooted at root within the tree, creating root
+// if needed
+func (fs *FS) Adapter(root string) (*Adapter, error) {
+	root, err := cleanPath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.mkdirAll(root); err != nil {
+		return nil, err
+	}
+
+	return &Adapter{fs: fs, root: root, algorithm: checksum.SHA256}, nil
+}
+
+// mkdirAll creates p and its parents; callers must hold the write lock
+func (fs *FS) mkdirAll(p string) error {
+	if e, ok := fs.entries[p]; ok {
+		if !e.dir {
+			return domain.ErrNotDirectory
+		}
+		return nil
+	}
+	if err := fs.mkdirAll(parentOf(p)); err != nil {
+		return err
+	}
+	fs.entries[p] = &entry{dir: true, modTime: fs.now()}
+	return nil
+}
+
+// Adapter implements the adapter.Adapter interface over an in-memory tree
+// Besides the adapter operations it lets tests set mtimes and checksums,
+// inject errors per path and operation, and snapshot the tree
+type Adapter struct {
+	fs        *FS
+	root      string
+	algorithm checksum.Algorithm
+}
+
+// New creates an adapter over a fresh, empty tree
+func New() *Adapter {
+	a, _ := NewFS().Adapter("")
+	return a
+}
+
+// FS returns the tree this adapter is a view of
+func (a *Adapter) FS() *FS {
+	return a.fs
+}
+
+// SetChecksumAlgorithm sets the algorithm used for file checksums (default: sha256)
+func (a *Adapter) SetChecksumAlgorithm(algo checksum.Algorithm) error {
+	if !checksum.IsSupported(algo) {
+		return fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+	a.algorithm = algo
+	return nil
+}
+
+// cleanPath normalizes a relative path, rejecting escapes
+func cleanPath(p string) (string, error) {
+	if p == "" || p == "." {
+		return "", nil
+	}
+
+	p = path.Clean(filepath.ToSlash(p))
+	p = strings.TrimPrefix(p, "/")
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", domain.ErrPermissionDenied
+	}
+	if p == "." {
+		return "", nil
+	}
+	return p, nil
+}
+
+// parentOf returns the parent of a cleaned path ("" for top-level entries)
+func parentOf(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// resolvePath maps a path relative to the adapter root to a tree path
+func (a *Adapter) resolvePath(relPath string) (string, error) {
+	if path.IsAbs(filepath.ToSlash(relPath)) {
+		return "", domain.ErrPermissionDenied
+	}
+	p, err := cleanPath(relPath)
+	if err != nil {
+		return "", err
+	}
+	if a.root == "" {
+		return p, nil
+	}
+	if p == "" {
+		return a.root, nil
+	}
+	return a.root + "/" + p, nil
+}
+
+// relative maps a tree path back to a path relative to the adapter root
+func (a *Adapter) relative(p string) string {
+	if a.root == "" {
+		return p
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p, a.root), "/")
+}
+
+// begin resolves the path and returns any error injected for op on it
+func (a *Adapter) begin(ctx context.Context, op Op, relPath string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	p, err := a.resolvePath(relPath)
+	if err != nil {
+		return "", err
+	}
+
+	a.fs.mu.RLock()
+	defer a.fs.mu.RUnlock()
+	if err := a.fs.failures[failureKey{op, p}]; err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// fileInfo converts an entry to domain.FileInfo; callers must hold a lock
+func (a *Adapter) fileInfo(p string, e *entry) domain.FileInfo {
+	info := domain.FileInfo{
+		Path:    a.relative(p),
+		Type:    domain.FileTypeRegular,
+		Size:    int64(len(e.data)),
+		ModTime: e.modTime,
+	}
+	if e.dir {
+		info.Type = domain.FileTypeDirectory
+		info.Size = 0
+		return info
+	}
+
+	info.Checksum = e.checksum
+	if info.Checksum == "" {
+		info.Checksum, _ = calculator.Calculate(context.Background(), bytes.NewReader(e.data), a.algorithm)
+	}
+	info.ChecksumAlgorithm = string(a.algorithm)
+	return info
+}
+
+// List returns all files and directories under the given path
+func (a *Adapter) List(ctx context.Context, relPath string) ([]domain.FileInfo, error) {
+	p, err := a.begin(ctx, OpList, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	a.fs.mu.RLock()
+	defer a.fs.mu.RUnlock()
+
+	e, ok := a.fs.entries[p]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if !e.dir {
+		return nil, domain.ErrNotDirectory
+	}
+
+	var result []domain.FileInfo
+	for childPath, child := range a.fs.entries {
+		if childPath != "" && childPath != p && parentOf(childPath) == p {
+			result = append(result, a.fileInfo(childPath, child))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+
+	return result, nil
+}
+
+// Read opens a file for reading
+func (a *Adapter) Read(ctx context.Context, relPath string) (io.ReadCloser, error) {
+	p, err := a.begin(ctx, OpRead, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	a.fs.mu.RLock()
+	defer a.fs.mu.RUnlock()
+
+	e, ok := a.fs.entries[p]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if e.dir {
+		return nil, domain.ErrNotFile
+	}
+
+	return io.NopCloser(bytes.NewReader(e.data)), nil
+}
+
+// Write creates or overwrites a file
+// The content only replaces the old one once fully read, like an atomic rename
+func (a *Adapter) Write(ctx context.Context, relPath string, r io.Reader) error {
+	p, err := a.begin(ctx, OpWrite, relPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	return a.fs.writeFile(p, data, a.fs.now())
+}
+
+// writeFile stores a file, creating parents; callers must hold the write lock
+func (fs *FS) writeFile(p string, data []byte, modTime time.Time) error {
+	if p == "" {
+		return domain.ErrNotFile
+	}
+	if e, ok := fs.entries[p]; ok && e.dir {
+		return domain.ErrNotFile
+	}
+	if err := fs.mkdirAll(parentOf(p)); err != nil {
+		return err
+	}
+
+	fs.entries[p] = &entry{data: data, modTime: modTime}
+	return nil
+}
+
+// Delete removes a file or empty directory
+func (a *Adapter) Delete(ctx context.Context, relPath string) error {
+	p, err := a.begin(ctx, OpDelete, relPath)
+	if err != nil {
+		return err
+	}
+	if p == a.root {
+		return domain.ErrPermissionDenied
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+
+	e, ok := a.fs.entries[p]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if e.dir {
+		for childPath := range a.fs.entries {
+			if childPath != "" && parentOf(childPath) == p && childPath != p {
+				return fmt.Errorf("%w: %s", domain.ErrDirectoryNotEmpty, relPath)
+			}
+		}
+	}
+
+	delete(a.fs.entries, p)
+	return nil
+}
+
+// Stat returns metadata for a single path
+func (a *Adapter) Stat(ctx context.Context, relPath string) (domain.FileInfo, error) {
+	p, err := a.begin(ctx, OpStat, relPath)
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+
+	a.fs.mu.RLock()
+	defer a.fs.mu.RUnlock()
+
+	e, ok := a.fs.entries[p]
+	if !ok {
+		return domain.FileInfo{}, domain.ErrNotFound
+	}
+	return a.fileInfo(p, e), nil
+}
+
+// Mkdir creates a directory and any necessary parents
+func (a *Adapter) Mkdir(ctx context.Context, relPath string) error {
+	p, err := a.begin(ctx, OpMkdir, relPath)
+	if err != nil {
+		return err
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	return a.fs.mkdirAll(p)
+}
+
+// Exists checks if a path exists
+func (a *Adapter) Exists(ctx context.Context, relPath string) (bool, error) {
+	p, err := a.begin(ctx, OpExists, relPath)
+	if err != nil {
+		return false, err
+	}
+
+	a.fs.mu.RLock()
+	defer a.fs.mu.RUnlock()
+	_, ok := a.fs.entries[p]
+	return ok, nil
+}
+
+// Rename moves a file or directory with its contents, creating the parents
+// of newPath
+func (a *Adapter) Rename(ctx context.Context, oldPath, newPath string) error {
+	from, err := a.begin(ctx, OpRename, oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := a.resolvePath(newPath)
+	if err != nil {
+		return err
+	}
+	if from == a.root || to == a.root {
+		return domain.ErrPermissionDenied
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return fmt.Errorf("%w: cannot move %s into itself", domain.ErrPermissionDenied, oldPath)
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+
+	if _, ok := a.fs.entries[from]; !ok {
+		return domain.ErrNotFound
+	}
+	if _, ok := a.fs.entries[to]; ok {
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyExists, newPath)
+	}
+	if err := a.fs.mkdirAll(parentOf(to)); err != nil {
+		return err
+	}
+
+	moved := make(map[string]*entry)
+	for p, e := range a.fs.entries {
+		if p == from || strings.HasPrefix(p, from+"/") {
+			moved[to+strings.TrimPrefix(p, from)] = e
+			delete(a.fs.entries, p)
+		}
+	}
+	for p, e := range moved {
+		a.fs.entries[p] = e
+	}
+	return nil
+}
+
+// Capabilities describes the memory backend
+func (a *Adapter) Capabilities() adapter.Capabilities {
+	return adapter.Capabilities{
+		Checksums:   []checksum.Algorithm{checksum.SHA256, checksum.MD5},
+		SetModTime:  true,
+		Rename:      true,
+		AtomicWrite: true,
+	}
+}
+
+// Close releases any resources (no-op for memory adapter)
+func (a *Adapter) Close() error {
+	return nil
+}
+
+// Root returns the root of this adapter within its tree
+func (a *Adapter) Root() string {
+	return a.root
+}
+
+// WriteFile stores a file with the given content and mtime, creating parents
+func (a *Adapter) WriteFile(relPath string, data []byte, modTime time.Time) error {
+	p, err := a.resolvePath(relPath)
+	if err != nil {
+		return err
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	return a.fs.writeFile(p, append([]byte(nil), data...), modTime)
+}
+
+// SetModTime changes the mtime of a file or directory
+func (a *Adapter) SetModTime(ctx context.Context, relPath string, modTime time.Time) error {
+	p, err := a.begin(ctx, OpSetModTime, relPath)
+	if err != nil {
+		return err
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	e, ok := a.fs.entries[p]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	e.modTime = modTime
+	return nil
+}
+
+// SetChecksum overrides the checksum reported for a file
+// An empty sum restores the checksum computed from the content
+func (a *Adapter) SetChecksum(relPath string, sum string) error {
+	p, err := a.resolvePath(relPath)
+	if err != nil {
+		return err
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	e, ok := a.fs.entries[p]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if e.dir {
+		return domain.ErrNotFile
+	}
+	e.checksum = sum
+	return nil
+}
+
+// FailOn makes op on relPath return err until cleared
+// A nil err removes the injected failure
+func (a *Adapter) FailOn(op Op, relPath string, err error) {
+	p, resolveErr := a.resolvePath(relPath)
+	if resolveErr != nil {
+		return
+	}
+
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	if err == nil {
+		delete(a.fs.failures, failureKey{op, p})
+		return
+	}
+	a.fs.failures[failureKey{op, p}] = err
+}
+
+// ClearFailures removes all injected failures from the tree
+func (a *Adapter) ClearFailures() {
+	a.fs.mu.Lock()
+	defer a.fs.mu.Unlock()
+	a.fs.failures = make(map[failureKey]error)
+}
+
+// Entry is one file or directory in a snapshot
+type Entry struct {
+	domain.FileInfo
+
+	// Content holds the file data (nil for directories)
+	Content []byte
+}
+
+// Snapshot returns every entry under the adapter root, sorted by path
+// The root itself is not included
+func (a *Adapter) Snapshot() []Entry {
+	a.fs.mu.RLock()
+	defer a.fs.mu.RUnlock()
+
+	prefix := ""
+	if a.root != "" {
+		prefix = a.root + "/"
+	}
+
+	var result []Entry
+	for p, e := range a.fs.entries {
+		if p == "" || p == a.root || !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		snap := Entry{FileInfo: a.fileInfo(p, e)}
+		if !e.dir {
+			snap.Content = append([]byte(nil), e.data...)
+		}
+		result = append(result, snap)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+
+	return result
+}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/adaptertest"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+func TestAdapter_Operations(t *testing.T) {
+	a := New()
+	ctx := context.Background()
+
+	if err := a.Write(ctx, "dir/file.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := a.Mkdir(ctx, "empty/nested"); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	entries, err := a.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "dir" || entries[1].Path != "empty" || !entries[0].IsDir() {
+		t.Errorf("List root = %+v", entries)
+	}
+
+	info, err := a.Stat(ctx, "dir/file.txt")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size != 5 || info.ChecksumAlgorithm != "sha256" || info.Checksum == "" {
+		t.Errorf("Stat = %+v", info)
+	}
+
+	r, err := a.Read(ctx, "dir/file.txt")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	if string(data) != "hello" {
+		t.Errorf("Read = %q", data)
+	}
+
+	if _, err := a.Read(ctx, "dir"); !errors.Is(err, domain.ErrNotFile) {
+		t.Errorf("Read dir error = %v, want ErrNotFile", err)
+	}
+	if _, err := a.List(ctx, "dir/file.txt"); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("List file error = %v, want ErrNotDirectory", err)
+	}
+	if err := a.Delete(ctx, "dir"); !errors.Is(err, domain.ErrDirectoryNotEmpty) {
+		t.Errorf("Delete non-empty dir error = %v, want ErrDirectoryNotEmpty", err)
+	}
+	if err := a.Write(ctx, "dir/file.txt/child", strings.NewReader("x")); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("Write under file error = %v, want ErrNotDirectory", err)
+	}
+	if _, err := a.Stat(ctx, "../escape"); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("Stat escape error = %v, want ErrPermissionDenied", err)
+	}
+
+	if err := a.Delete(ctx, "dir/file.txt"); err != nil {
+		t.Fatalf("Delete file failed: %v", err)
+	}
+	if err := a.Delete(ctx, "dir"); err != nil {
+		t.Fatalf("Delete empty dir failed: %v", err)
+	}
+	if exists, _ := a.Exists(ctx, "dir"); exists {
+		t.Error("dir should be deleted")
+	}
+	if err := a.Delete(ctx, "dir"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Delete missing error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdapter_Controls(t *testing.T) {
+	a := New()
+	ctx := context.Background()
+
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	a.FS().SetClock(func() time.Time { return clock })
+	a.Write(ctx, "written.txt", strings.NewReader("w"))
+	if info, _ := a.Stat(ctx, "written.txt"); !info.ModTime.Equal(clock) {
+		t.Errorf("write mtime = %v, want clock %v", info.ModTime, clock)
+	}
+
+	old := clock.Add(-time.Hour)
+	if err := a.WriteFile("seeded.txt", []byte("s"), old); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if info, _ := a.Stat(ctx, "seeded.txt"); !info.ModTime.Equal(old) {
+		t.Errorf("seeded mtime = %v, want %v", info.ModTime, old)
+	}
+	newer := clock.Add(time.Hour)
+	a.SetModTime(ctx, "seeded.txt", newer)
+	if info, _ := a.Stat(ctx, "seeded.txt"); !info.ModTime.Equal(newer) {
+		t.Errorf("SetModTime mtime = %v, want %v", info.ModTime, newer)
+	}
+
+	a.SetChecksum("seeded.txt", "forced")
+	if info, _ := a.Stat(ctx, "seeded.txt"); info.Checksum != "forced" {
+		t.Errorf("checksum = %q, want forced", info.Checksum)
+	}
+	a.SetChecksum("seeded.txt", "")
+	a.SetChecksumAlgorithm(checksum.MD5)
+	if info, _ := a.Stat(ctx, "seeded.txt"); info.Checksum != "03c7c0ace395d80182db07ae2c30f034" || info.ChecksumAlgorithm != "md5" {
+		t.Errorf("md5 checksum = %+v", info)
+	}
+
+	injected := errors.New("disk on fire")
+	a.FailOn(OpWrite, "seeded.txt", injected)
+	a.FailOn(OpList, "", injected)
+	if err := a.Write(ctx, "seeded.txt", strings.NewReader("x")); !errors.Is(err, injected) {
+		t.Errorf("Write error = %v, want injected", err)
+	}
+	if _, err := a.List(ctx, "."); !errors.Is(err, injected) {
+		t.Errorf("List error = %v, want injected", err)
+	}
+	if _, err := a.Read(ctx, "seeded.txt"); err != nil {
+		t.Errorf("Read should not be affected: %v", err)
+	}
+	a.FailOn(OpWrite, "seeded.txt", nil)
+	if err := a.Write(ctx, "seeded.txt", strings.NewReader("x")); err != nil {
+		t.Errorf("Write after clearing = %v", err)
+	}
+	a.ClearFailures()
+	if _, err := a.List(ctx, ""); err != nil {
+		t.Errorf("List after ClearFailures = %v", err)
+	}
+}
+
+func TestFS_SharedViews(t *testing.T) {
+	fs := NewFS()
+	src, err := fs.Adapter("/sim/src")
+	if err != nil {
+		t.Fatalf("Adapter failed: %v", err)
+	}
+	whole, _ := fs.Adapter("")
+	ctx := context.Background()
+
+	src.Write(ctx, "a/b.txt", strings.NewReader("b"))
+
+	snap := src.Snapshot()
+	if len(snap) != 2 || snap[0].Path != "a" || snap[1].Path != "a/b.txt" || string(snap[1].Content) != "b" {
+		t.Errorf("src snapshot = %+v", snap)
+	}
+	if exists, _ := whole.Exists(ctx, "sim/src/a/b.txt"); !exists {
+		t.Error("write through a view should be visible from the tree root")
+	}
+	if err := src.Delete(ctx, ""); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("Delete root error = %v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestFactory(t *testing.T) {
+	defer DropStore("factory-test")
+
+	seed := t.TempDir()
+	os.MkdirAll(filepath.Join(seed, "docs"), 0755)
+	os.WriteFile(filepath.Join(seed, "docs", "readme.md"), []byte("seeded"), 0644)
+	mtime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(seed, "docs", "readme.md"), mtime, mtime)
+
+	f := Factory{}
+	transport := domain.Transport{
+		Name:   "sim",
+		Type:   TransportType,
+		Config: map[string]string{"store": "factory-test", "seed": seed},
+	}
+	if err := f.ValidateTransport(transport); err != nil {
+		t.Fatalf("ValidateTransport failed: %v", err)
+	}
+
+	created, err := f.Create(transport, "src")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	info, err := created.Stat(context.Background(), "docs/readme.md")
+	if err != nil || info.Size != 6 || !info.ModTime.Equal(mtime) {
+		t.Errorf("seeded file = %+v, %v", info, err)
+	}
+
+	// Other endpoints of the store share the tree but are not seeded again
+	created.Write(context.Background(), "docs/readme.md", strings.NewReader("changed"))
+	again, _ := f.Create(transport, "src")
+	r, _ := again.Read(context.Background(), "docs/readme.md")
+	if data, _ := io.ReadAll(r); string(data) != "changed" {
+		t.Errorf("re-created adapter content = %q, want changed", data)
+	}
+
+	transport.Config["seed"] = filepath.Join(seed, "missing")
+	if err := f.ValidateTransport(transport); err == nil {
+		t.Error("ValidateTransport should reject a missing seed")
+	}
+}
+
+func TestAdapter_Conformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) adapter.Adapter {
+		return New()
+	})
+}
@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// TransportValidator is implemented by factories that can check a transport's
+// settings before any adapter is created
+type TransportValidator interface {
+	// ValidateTransport returns an error if the transport config is unusable
+	ValidateTransport(transport domain.Transport) error
+}
+
+// Registry maps transport types to the factories that create their adapters
+type Registry struct {
+	mu        sync.RWMutex
+	factories []AdapterFactory
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a factory
+// Factories registered later take precedence for the types they support
+func (r *Registry) Register(factory AdapterFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories = append(r.factories, factory)
+}
+
+// Lookup returns the factory for a transport type
+func (r *Registry) Lookup(transportType domain.TransportType) (AdapterFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.factories) - 1; i >= 0; i-- {
+		if r.factories[i].Supports(transportType) {
+			return r.factories[i], true
+		}
+	}
+	return nil, false
+}
+
+// Supports returns true if a registered factory handles the transport type
+func (r *Registry) Supports(transportType domain.TransportType) bool {
+	_, ok := r.Lookup(transportType)
+	return ok
+}
+
+// Validate checks that the transport type is registered and, if its factory
+// implements TransportValidator, that the transport config is usable
+func (r *Registry) Validate(transport domain.Transport) error {
+	factory, ok := r.Lookup(transport.Type)
+	if !ok {
+		return fmt.Errorf("unknown transport type: %s", transport.Type)
+	}
+	if v, ok := factory.(TransportValidator); ok {
+		return v.ValidateTransport(transport)
+	}
+	return nil
+}
+
+// Create builds an adapter for the transport rooted at root
+func (r *Registry) Create(transport domain.Transport, root string) (Adapter, error) {
+	factory, ok := r.Lookup(transport.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown transport type: %s", transport.Type)
+	}
+	return factory.Create(transport, root)
+}
+
+// defaultRegistry holds the factories registered by backend packages
+var defaultRegistry = NewRegistry()
+
+// Default returns the process-wide registry that backends register into
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register adds a factory to the default registry
+// Backend packages call it from init, so importing a backend enables it
+func Register(factory AdapterFactory) {
+	defaultRegistry.Register(factory)
+}
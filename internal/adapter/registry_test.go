@@ -0,0 +1,71 @@
+package adapter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// stubFactory supports a single type and records what it was asked to create
+type stubFactory struct {
+	transportType domain.TransportType
+	validateErr   error
+	created       []string
+}
+
+func (f *stubFactory) Supports(t domain.TransportType) bool {
+	return t == f.transportType
+}
+
+func (f *stubFactory) Create(transport domain.Transport, root string) (Adapter, error) {
+	f.created = append(f.created, root)
+	return nil, nil
+}
+
+// validatingFactory adds TransportValidator to stubFactory
+type validatingFactory struct {
+	*stubFactory
+}
+
+func (f validatingFactory) ValidateTransport(domain.Transport) error {
+	return f.validateErr
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	if r.Supports("mem") {
+		t.Fatal("empty registry should support nothing")
+	}
+	if err := r.Validate(domain.Transport{Type: "mem"}); err == nil {
+		t.Error("Validate should reject unregistered type")
+	}
+	if _, err := r.Create(domain.Transport{Type: "mem"}, "/"); err == nil {
+		t.Error("Create should reject unregistered type")
+	}
+
+	first := &stubFactory{transportType: "mem"}
+	r.Register(first)
+	if !r.Supports("mem") || r.Supports("other") {
+		t.Error("Supports should reflect registered factories")
+	}
+	if err := r.Validate(domain.Transport{Type: "mem"}); err != nil {
+		t.Errorf("Validate without validator = %v, want nil", err)
+	}
+	r.Create(domain.Transport{Type: "mem"}, "root-a")
+	if len(first.created) != 1 || first.created[0] != "root-a" {
+		t.Errorf("first factory created %v", first.created)
+	}
+
+	// A later registration overrides and may validate config
+	second := &stubFactory{transportType: "mem", validateErr: errors.New("bad config")}
+	r.Register(validatingFactory{second})
+	r.Create(domain.Transport{Type: "mem"}, "root-b")
+	if len(second.created) != 1 || len(first.created) != 1 {
+		t.Errorf("created first=%v second=%v, want second to win", first.created, second.created)
+	}
+	if err := r.Validate(domain.Transport{Type: "mem"}); err == nil || err.Error() != "bad config" {
+		t.Errorf("Validate = %v, want validator error", err)
+	}
+}
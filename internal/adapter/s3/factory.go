@@ -0,0 +1,43 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// TransportType is the transport type handled by this package
+const TransportType domain.TransportType = "s3"
+
+func init() {
+	adapter.Register(Factory{})
+}
+
+// Factory creates S3 adapters from transport config (see OptionsFromConfig)
+type Factory struct{}
+
+// Supports returns true for the s3 transport type
+func (Factory) Supports(transportType domain.TransportType) bool {
+	return transportType == TransportType
+}
+
+// ValidateTransport checks the connection settings without connecting
+func (Factory) ValidateTransport(transport domain.Transport) error {
+	_, err := OptionsFromConfig(transport.Config)
+	return err
+}
+
+// Create returns an adapter for the "bucket/prefix" root
+func (Factory) Create(transport domain.Transport, root string) (adapter.Adapter, error) {
+	opts, err := OptionsFromConfig(transport.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := New(context.Background(), opts, root)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
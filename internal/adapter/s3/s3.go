@@ -0,0 +1,728 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/logger"
+)
+
+const (
+	// DefaultRegion is used when no region is configured
+	DefaultRegion = "us-east-1"
+
+	// DefaultPartSize is the multipart chunk size; smaller files use a single PUT
+	DefaultPartSize = 8 * 1024 * 1024
+
+	// MinPartSize is the smallest part S3 accepts (except for the last part)
+	MinPartSize = 5 * 1024 * 1024
+
+	// maxCopySize is the largest object CopyObject accepts
+	maxCopySize = 5 * 1024 * 1024 * 1024
+
+	// md5MetaKey is the user metadata key (x-amz-meta-syncrules-md5) holding
+	// the MD5 of objects uploaded in parts, whose ETags are not content hashes
+	md5MetaKey = "syncrules-md5"
+)
+
+// Options configures the connection to an S3-compatible service
+type Options struct {
+	// Endpoint is the service URL, e.g. "http://localhost:9000" for MinIO
+	// (empty = AWS)
+	Endpoint string
+
+	// Region is the bucket region (empty = DefaultRegion)
+	Region string
+
+	// AccessKeyID and SecretAccessKey are static credentials
+	// (empty = AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY)
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is used with temporary credentials (optional)
+	SessionToken string
+
+	// PathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint
+	PathStyle bool
+
+	// PartSize is the multipart chunk size (0 = DefaultPartSize)
+	PartSize int64
+
+	// HTTPClient overrides the HTTP client (optional)
+	HTTPClient *http.Client
+}
+
+// OptionsFromConfig builds connection options from a transport's config map
+// Keys: endpoint, region, access_key_id, secret_access_key, session_token,
+// path_style, part_size_mb
+func OptionsFromConfig(config map[string]string) (Options, error) {
+	opts := Options{
+		Endpoint:        config["endpoint"],
+		Region:          config["region"],
+		AccessKeyID:     config["access_key_id"],
+		SecretAccessKey: config["secret_access_key"],
+		SessionToken:    config["session_token"],
+		// Self-hosted services rarely have per-bucket DNS names
+		PathStyle: config["endpoint"] != "",
+	}
+
+	if pathStyle := config["path_style"]; pathStyle != "" {
+		v, err := strconv.ParseBool(pathStyle)
+		if err != nil {
+			return opts, fmt.Errorf("invalid s3 path_style: %s", pathStyle)
+		}
+		opts.PathStyle = v
+	}
+
+	if partSize := config["part_size_mb"]; partSize != "" {
+		mb, err := strconv.ParseInt(partSize, 10, 64)
+		if err != nil || mb*1024*1024 < MinPartSize {
+			return opts, fmt.Errorf("invalid s3 part_size_mb: %s (minimum 5)", partSize)
+		}
+		opts.PartSize = mb * 1024 * 1024
+	}
+
+	return opts, nil
+}
+
+// Adapter implements the adapter.Adapter interface over an S3 bucket
+// Keys are mapped onto directories by "/": a key "a/b.txt" appears as
+// directory "a" containing file "b.txt". Empty directories are kept as
+// zero-byte marker objects whose key ends in "/"
+type Adapter struct {
+	client    *awss3.Client
+	bucket    string
+	prefix    string // Key prefix without trailing slash ("" = bucket root)
+	partSize  int64
+	algorithm checksum.Algorithm
+}
+
+// New creates an S3 adapter rooted at root, given as "bucket" or "bucket/prefix"
+// The bucket must exist; the prefix need not
+func New(ctx context.Context, opts Options, root string) (*Adapter, error) {
+	bucket, prefix, err := splitRoot(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.AccessKeyID == "" && opts.SecretAccessKey == "" {
+		opts.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		opts.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		opts.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 transport requires access_key_id and secret_access_key")
+	}
+	if opts.Region == "" {
+		opts.Region = DefaultRegion
+	}
+	if opts.PartSize <= 0 {
+		opts.PartSize = DefaultPartSize
+	}
+
+	s3Opts := awss3.Options{
+		Region: opts.Region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     opts.AccessKeyID,
+				SecretAccessKey: opts.SecretAccessKey,
+				SessionToken:    opts.SessionToken,
+				Source:          "syncrules",
+			}, nil
+		}),
+		UsePathStyle: opts.PathStyle,
+		// Many S3-compatible services reject the SDK's default trailing checksums
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	}
+	if opts.Endpoint != "" {
+		s3Opts.BaseEndpoint = aws.String(opts.Endpoint)
+	}
+	if opts.HTTPClient != nil {
+		s3Opts.HTTPClient = opts.HTTPClient
+	}
+
+	a := &Adapter{
+		client:    awss3.New(s3Opts),
+		bucket:    bucket,
+		prefix:    prefix,
+		partSize:  opts.PartSize,
+		algorithm: checksum.SHA256,
+	}
+
+	// Verify bucket exists and is reachable
+	if _, err := a.client.HeadBucket(ctx, &awss3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return nil, a.mapError(err)
+	}
+
+	return a, nil
+}
+
+// SetChecksumAlgorithm sets the algorithm used for file checksums (default: sha256)
+// Only md5 yields checksums: they are read from the ETags of objects uploaded
+// in a single part, which S3 sets to the MD5 of the content, and by
+// StatWithChecksum from the metadata of objects uploaded in parts
+func (a *Adapter) SetChecksumAlgorithm(algo checksum.Algorithm) error {
+	if !checksum.IsSupported(algo) {
+		return fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+	a.algorithm = algo
+	return nil
+}
+
+// Capabilities describes the S3 backend
+func (a *Adapter) Capabilities() adapter.Capabilities {
+	return adapter.Capabilities{
+		Checksums:       []checksum.Algorithm{checksum.MD5},
+		ServerChecksums: true,
+		AtomicWrite:     true,
+		ETags:           true,
+	}
+}
+
+// splitRoot separates "bucket/prefix" into its parts
+func splitRoot(root string) (bucket, prefix string, err error) {
+	root = strings.Trim(filepath.ToSlash(root), "/")
+	bucket, prefix, _ = strings.Cut(root, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("s3 root must start with a bucket name")
+	}
+	if prefix != "" {
+		prefix = path.Clean(prefix)
+		if prefix == "." {
+			prefix = ""
+		}
+	}
+	return bucket, prefix, nil
+}
+
+// resolveKey safely maps a relative path to an object key within the prefix
+// Returns error if path attempts to escape the prefix
+func (a *Adapter) resolveKey(relPath string) (string, error) {
+	if relPath == "" || relPath == "." {
+		return a.prefix, nil
+	}
+
+	relPath = path.Clean(filepath.ToSlash(relPath))
+	if path.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", domain.ErrPermissionDenied
+	}
+	if relPath == "." {
+		return a.prefix, nil
+	}
+
+	if a.prefix == "" {
+		return relPath, nil
+	}
+	return a.prefix + "/" + relPath, nil
+}
+
+// dirKey returns the key prefix under which a directory's children live
+func dirKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// List returns all files and directories directly under the given path
+func (a *Adapter) List(ctx context.Context, p string) ([]domain.FileInfo, error) {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return nil, err
+	}
+	prefix := dirKey(key)
+
+	var result []domain.FileInfo
+	found := key == a.prefix // The root always exists
+
+	paginator := awss3.NewListObjectsV2Paginator(a.client, &awss3.ListObjectsV2Input{
+		Bucket:    aws.String(a.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, a.mapError(err)
+		}
+
+		for _, cp := range page.CommonPrefixes {
+			found = true
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(cp.Prefix), prefix), "/")
+			if name == "" {
+				continue
+			}
+			result = append(result, domain.FileInfo{
+				Path: path.Join(p, name),
+				Type: domain.FileTypeDirectory,
+			})
+		}
+
+		for _, obj := range page.Contents {
+			found = true
+			name := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if name == "" {
+				continue // Directory marker of p itself
+			}
+			result = append(result, a.fileInfoFromObject(path.Join(p, name), obj))
+		}
+	}
+
+	if !found {
+		// Either nothing exists at p or p is a file
+		if _, err := a.headObject(ctx, key); err == nil {
+			return nil, domain.ErrNotDirectory
+		}
+		return nil, domain.ErrNotFound
+	}
+
+	return result, nil
+}
+
+// Read opens an object for reading
+func (a *Adapter) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := a.client.GetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		err = a.mapError(err)
+		if errors.Is(err, domain.ErrNotFound) {
+			if isDir, dirErr := a.isDir(ctx, key); dirErr == nil && isDir {
+				return nil, domain.ErrNotFile
+			}
+		}
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+// Write creates or overwrites an object
+// Objects up to the part size are sent in one PUT; larger ones use a
+// multipart upload that only becomes visible once complete
+func (a *Adapter) Write(ctx context.Context, p string, r io.Reader) error {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return err
+	}
+	if key == a.prefix {
+		return domain.ErrNotFile
+	}
+
+	buf := make([]byte, a.partSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err := a.client.PutObject(ctx, &awss3.PutObjectInput{
+			Bucket:        aws.String(a.bucket),
+			Key:           aws.String(key),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		return a.mapError(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.writeMultipart(ctx, key, buf, r)
+}
+
+// writeMultipart uploads r in parts, starting with the already-read first part
+// The upload is aborted on any error so no partial object is left behind
+// The MD5 of the content is then stored in the object's metadata
+func (a *Adapter) writeMultipart(ctx context.Context, key string, first []byte, r io.Reader) error {
+	created, err := a.client.CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return a.mapError(err)
+	}
+	uploadID := created.UploadId
+
+	abort := func(cause error) error {
+		// Abort even if ctx was cancelled, otherwise the parts keep using storage
+		a.client.AbortMultipartUpload(context.WithoutCancel(ctx), &awss3.AbortMultipartUploadInput{
+			Bucket:   aws.String(a.bucket),
+			Key:      aws.String(key),
+			UploadId: uploadID,
+		})
+		return cause
+	}
+
+	var parts []types.CompletedPart
+	hash := md5.New()
+	size := int64(0)
+	buf, n := first, len(first)
+	for partNumber := int32(1); ; partNumber++ {
+		hash.Write(buf[:n])
+		size += int64(n)
+		out, err := a.client.UploadPart(ctx, &awss3.UploadPartInput{
+			Bucket:        aws.String(a.bucket),
+			Key:           aws.String(key),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(partNumber),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		if err != nil {
+			return abort(a.mapError(err))
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       out.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+
+		n, err = io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return abort(err)
+		}
+	}
+
+	completed, err := a.client.CompleteMultipartUpload(ctx, &awss3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(a.bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(a.mapError(err))
+	}
+
+	// The object is in place; without the checksum it is only compared by
+	// size and mtime, so a failure here does not fail the write
+	if size <= maxCopySize {
+		if err := a.setMD5(ctx, key, completed.ETag, hex.EncodeToString(hash.Sum(nil))); err != nil {
+			logger.Get().Warn("failed to store checksum of s3 object", "key", key, "error", err)
+		}
+	}
+	return nil
+}
+
+// setMD5 stores sum as the MD5 metadata of an object by copying it onto
+// itself, which is how S3 replaces metadata; it only applies while the object
+// still has the given ETag
+func (a *Adapter) setMD5(ctx context.Context, key string, etag *string, sum string) error {
+	segments := strings.Split(a.bucket+"/"+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	_, err := a.client.CopyObject(ctx, &awss3.CopyObjectInput{
+		Bucket:            aws.String(a.bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String(strings.Join(segments, "/")),
+		CopySourceIfMatch: etag,
+		MetadataDirective: types.MetadataDirectiveReplace,
+		Metadata:          map[string]string{md5MetaKey: sum},
+	})
+	return a.mapError(err)
+}
+
+// Delete removes an object or an empty directory
+func (a *Adapter) Delete(ctx context.Context, p string) error {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.headObject(ctx, key); err == nil {
+		if err := a.deleteObject(ctx, key); err != nil {
+			return err
+		}
+		return a.keepParent(ctx, key)
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+
+	// Not an object: only an empty directory (just its marker) can be deleted
+	out, err := a.client.ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+		Bucket:  aws.String(a.bucket),
+		Prefix:  aws.String(dirKey(key)),
+		MaxKeys: aws.Int32(2),
+	})
+	if err != nil {
+		return a.mapError(err)
+	}
+	switch {
+	case len(out.Contents) == 0:
+		return domain.ErrNotFound
+	case len(out.Contents) > 1 || aws.ToString(out.Contents[0].Key) != dirKey(key):
+		return fmt.Errorf("%w: %s", domain.ErrDirectoryNotEmpty, p)
+	}
+
+	return a.deleteObject(ctx, dirKey(key))
+}
+
+// keepParent adds a marker for the parent of a deleted key if nothing else
+// keeps it, so directories do not vanish with their last file
+func (a *Adapter) keepParent(ctx context.Context, key string) error {
+	parent := path.Dir(key)
+	if parent == "." || parent == a.prefix {
+		return nil
+	}
+
+	isDir, err := a.isDir(ctx, parent)
+	if err != nil || isDir {
+		return err
+	}
+	return a.putMarker(ctx, parent)
+}
+
+func (a *Adapter) deleteObject(ctx context.Context, key string) error {
+	_, err := a.client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(key),
+	})
+	return a.mapError(err)
+}
+
+// Stat returns metadata for a single path
+func (a *Adapter) Stat(ctx context.Context, p string) (domain.FileInfo, error) {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+	relPath := cleanPath(p)
+
+	if key != a.prefix {
+		out, err := a.headObject(ctx, key)
+		if err == nil {
+			return a.fileInfoFromHead(relPath, out), nil
+		}
+		if !errors.Is(err, domain.ErrNotFound) {
+			return domain.FileInfo{}, err
+		}
+
+		isDir, err := a.isDir(ctx, key)
+		if err != nil {
+			return domain.FileInfo{}, err
+		}
+		if !isDir {
+			return domain.FileInfo{}, domain.ErrNotFound
+		}
+	}
+
+	return domain.FileInfo{Path: relPath, Type: domain.FileTypeDirectory}, nil
+}
+
+// StatWithChecksum returns metadata including the MD5 checksum of a file,
+// from its ETag or, for objects uploaded in parts, its metadata
+// No checksum is filled unless md5 is the configured algorithm, or if the
+// object was uploaded in parts by another client
+func (a *Adapter) StatWithChecksum(ctx context.Context, p string) (domain.FileInfo, error) {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+	if key == a.prefix {
+		return a.Stat(ctx, p)
+	}
+
+	out, err := a.headObject(ctx, key)
+	if errors.Is(err, domain.ErrNotFound) {
+		return a.Stat(ctx, p) // A directory, or nothing
+	}
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+
+	info := a.fileInfoFromHead(cleanPath(p), out)
+	if info.Checksum != "" || a.algorithm != checksum.MD5 {
+		return info, nil
+	}
+	if sum := out.Metadata[md5MetaKey]; len(sum) == 32 {
+		if _, err := hex.DecodeString(sum); err == nil {
+			info.Checksum = strings.ToLower(sum)
+			info.ChecksumAlgorithm = string(checksum.MD5)
+		}
+	}
+	return info, nil
+}
+
+// Mkdir creates a directory marker so empty directories survive
+// Parents need no markers: they exist implicitly through the child's key
+func (a *Adapter) Mkdir(ctx context.Context, p string) error {
+	key, err := a.resolveKey(p)
+	if err != nil {
+		return err
+	}
+	if key == a.prefix {
+		return nil
+	}
+
+	return a.putMarker(ctx, key)
+}
+
+// putMarker stores the empty marker object for the directory at key
+func (a *Adapter) putMarker(ctx context.Context, key string) error {
+	_, err := a.client.PutObject(ctx, &awss3.PutObjectInput{
+		Bucket:        aws.String(a.bucket),
+		Key:           aws.String(dirKey(key)),
+		Body:          bytes.NewReader(nil),
+		ContentLength: aws.Int64(0),
+	})
+	return a.mapError(err)
+}
+
+// Exists checks if a path exists
+func (a *Adapter) Exists(ctx context.Context, p string) (bool, error) {
+	_, err := a.Stat(ctx, p)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Close releases any resources (no-op for S3 adapter)
+func (a *Adapter) Close() error {
+	return nil
+}
+
+// Root returns the bucket and prefix of this adapter
+func (a *Adapter) Root() string {
+	if a.prefix == "" {
+		return a.bucket
+	}
+	return a.bucket + "/" + a.prefix
+}
+
+// headObject fetches object metadata, mapping a missing key to ErrNotFound
+func (a *Adapter) headObject(ctx context.Context, key string) (*awss3.HeadObjectOutput, error) {
+	out, err := a.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, a.mapError(err)
+	}
+	return out, nil
+}
+
+// isDir reports whether any object lives under key as a directory
+func (a *Adapter) isDir(ctx context.Context, key string) (bool, error) {
+	out, err := a.client.ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+		Bucket:  aws.String(a.bucket),
+		Prefix:  aws.String(dirKey(key)),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, a.mapError(err)
+	}
+	return len(out.Contents) > 0, nil
+}
+
+// fileInfoFromObject converts a listed object to domain.FileInfo
+func (a *Adapter) fileInfoFromObject(p string, obj types.Object) domain.FileInfo {
+	info := domain.FileInfo{
+		Path:    p,
+		Type:    domain.FileTypeRegular,
+		Size:    aws.ToInt64(obj.Size),
+		ModTime: aws.ToTime(obj.LastModified),
+		ETag:    trimETag(obj.ETag),
+	}
+	a.setChecksum(&info)
+	return info
+}
+
+// fileInfoFromHead converts the metadata of an object to domain.FileInfo
+func (a *Adapter) fileInfoFromHead(p string, out *awss3.HeadObjectOutput) domain.FileInfo {
+	info := domain.FileInfo{
+		Path:    p,
+		Type:    domain.FileTypeRegular,
+		Size:    aws.ToInt64(out.ContentLength),
+		ModTime: aws.ToTime(out.LastModified),
+		ETag:    trimETag(out.ETag),
+	}
+	a.setChecksum(&info)
+	return info
+}
+
+// cleanPath normalizes a relative path as reported in domain.FileInfo
+func cleanPath(p string) string {
+	relPath := strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "./")
+	if relPath == "." {
+		return ""
+	}
+	return relPath
+}
+
+// setChecksum fills the MD5 checksum from the ETag when md5 is configured
+// Multipart ETags ("<hash>-<parts>") are not content hashes and are skipped
+func (a *Adapter) setChecksum(info *domain.FileInfo) {
+	if a.algorithm != checksum.MD5 || len(info.ETag) != 32 {
+		return
+	}
+	if _, err := hex.DecodeString(info.ETag); err != nil {
+		return
+	}
+	info.Checksum = strings.ToLower(info.ETag)
+	info.ChecksumAlgorithm = string(checksum.MD5)
+}
+
+// trimETag strips the quotes S3 puts around ETags
+func trimETag(etag *string) string {
+	return strings.Trim(aws.ToString(etag), `"`)
+}
+
+// mapError converts S3 errors to domain errors
+func (a *Adapter) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NoSuchBucket", "NotFound":
+			return domain.ErrNotFound
+		case "AccessDenied", "Forbidden", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+			return fmt.Errorf("%w: %v", domain.ErrPermissionDenied, err)
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusNotFound:
+			return domain.ErrNotFound
+		case http.StatusForbidden:
+			return fmt.Errorf("%w: %v", domain.ErrPermissionDenied, err)
+		}
+	}
+
+	return err
+}
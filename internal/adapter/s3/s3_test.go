@@ -0,0 +1,577 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/adaptertest"
+	"github.com/Ning0612/Syncrules/internal/adapter/local"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/core/rule"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// fakeS3 is a minimal in-process S3 server for path-style requests
+// It supports what the adapter uses: HeadBucket, ListObjectsV2, Get/Head/
+// Put/Copy/DeleteObject and multipart uploads. Listings are paged in small
+// pages to exercise pagination
+type fakeS3 struct {
+	mu       sync.Mutex
+	bucket   string
+	objects  map[string]fakeObject
+	uploads  map[string]map[int][]byte
+	nextID   int
+	pageSize int
+
+	completed int // Multipart uploads completed
+	aborted   int // Multipart uploads aborted
+}
+
+type fakeObject struct {
+	data    []byte
+	etag    string
+	modTime time.Time
+	meta    map[string]string
+}
+
+// metadataOf returns the x-amz-meta-* headers of a request
+func metadataOf(header http.Header) map[string]string {
+	meta := make(map[string]string)
+	for k, v := range header {
+		if name, ok := strings.CutPrefix(strings.ToLower(k), "x-amz-meta-"); ok {
+			meta[name] = v[0]
+		}
+	}
+	return meta
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:   bucket,
+		objects:  make(map[string]fakeObject),
+		uploads:  make(map[string]map[int][]byte),
+		pageSize: 2,
+	}
+}
+
+type listResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	KeyCount              int            `xml:"KeyCount"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	Contents              []listObject   `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type listObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	q := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, q.Get("prefix"), q.Get("delimiter"), q.Get("continuation-token"), q.Get("max-keys"))
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucket, key, id)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		parts, ok := f.uploads[q.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		parts[n] = data
+		w.Header().Set("ETag", etagOf(data))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		parts, ok := f.uploads[q.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var data []byte
+		for i := 1; i <= len(parts); i++ {
+			data = append(data, parts[i]...)
+		}
+		delete(f.uploads, q.Get("uploadId"))
+		f.completed++
+		etag := fmt.Sprintf(`"%s-%d"`, strings.Trim(etagOf(data), `"`), len(parts))
+		f.objects[key] = fakeObject{data: data, etag: etag, modTime: time.Now().UTC()}
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>`, bucket, key, etag)
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(f.uploads, q.Get("uploadId"))
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		// Copies keep the source ETag, as some services do for objects
+		// uploaded in parts
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		obj, ok := f.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), f.bucket+"/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if match := r.Header.Get("X-Amz-Copy-Source-If-Match"); match != "" && match != obj.etag {
+			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			obj.meta = metadataOf(r.Header)
+		}
+		obj.modTime = time.Now().UTC()
+		f.objects[key] = obj
+		fmt.Fprintf(w, `<CopyObjectResult><ETag>%s</ETag><LastModified>%s</LastModified></CopyObjectResult>`, obj.etag, obj.modTime.Format(time.RFC3339))
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, etag: etagOf(data), modTime: time.Now().UTC(), meta: metadataOf(r.Header)}
+		w.Header().Set("ETag", etagOf(data))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		for k, v := range obj.meta {
+			w.Header().Set("x-amz-meta-"+k, v)
+		}
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix, delimiter, token, maxKeys string) {
+	limit := f.pageSize
+	if n, err := strconv.Atoi(maxKeys); err == nil && n < limit {
+		limit = n
+	}
+
+	// Collect keys and common prefixes in lexical order
+	isPrefix := make(map[string]bool)
+	var names []string
+	for key := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		name, rolledUp := key, false
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				name, rolledUp = key[:len(prefix)+i+len(delimiter)], true
+			}
+		}
+		if _, ok := isPrefix[name]; !ok {
+			names = append(names, name)
+		}
+		isPrefix[name] = isPrefix[name] || rolledUp
+	}
+	sort.Strings(names)
+
+	result := listResult{Name: f.bucket, Prefix: prefix}
+	for _, name := range names {
+		if token != "" && name <= token {
+			continue
+		}
+		if result.KeyCount == limit {
+			result.IsTruncated = true
+			break
+		}
+		result.KeyCount++
+		result.NextContinuationToken = name
+		if obj := f.objects[name]; !isPrefix[name] {
+			result.Contents = append(result.Contents, listObject{
+				Key:          name,
+				LastModified: obj.modTime.Format(time.RFC3339),
+				ETag:         obj.etag,
+				Size:         int64(len(obj.data)),
+			})
+		} else {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: name})
+		}
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// newTestAdapter serves a fake bucket and returns an adapter rooted at bucket/prefix
+func newTestAdapter(t *testing.T, prefix string) (*Adapter, *fakeS3) {
+	t.Helper()
+
+	fake := newFakeS3("bucket")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	a, err := New(context.Background(), Options{
+		Endpoint:        server.URL,
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	}, "bucket/"+prefix)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return a, fake
+}
+
+func TestAdapter_Operations(t *testing.T) {
+	a, fake := newTestAdapter(t, "backup")
+	ctx := context.Background()
+
+	files := map[string]string{
+		"a.txt":         "alpha",
+		"dir/b.txt":     "bravo",
+		"dir/c.txt":     "charlie",
+		"dir/sub/d.txt": "delta",
+	}
+	for p, content := range files {
+		if err := a.Write(ctx, p, strings.NewReader(content)); err != nil {
+			t.Fatalf("Write(%s) failed: %v", p, err)
+		}
+	}
+	if _, ok := fake.objects["backup/dir/sub/d.txt"]; !ok {
+		t.Fatalf("object keys = %v, want prefix backup/", fake.objects)
+	}
+
+	if err := a.Mkdir(ctx, "empty"); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	entries, err := a.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List root failed: %v", err)
+	}
+	got := make(map[string]domain.FileType)
+	for _, e := range entries {
+		got[e.Path] = e.Type
+	}
+	want := map[string]domain.FileType{
+		"a.txt": domain.FileTypeRegular,
+		"dir":   domain.FileTypeDirectory,
+		"empty": domain.FileTypeDirectory,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("List root = %v, want %v", got, want)
+	}
+
+	// Paged listing (page size 2) must still return every entry
+	entries, err = a.List(ctx, "dir")
+	if err != nil {
+		t.Fatalf("List dir failed: %v", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+		if e.Path == "dir/b.txt" && (e.ETag == "" || e.Size != 5) {
+			t.Errorf("listed file = %+v, want ETag and size", e)
+		}
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "dir/b.txt,dir/c.txt,dir/sub" {
+		t.Errorf("List dir = %v", paths)
+	}
+
+	entries, err = a.List(ctx, "empty")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("List empty = %v, %v", entries, err)
+	}
+
+	info, err := a.Stat(ctx, "dir/b.txt")
+	if err != nil {
+		t.Fatalf("Stat file failed: %v", err)
+	}
+	if info.Path != "dir/b.txt" || !info.IsFile() || info.Size != 5 || info.ETag != strings.Trim(etagOf([]byte("bravo")), `"`) {
+		t.Errorf("Stat file = %+v", info)
+	}
+	info, err = a.Stat(ctx, "dir/sub")
+	if err != nil || !info.IsDir() {
+		t.Errorf("Stat dir = %+v, %v", info, err)
+	}
+
+	r, err := a.Read(ctx, "dir/sub/d.txt")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "delta" {
+		t.Errorf("Read = %q, want delta", data)
+	}
+
+	if _, err := a.Read(ctx, "dir"); !errors.Is(err, domain.ErrNotFile) {
+		t.Errorf("Read dir error = %v, want ErrNotFile", err)
+	}
+	if _, err := a.Read(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Read missing error = %v, want ErrNotFound", err)
+	}
+	if _, err := a.List(ctx, "a.txt"); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("List file error = %v, want ErrNotDirectory", err)
+	}
+
+	// Directories can only be deleted once empty
+	if err := a.Delete(ctx, "dir/sub"); !errors.Is(err, domain.ErrDirectoryNotEmpty) {
+		t.Errorf("Delete non-empty dir error = %v, want ErrDirectoryNotEmpty", err)
+	}
+	if err := a.Delete(ctx, "dir/sub/d.txt"); err != nil {
+		t.Fatalf("Delete file failed: %v", err)
+	}
+	if info, err := a.Stat(ctx, "dir/sub"); err != nil || !info.IsDir() {
+		t.Errorf("dir/sub should remain as an empty directory: %+v, %v", info, err)
+	}
+	if _, ok := fake.objects["backup/dir/sub/"]; !ok {
+		t.Error("expected a marker to keep dir/sub")
+	}
+	if err := a.Delete(ctx, "dir/sub"); err != nil {
+		t.Fatalf("Delete emptied dir failed: %v", err)
+	}
+	if err := a.Delete(ctx, "empty"); err != nil {
+		t.Fatalf("Delete empty dir failed: %v", err)
+	}
+	if exists, _ := a.Exists(ctx, "empty"); exists {
+		t.Error("empty dir should be deleted")
+	}
+	if err := a.Delete(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Delete missing error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdapter_MultipartWrite(t *testing.T) {
+	a, fake := newTestAdapter(t, "")
+	a.partSize = 1024
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("0123456789"), 350) // 3500 bytes = 4 parts
+	if err := a.Write(ctx, "big.bin", bytes.NewReader(data)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if fake.completed != 1 {
+		t.Errorf("completed uploads = %d, want 1", fake.completed)
+	}
+	if !bytes.Equal(fake.objects["big.bin"].data, data) {
+		t.Error("multipart object content mismatch")
+	}
+	info, err := a.Stat(ctx, "big.bin")
+	if err != nil || !strings.HasSuffix(info.ETag, "-4") {
+		t.Errorf("Stat = %+v, %v; want multipart ETag", info, err)
+	}
+
+	// The content hash is kept in the object's metadata
+	a.SetChecksumAlgorithm(checksum.MD5)
+	info, err = a.StatWithChecksum(ctx, "big.bin")
+	if err != nil || info.Checksum != strings.Trim(etagOf(data), `"`) || info.ChecksumAlgorithm != "md5" {
+		t.Errorf("StatWithChecksum = %+v, %v; want the MD5 of the content", info, err)
+	}
+
+	// A failing reader aborts the upload and leaves the old object intact
+	failing := io.MultiReader(bytes.NewReader(data[:2048]), errReader{})
+	if err := a.Write(ctx, "big.bin", failing); err == nil {
+		t.Fatal("Write with failing reader should error")
+	}
+	if fake.aborted != 1 || len(fake.uploads) != 0 {
+		t.Errorf("aborted = %d, pending uploads = %d; want 1, 0", fake.aborted, len(fake.uploads))
+	}
+	if !bytes.Equal(fake.objects["big.bin"].data, data) {
+		t.Error("failed upload must not replace the object")
+	}
+}
+
+func TestAdapter_SecondSyncOfLargeObject(t *testing.T) {
+	a, fake := newTestAdapter(t, "")
+	a.partSize = 1024
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 350) // Uploaded in 4 parts
+	if err := os.WriteFile(filepath.Join(dir, "big.bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := local.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The algorithm the service picks for local and S3
+	source.SetChecksumAlgorithm(checksum.MD5)
+	a.SetChecksumAlgorithm(checksum.MD5)
+
+	executor := rule.NewDefaultExecutor()
+	push := &domain.SyncRule{Name: "push", Mode: domain.SyncModeOneWayPush}
+	runSync := func() *domain.SyncPlan {
+		t.Helper()
+		plan, err := executor.Plan(ctx, push, source, a)
+		if err != nil {
+			t.Fatalf("Plan failed: %v", err)
+		}
+		for _, action := range plan.Actions {
+			r, err := source.Read(ctx, action.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = a.Write(ctx, action.Path, r)
+			r.Close()
+			if err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+		return plan
+	}
+
+	if plan := runSync(); plan.Stats.FilesToCopy != 1 || fake.completed != 1 {
+		t.Fatalf("First sync = %+v, %d multipart uploads; want one", plan.Actions, fake.completed)
+	}
+	if plan := runSync(); len(plan.Actions) != 0 {
+		t.Errorf("Second sync of an unchanged object planned %+v", plan.Actions)
+	}
+
+	// An edit that keeps the size is still found by its checksum
+	copy(data, "edited")
+	if err := os.WriteFile(filepath.Join(dir, "big.bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if plan := runSync(); plan.Stats.FilesToCopy != 1 {
+		t.Errorf("Sync after an edit planned %+v, want a copy", plan.Actions)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestAdapter_PathEscape(t *testing.T) {
+	a, _ := newTestAdapter(t, "backup")
+	ctx := context.Background()
+
+	for _, p := range []string{"../outside", "a/../../outside", "/etc/passwd"} {
+		if _, err := a.Stat(ctx, p); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Errorf("Stat(%q) error = %v, want ErrPermissionDenied", p, err)
+		}
+	}
+}
+
+func TestNew_MissingBucket(t *testing.T) {
+	server := httptest.NewServer(newFakeS3("bucket"))
+	defer server.Close()
+
+	_, err := New(context.Background(), Options{
+		Endpoint:        server.URL,
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	}, "other/prefix")
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("New error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSplitRoot(t *testing.T) {
+	tests := []struct {
+		root, bucket, prefix string
+	}{
+		{"bucket", "bucket", ""},
+		{"/bucket/", "bucket", ""},
+		{"bucket/a/b", "bucket", "a/b"},
+		{"bucket/a//b/", "bucket", "a/b"},
+	}
+	for _, tt := range tests {
+		bucket, prefix, err := splitRoot(tt.root)
+		if err != nil || bucket != tt.bucket || prefix != tt.prefix {
+			t.Errorf("splitRoot(%q) = %q, %q, %v; want %q, %q", tt.root, bucket, prefix, err, tt.bucket, tt.prefix)
+		}
+	}
+	if _, _, err := splitRoot("/"); err == nil {
+		t.Error("splitRoot(\"/\") should error")
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts, err := OptionsFromConfig(map[string]string{
+		"endpoint": "http://minio:9000", "region": "eu-west-1", "part_size_mb": "16",
+	})
+	if err != nil {
+		t.Fatalf("OptionsFromConfig failed: %v", err)
+	}
+	if !opts.PathStyle || opts.Region != "eu-west-1" || opts.PartSize != 16*1024*1024 {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	opts, _ = OptionsFromConfig(map[string]string{})
+	if opts.PathStyle {
+		t.Error("PathStyle should default to false without endpoint")
+	}
+
+	if _, err := OptionsFromConfig(map[string]string{"part_size_mb": "1"}); err == nil {
+		t.Error("part size below 5 MB should error")
+	}
+	if _, err := OptionsFromConfig(map[string]string{"path_style": "sometimes"}); err == nil {
+		t.Error("invalid path_style should error")
+	}
+}
+
+func TestAdapter_Conformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) adapter.Adapter {
+		a, _ := newTestAdapter(t, "conformance")
+		return a
+	})
+}
@@ -0,0 +1,50 @@
+package sftp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// TransportType is the transport type handled by this package
+const TransportType domain.TransportType = "sftp"
+
+func init() {
+	adapter.Register(Factory{})
+}
+
+// Factory creates SFTP adapters from transport config (see OptionsFromConfig)
+type Factory struct{}
+
+// Supports returns true for the sftp transport type
+func (Factory) Supports(transportType domain.TransportType) bool {
+	return transportType == TransportType
+}
+
+// ValidateTransport checks the connection settings without connecting
+func (Factory) ValidateTransport(transport domain.Transport) error {
+	opts, err := OptionsFromConfig(transport.Config)
+	if err != nil {
+		return err
+	}
+	if opts.Host == "" || opts.User == "" {
+		return fmt.Errorf("sftp transport requires host and user in config")
+	}
+	return nil
+}
+
+// Create connects to the server and returns an adapter rooted at root
+func (Factory) Create(transport domain.Transport, root string) (adapter.Adapter, error) {
+	opts, err := OptionsFromConfig(transport.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := New(context.Background(), opts, root)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,590 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	sftpclient "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+const (
+	// DefaultPort is the SSH port used when none is configured
+	DefaultPort = 22
+
+	// DefaultTimeout bounds connecting and the SSH handshake
+	DefaultTimeout = 30 * time.Second
+
+	// tempFileSuffix marks in-flight writes, same as the local adapter
+	tempFileSuffix = ".syncrules.tmp"
+)
+
+// Options configures the SSH connection of an SFTP adapter
+type Options struct {
+	// Host is the server name or address
+	Host string
+
+	// Port is the SSH port (0 = DefaultPort)
+	Port int
+
+	// User is the login name
+	User string
+
+	// KeyPath is a private key file used for public key auth (optional)
+	KeyPath string
+
+	// KeyPassphrase decrypts KeyPath if it is encrypted (optional)
+	KeyPassphrase string
+
+	// UseAgent authenticates with the SSH agent at $SSH_AUTH_SOCK
+	UseAgent bool
+
+	// KnownHostsPath is the known_hosts file the server key is checked against
+	// (empty = ~/.ssh/known_hosts)
+	KnownHostsPath string
+
+	// Timeout bounds connecting and the handshake (0 = DefaultTimeout)
+	Timeout time.Duration
+}
+
+// OptionsFromConfig builds connection options from a transport's config map
+// Keys: host, port, user, key_path, key_passphrase, use_agent, known_hosts
+func OptionsFromConfig(config map[string]string) (Options, error) {
+	opts := Options{
+		Host:           config["host"],
+		User:           config["user"],
+		KeyPath:        config["key_path"],
+		KeyPassphrase:  config["key_passphrase"],
+		KnownHostsPath: config["known_hosts"],
+	}
+
+	if port := config["port"]; port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return opts, fmt.Errorf("invalid sftp port: %s", port)
+		}
+		opts.Port = p
+	}
+
+	if useAgent := config["use_agent"]; useAgent != "" {
+		v, err := strconv.ParseBool(useAgent)
+		if err != nil {
+			return opts, fmt.Errorf("invalid sftp use_agent: %s", useAgent)
+		}
+		opts.UseAgent = v
+	} else {
+		// Fall back to the agent when no key is given
+		opts.UseAgent = opts.KeyPath == ""
+	}
+
+	return opts, nil
+}
+
+// Adapter implements the adapter.Adapter interface over SFTP
+type Adapter struct {
+	ssh    *ssh.Client
+	client *sftpclient.Client
+	root   string // Remote root directory (POSIX path)
+}
+
+// New connects to an SFTP server and creates an adapter rooted at root
+// root must be an existing directory on the server; relative roots are
+// resolved against the login directory
+func New(ctx context.Context, opts Options, root string) (*Adapter, error) {
+	if opts.Host == "" {
+		return nil, fmt.Errorf("sftp host is required")
+	}
+	if opts.User == "" {
+		return nil, fmt.Errorf("sftp user is required")
+	}
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	config, err := clientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sshClient, err := dial(ctx, net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)), config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftpclient.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("failed to start sftp session: %w", err)
+	}
+
+	a := &Adapter{
+		ssh:    sshClient,
+		client: client,
+		root:   path.Clean(filepath.ToSlash(root)),
+	}
+
+	// Verify root exists and is a directory
+	info, err := client.Stat(a.root)
+	if err != nil {
+		a.Close()
+		return nil, mapError(err)
+	}
+	if !info.IsDir() {
+		a.Close()
+		return nil, domain.ErrNotDirectory
+	}
+
+	return a, nil
+}
+
+// clientConfig builds the SSH client configuration with auth and host key checking
+func clientConfig(opts Options) (*ssh.ClientConfig, error) {
+	var auths []ssh.AuthMethod
+
+	if opts.KeyPath != "" {
+		signer, err := loadKey(opts.KeyPath, opts.KeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+
+	if opts.UseAgent {
+		if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+			auths = append(auths, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				conn, err := net.Dial("unix", sock)
+				if err != nil {
+					return nil, fmt.Errorf("failed to reach ssh agent: %w", err)
+				}
+				defer conn.Close()
+				return agent.NewClient(conn).Signers()
+			}))
+		}
+	}
+
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("sftp requires key_path or a running ssh agent (SSH_AUTH_SOCK)")
+	}
+
+	knownHostsPath := opts.KnownHostsPath
+	if knownHostsPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate known_hosts: %w", err)
+		}
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts %s: %w", knownHostsPath, err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         opts.Timeout,
+	}, nil
+}
+
+// loadKey reads a private key, decrypting it with passphrase if needed
+func loadKey(keyPath, passphrase string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh key: %w", err)
+	}
+
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh key %s: %w", keyPath, err)
+	}
+	return signer, nil
+}
+
+// dial opens an SSH connection honouring ctx and the configured timeout
+func dial(ctx context.Context, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrNetworkError, err)
+	}
+
+	// The handshake has no context; bound it with a deadline instead
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// resolvePath safely resolves a relative path to a remote path within root
+// Returns error if path attempts to escape root directory
+func (a *Adapter) resolvePath(relPath string) (string, error) {
+	if relPath == "" || relPath == "." {
+		return a.root, nil
+	}
+
+	relPath = path.Clean(filepath.ToSlash(relPath))
+	if path.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", domain.ErrPermissionDenied
+	}
+
+	return path.Join(a.root, relPath), nil
+}
+
+// List returns all files and directories under the given path
+// Checksums are not computed remotely, so comparisons fall back to size and mtime
+func (a *Adapter) List(ctx context.Context, p string) ([]domain.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := a.client.Stat(fullPath)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if !info.IsDir() {
+		return nil, domain.ErrNotDirectory
+	}
+
+	entries, err := a.client.ReadDirContext(ctx, fullPath)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	result := make([]domain.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		result = append(result, fileInfoFromOS(path.Join(p, entry.Name()), entry))
+	}
+
+	return result, nil
+}
+
+// Read opens a file for reading
+func (a *Adapter) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := a.client.Stat(fullPath)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if info.IsDir() {
+		return nil, domain.ErrNotFile
+	}
+
+	file, err := a.client.Open(fullPath)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return file, nil
+}
+
+// Write creates or overwrites a file
+// Data goes to a temp file that is renamed over the target once complete
+func (a *Adapter) Write(ctx context.Context, p string, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	// Create parent directories
+	if err := a.client.MkdirAll(path.Dir(fullPath)); err != nil {
+		return mapError(err)
+	}
+
+	tempPath := fullPath + tempFileSuffix
+	file, err := a.client.Create(tempPath)
+	if err != nil {
+		return mapError(err)
+	}
+
+	_, copyErr := io.Copy(file, r)
+	closeErr := file.Close()
+
+	if copyErr != nil {
+		a.client.Remove(tempPath)
+		return copyErr
+	}
+	if closeErr != nil {
+		a.client.Remove(tempPath)
+		return mapError(closeErr)
+	}
+	if err := ctx.Err(); err != nil {
+		a.client.Remove(tempPath)
+		return err
+	}
+
+	if err := a.replace(tempPath, fullPath); err != nil {
+		a.client.Remove(tempPath)
+		return mapError(err)
+	}
+
+	return nil
+}
+
+// replace replaces newPath with oldPath, atomically if the server supports
+// the posix-rename extension
+func (a *Adapter) replace(oldPath, newPath string) error {
+	if _, ok := a.client.HasExtension("posix-rename@openssh.com"); ok {
+		return a.client.PosixRename(oldPath, newPath)
+	}
+
+	// Plain SFTP rename fails if the target exists
+	if err := a.client.Remove(newPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return a.client.Rename(oldPath, newPath)
+}
+
+// Delete removes a file or empty directory
+func (a *Adapter) Delete(ctx context.Context, p string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	return a.deleteError(fullPath, a.client.Remove(fullPath))
+}
+
+// deleteError maps an error removing fullPath to a domain error
+// Servers report removing a non-empty directory as a generic failure, but
+// the same failure on a file means something else, such as a read-only mount
+func (a *Adapter) deleteError(fullPath string, err error) error {
+	var statusErr *sftpclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.FxCode() == sftpclient.ErrSSHFxFailure {
+		if info, statErr := a.client.Lstat(fullPath); statErr == nil && info.IsDir() {
+			return fmt.Errorf("%w: %v", domain.ErrDirectoryNotEmpty, err)
+		}
+	}
+	return mapError(err)
+}
+
+// Stat returns metadata for a single path
+func (a *Adapter) Stat(ctx context.Context, p string) (domain.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.FileInfo{}, err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+
+	info, err := a.client.Stat(fullPath)
+	if err != nil {
+		return domain.FileInfo{}, mapError(err)
+	}
+
+	return fileInfoFromOS(p, info), nil
+}
+
+// Mkdir creates a directory and any necessary parents
+func (a *Adapter) Mkdir(ctx context.Context, p string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	return mapError(a.client.MkdirAll(fullPath))
+}
+
+// SetModTime sets the modification time of a file or directory
+// SFTP sets both times at once, so the access time is set to modTime too
+func (a *Adapter) SetModTime(ctx context.Context, p string, modTime time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	return mapError(a.client.Chtimes(fullPath, modTime, modTime))
+}
+
+// Rename moves a file or directory, creating the parents of newPath
+func (a *Adapter) Rename(ctx context.Context, oldPath, newPath string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	from, err := a.resolvePath(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := a.resolvePath(newPath)
+	if err != nil {
+		return err
+	}
+	if from == a.root || to == a.root {
+		return domain.ErrPermissionDenied
+	}
+
+	if _, err := a.client.Lstat(from); err != nil {
+		return mapError(err)
+	}
+	if _, err := a.client.Lstat(to); err == nil {
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyExists, newPath)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return mapError(err)
+	}
+
+	if err := a.client.MkdirAll(path.Dir(to)); err != nil {
+		return mapError(err)
+	}
+	// Plain SFTP rename never replaces an existing target
+	return mapError(a.client.Rename(from, to))
+}
+
+// Capabilities describes the SFTP backend
+// Writes are only atomic if the server supports the posix-rename extension
+func (a *Adapter) Capabilities() adapter.Capabilities {
+	_, posixRename := a.client.HasExtension("posix-rename@openssh.com")
+	return adapter.Capabilities{
+		SetModTime:  true,
+		Symlinks:    true,
+		Rename:      true,
+		AtomicWrite: posixRename,
+	}
+}
+
+// Exists checks if a path exists
+func (a *Adapter) Exists(ctx context.Context, p string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = a.client.Stat(fullPath)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, mapError(err)
+}
+
+// Close ends the SFTP session and the SSH connection
+func (a *Adapter) Close() error {
+	clientErr := a.client.Close()
+	sshErr := a.ssh.Close()
+	if clientErr != nil {
+		return clientErr
+	}
+	return sshErr
+}
+
+// Root returns the remote root path of this adapter
+func (a *Adapter) Root() string {
+	return a.root
+}
+
+// fileInfoFromOS converts os.FileInfo to domain.FileInfo
+func fileInfoFromOS(p string, info os.FileInfo) domain.FileInfo {
+	fileType := domain.FileTypeRegular
+	if info.IsDir() {
+		fileType = domain.FileTypeDirectory
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		fileType = domain.FileTypeSymlink
+	}
+
+	// The root is "", as in the local adapter
+	rel := strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "./")
+	if rel == "." {
+		rel = ""
+	}
+
+	return domain.FileInfo{
+		Path:    rel,
+		Type:    fileType,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// mapError converts SFTP errors to domain errors
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return domain.ErrNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return domain.ErrPermissionDenied
+	case errors.Is(err, fs.ErrExist):
+		return domain.ErrAlreadyExists
+	}
+
+	return err
+}
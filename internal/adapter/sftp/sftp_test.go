@@ -0,0 +1,410 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	sftpclient "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/adaptertest"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// testServer is an in-process SSH server that serves the sftp subsystem
+type testServer struct {
+	addr    string
+	hostKey ssh.Signer
+}
+
+// startServer serves SFTP on a loopback port, accepting only clientKey
+func startServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate host key: %v", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatalf("host signer: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config)
+		}
+	}()
+
+	return &testServer{addr: listener.Addr().String(), hostKey: hostKey}
+}
+
+func serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel")
+			continue
+		}
+		channel, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if !ok {
+					continue
+				}
+				server, err := sftpclient.NewServer(channel)
+				if err != nil {
+					channel.Close()
+					return
+				}
+				server.Serve()
+				server.Close()
+				return
+			}
+		}()
+	}
+}
+
+// newTestAdapter starts a server and connects an adapter rooted at a temp dir
+func newTestAdapter(t *testing.T) (*Adapter, string) {
+	t.Helper()
+
+	opts, server := testOptions(t)
+	writeKnownHosts(t, opts.KnownHostsPath, server.addr, server.hostKey.PublicKey())
+
+	root := t.TempDir()
+	a, err := New(context.Background(), opts, root)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+
+	return a, root
+}
+
+// testOptions creates a client key, a server accepting it and matching options
+// The known_hosts file is left empty
+func testOptions(t *testing.T) (Options, *testServer) {
+	t.Helper()
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatalf("marshal client key: %v", err)
+	}
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("write client key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatalf("client public key: %v", err)
+	}
+
+	server := startServer(t, sshPub)
+
+	host, portStr, _ := net.SplitHostPort(server.addr)
+	port, _ := strconv.Atoi(portStr)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(knownHosts, nil, 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+
+	return Options{
+		Host:           host,
+		Port:           port,
+		User:           "tester",
+		KeyPath:        keyPath,
+		KnownHostsPath: knownHosts,
+	}, server
+}
+
+func writeKnownHosts(t *testing.T, file, addr string, key ssh.PublicKey) {
+	t.Helper()
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)
+	if err := os.WriteFile(file, []byte(line+"\n"), 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+}
+
+func TestAdapter_Operations(t *testing.T) {
+	a, root := newTestAdapter(t)
+	ctx := context.Background()
+
+	if err := a.Write(ctx, "dir/file.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "dir", "file.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("file content = %q, %v", data, err)
+	}
+
+	r, err := a.Read(ctx, "dir/file.txt")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "hello" {
+		t.Errorf("Read = %q, want hello", got)
+	}
+
+	info, err := a.Stat(ctx, "dir/file.txt")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Path != "dir/file.txt" || info.Size != 5 || !info.IsFile() {
+		t.Errorf("Stat = %+v", info)
+	}
+
+	if err := a.Mkdir(ctx, "empty/nested"); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	entries, err := a.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	types := make(map[string]domain.FileType)
+	for _, e := range entries {
+		types[e.Path] = e.Type
+	}
+	if types["dir"] != domain.FileTypeDirectory || types["empty"] != domain.FileTypeDirectory || len(types) != 2 {
+		t.Errorf("List root = %v", types)
+	}
+
+	entries, err = a.List(ctx, "dir")
+	if err != nil {
+		t.Fatalf("List dir failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "dir/file.txt" {
+		t.Errorf("List dir = %+v", entries)
+	}
+
+	if _, err := a.Read(ctx, "dir"); !errors.Is(err, domain.ErrNotFile) {
+		t.Errorf("Read dir error = %v, want ErrNotFile", err)
+	}
+
+	if err := a.Delete(ctx, "dir/file.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	exists, err := a.Exists(ctx, "dir/file.txt")
+	if err != nil || exists {
+		t.Errorf("Exists after delete = %v, %v", exists, err)
+	}
+	if _, err := a.Stat(ctx, "dir/file.txt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Stat after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdapter_MatchesLocal(t *testing.T) {
+	a, root := newTestAdapter(t)
+	ctx := context.Background()
+
+	if info, err := a.Stat(ctx, ""); err != nil || info.Path != "" || !info.IsDir() {
+		t.Errorf("Stat(root) = %+v, %v, want directory with empty path", info, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "target.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := a.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	types := make(map[string]domain.FileType)
+	for _, e := range entries {
+		types[e.Path] = e.Type
+	}
+	if types["link"] != domain.FileTypeSymlink {
+		t.Errorf("List = %v, want link reported as a symlink", types)
+	}
+	if !a.Capabilities().Symlinks {
+		t.Error("Capabilities().Symlinks = false, but listings report symlinks")
+	}
+}
+
+func TestAdapter_DeleteFailure(t *testing.T) {
+	a, root := newTestAdapter(t)
+	ctx := context.Background()
+
+	if err := a.Write(ctx, "dir/file.txt", strings.NewReader("x")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	failure := &sftpclient.StatusError{Code: uint32(sftpclient.ErrSSHFxFailure)}
+
+	// A generic failure means "not empty" only for directories
+	if err := a.deleteError(filepath.Join(root, "dir"), failure); !errors.Is(err, domain.ErrDirectoryNotEmpty) {
+		t.Errorf("directory failure = %v, want ErrDirectoryNotEmpty", err)
+	}
+	if err := a.deleteError(filepath.Join(root, "dir", "file.txt"), failure); errors.Is(err, domain.ErrDirectoryNotEmpty) {
+		t.Errorf("file failure = %v, want it not reported as a non-empty directory", err)
+	}
+}
+
+func TestAdapter_WriteReplacesAtomically(t *testing.T) {
+	a, root := newTestAdapter(t)
+	ctx := context.Background()
+
+	if err := a.Write(ctx, "file.txt", strings.NewReader("old")); err != nil {
+		t.Fatalf("first Write failed: %v", err)
+	}
+	if err := a.Write(ctx, "file.txt", strings.NewReader("new content")); err != nil {
+		t.Fatalf("second Write failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(root, "file.txt"))
+	if string(data) != "new content" {
+		t.Errorf("content = %q, want new content", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "file.txt"+tempFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+
+	// A failed copy must leave the existing file untouched
+	failing := io.MultiReader(strings.NewReader("partial"), errReader{})
+	if err := a.Write(ctx, "file.txt", failing); err == nil {
+		t.Fatal("Write with failing reader should error")
+	}
+	data, _ = os.ReadFile(filepath.Join(root, "file.txt"))
+	if string(data) != "new content" {
+		t.Errorf("content after failed write = %q, want new content", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "file.txt"+tempFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind after failure: %v", err)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestAdapter_PathEscape(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	ctx := context.Background()
+
+	for _, p := range []string{"../outside", "a/../../outside", "/etc/passwd"} {
+		if _, err := a.Stat(ctx, p); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Errorf("Stat(%q) error = %v, want ErrPermissionDenied", p, err)
+		}
+	}
+}
+
+func TestNew_RejectsUnknownHostKey(t *testing.T) {
+	opts, _ := testOptions(t)
+
+	// Empty known_hosts: the server key is unknown
+	if _, err := New(context.Background(), opts, t.TempDir()); err == nil {
+		t.Fatal("New should fail for an unknown host key")
+	}
+
+	// A different key recorded for the host is a mismatch
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	other, _ := ssh.NewSignerFromKey(otherPriv)
+	writeKnownHosts(t, opts.KnownHostsPath, net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)), other.PublicKey())
+
+	_, err := New(context.Background(), opts, t.TempDir())
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) || len(keyErr.Want) == 0 {
+		t.Fatalf("New error = %v, want host key mismatch", err)
+	}
+}
+
+func TestNew_InvalidRoot(t *testing.T) {
+	opts, server := testOptions(t)
+	writeKnownHosts(t, opts.KnownHostsPath, server.addr, server.hostKey.PublicKey())
+
+	if _, err := New(context.Background(), opts, filepath.Join(t.TempDir(), "missing")); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("missing root error = %v, want ErrNotFound", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, []byte("x"), 0644)
+	if _, err := New(context.Background(), opts, file); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("file root error = %v, want ErrNotDirectory", err)
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts, err := OptionsFromConfig(map[string]string{
+		"host": "nas", "port": "2222", "user": "me", "key_path": "/k",
+	})
+	if err != nil {
+		t.Fatalf("OptionsFromConfig failed: %v", err)
+	}
+	if opts.Host != "nas" || opts.Port != 2222 || opts.User != "me" || opts.UseAgent {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	// Without a key the agent is used
+	opts, _ = OptionsFromConfig(map[string]string{"host": "nas", "user": "me"})
+	if !opts.UseAgent {
+		t.Error("UseAgent should default to true without key_path")
+	}
+
+	if _, err := OptionsFromConfig(map[string]string{"port": "ssh"}); err == nil {
+		t.Error("invalid port should error")
+	}
+	if _, err := OptionsFromConfig(map[string]string{"use_agent": "maybe"}); err == nil {
+		t.Error("invalid use_agent should error")
+	}
+}
+
+func TestAdapter_Conformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) adapter.Adapter {
+		a, _ := newTestAdapter(t)
+		return a
+	})
+}
@@ -0,0 +1,50 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// TransportType is the transport type handled by this package
+const TransportType domain.TransportType = "webdav"
+
+func init() {
+	adapter.Register(Factory{})
+}
+
+// Factory creates WebDAV adapters from transport config (see OptionsFromConfig)
+type Factory struct{}
+
+// Supports returns true for the webdav transport type
+func (Factory) Supports(transportType domain.TransportType) bool {
+	return transportType == TransportType
+}
+
+// ValidateTransport checks the connection settings without connecting
+func (Factory) ValidateTransport(transport domain.Transport) error {
+	opts, err := OptionsFromConfig(transport.Config)
+	if err != nil {
+		return err
+	}
+	if opts.URL == "" {
+		return fmt.Errorf("webdav transport requires url in config")
+	}
+	return nil
+}
+
+// Create returns an adapter rooted at the collection root below the base URL
+func (Factory) Create(transport domain.Transport, root string) (adapter.Adapter, error) {
+	opts, err := OptionsFromConfig(transport.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := New(context.Background(), opts, root)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,691 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/logger"
+)
+
+// DefaultTimeout bounds each WebDAV request
+const DefaultTimeout = 5 * time.Minute
+
+// tempFileSuffix marks uploads that are not yet moved into place
+const tempFileSuffix = ".syncrules.tmp"
+
+// propNamespace is the XML namespace of the dead properties SetModTime stores
+const propNamespace = "urn:syncrules:webdav"
+
+// propfindBody requests only the properties needed for domain.FileInfo
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:s="` + propNamespace + `">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+    <d:getetag/>
+    <s:mtime/>
+    <s:mtime-version/>
+  </d:prop>
+</d:propfind>`
+
+// proppatchBody stores a modification time (Unix nanoseconds) and the
+// version of the resource it was set on
+const proppatchBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:s="` + propNamespace + `">
+  <d:set>
+    <d:prop>
+      <s:mtime>%d</s:mtime>
+      <s:mtime-version>%s</s:mtime-version>
+    </d:prop>
+  </d:set>
+</d:propertyupdate>`
+
+// Options configures the connection to a WebDAV server
+type Options struct {
+	// URL is the WebDAV base URL,
+	// e.g. "https://cloud.example.com/remote.php/dav/files/alice"
+	URL string
+
+	// User and Password are sent as HTTP basic auth (optional)
+	User     string
+	Password string
+
+	// Timeout bounds each request (0 = DefaultTimeout)
+	Timeout time.Duration
+
+	// HTTPClient overrides the HTTP client (optional)
+	HTTPClient *http.Client
+}
+
+// OptionsFromConfig builds connection options from a transport's config map
+// Keys: url, user, password, timeout
+func OptionsFromConfig(config map[string]string) (Options, error) {
+	opts := Options{
+		URL:      config["url"],
+		User:     config["user"],
+		Password: config["password"],
+	}
+
+	if timeout := config["timeout"]; timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid webdav timeout: %s", timeout)
+		}
+		opts.Timeout = d
+	}
+
+	return opts, nil
+}
+
+// Adapter implements the adapter.Adapter interface over WebDAV
+type Adapter struct {
+	client   *http.Client
+	base     *url.URL
+	user     string
+	password string
+	root     string // Root collection path on the server, e.g. "/remote.php/dav/files/alice/Notes"
+
+	// warnNoProps logs once that the server refuses to store modification times
+	warnNoProps sync.Once
+}
+
+// New creates a WebDAV adapter rooted at root below the base URL
+// root must be an existing collection
+func New(ctx context.Context, opts Options, root string) (*Adapter, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("webdav url is required")
+	}
+	base, err := url.Parse(opts.URL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid webdav url: %s", opts.URL)
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	a := &Adapter{
+		client:   client,
+		base:     base,
+		user:     opts.User,
+		password: opts.Password,
+		root:     path.Join("/", base.Path, filepath.ToSlash(root)),
+	}
+
+	// Verify root exists and is a collection
+	info, err := a.Stat(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, domain.ErrNotDirectory
+	}
+
+	return a, nil
+}
+
+// resolvePath safely resolves a relative path to a server path within root
+// Returns error if path attempts to escape root collection
+func (a *Adapter) resolvePath(relPath string) (string, error) {
+	if relPath == "" || relPath == "." {
+		return a.root, nil
+	}
+
+	relPath = path.Clean(filepath.ToSlash(relPath))
+	if path.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", domain.ErrPermissionDenied
+	}
+
+	return path.Join(a.root, relPath), nil
+}
+
+// List returns all files and directories under the given path
+func (a *Adapter) List(ctx context.Context, p string) ([]domain.FileInfo, error) {
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := a.propfind(ctx, fullPath, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.path == fullPath {
+			if !entry.collection {
+				return nil, domain.ErrNotDirectory
+			}
+			continue
+		}
+
+		name := strings.TrimPrefix(entry.path, strings.TrimSuffix(fullPath, "/")+"/")
+		if name == entry.path || name == "" || strings.Contains(name, "/") {
+			continue // Not a direct child
+		}
+		// Servers may still commit an aborted upload after Write removed it;
+		// the next write of the file moves it away
+		if !entry.collection && strings.HasSuffix(name, tempFileSuffix) {
+			continue
+		}
+		result = append(result, entry.fileInfo(path.Join(p, name)))
+	}
+
+	return result, nil
+}
+
+// Read opens a file for reading
+func (a *Adapter) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	info, err := a.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, domain.ErrNotFile
+	}
+
+	fullPath, _ := a.resolvePath(p)
+	resp, err := a.do(ctx, http.MethodGet, fullPath, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// Write creates or overwrites a file
+// Missing parent collections are created first, since PUT does not create them
+func (a *Adapter) Write(ctx context.Context, p string, r io.Reader) error {
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+	if fullPath == a.root {
+		return domain.ErrNotFile
+	}
+
+	if err := a.ensureParent(ctx, p); err != nil {
+		return err
+	}
+
+	// Upload to a temporary resource first: servers write PUT bodies in
+	// place, so an interrupted upload would otherwise truncate the file
+	tempPath := fullPath + tempFileSuffix
+	if err := a.put(ctx, tempPath, r); err != nil {
+		a.remove(tempPath)
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Destination", a.urlFor(fullPath))
+	header.Set("Overwrite", "T")
+	resp, err := a.do(ctx, "MOVE", tempPath, nil, header)
+	if err != nil {
+		a.remove(tempPath)
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusNoContent:
+		return nil
+	}
+	a.remove(tempPath)
+	return statusError(resp)
+}
+
+// ensureParent creates the parent collections of p if missing
+func (a *Adapter) ensureParent(ctx context.Context, p string) error {
+	parent := path.Dir(path.Clean(filepath.ToSlash(p)))
+	if parent == "." {
+		return nil
+	}
+	exists, err := a.Exists(ctx, parent)
+	if err != nil || exists {
+		return err
+	}
+	return a.Mkdir(ctx, parent)
+}
+
+// put uploads r to serverPath
+func (a *Adapter) put(ctx context.Context, serverPath string, r io.Reader) error {
+	resp, err := a.do(ctx, http.MethodPut, serverPath, r, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	}
+	return statusError(resp)
+}
+
+// remove deletes a leftover temporary resource, ignoring errors
+// It runs even if the write's context was cancelled
+func (a *Adapter) remove(serverPath string) {
+	resp, err := a.do(context.Background(), http.MethodDelete, serverPath, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Delete removes a file or empty directory
+// WebDAV deletes collections recursively, so non-empty ones are refused here
+func (a *Adapter) Delete(ctx context.Context, p string) error {
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	info, err := a.Stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		children, err := a.List(ctx, p)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("%w: %s", domain.ErrDirectoryNotEmpty, p)
+		}
+		fullPath += "/"
+	}
+
+	resp, err := a.do(ctx, "DELETE", fullPath, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	}
+	return statusError(resp)
+}
+
+// Stat returns metadata for a single path
+func (a *Adapter) Stat(ctx context.Context, p string) (domain.FileInfo, error) {
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+
+	entries, err := a.propfind(ctx, fullPath, "0")
+	if err != nil {
+		return domain.FileInfo{}, err
+	}
+	for _, entry := range entries {
+		if entry.path == fullPath {
+			relPath := strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "./")
+			if relPath == "." {
+				relPath = ""
+			}
+			return entry.fileInfo(relPath), nil
+		}
+	}
+
+	return domain.FileInfo{}, domain.ErrNotFound
+}
+
+// Mkdir creates a directory and any necessary parents
+func (a *Adapter) Mkdir(ctx context.Context, p string) error {
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+	if fullPath == a.root {
+		return nil
+	}
+
+	// MKCOL needs an existing parent, so create each level in turn
+	rel := strings.TrimPrefix(fullPath, strings.TrimSuffix(a.root, "/")+"/")
+	current := a.root
+	for _, part := range strings.Split(rel, "/") {
+		current = path.Join(current, part)
+
+		resp, err := a.do(ctx, "MKCOL", current+"/", nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusCreated:
+		case http.StatusMethodNotAllowed:
+			// Already exists; make sure it is a collection
+			info, err := a.Stat(ctx, strings.TrimPrefix(current, strings.TrimSuffix(a.root, "/")+"/"))
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return domain.ErrNotDirectory
+			}
+		default:
+			return statusError(resp)
+		}
+	}
+
+	return nil
+}
+
+// Rename moves a file or collection with MOVE, creating the parents of newPath
+func (a *Adapter) Rename(ctx context.Context, oldPath, newPath string) error {
+	from, err := a.resolvePath(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := a.resolvePath(newPath)
+	if err != nil {
+		return err
+	}
+	if from == a.root || to == a.root {
+		return domain.ErrPermissionDenied
+	}
+
+	if _, err := a.Stat(ctx, oldPath); err != nil {
+		return err
+	}
+	if err := a.ensureParent(ctx, newPath); err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Destination", a.urlFor(to))
+	header.Set("Overwrite", "F")
+	resp, err := a.do(ctx, "MOVE", from, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed:
+		// Overwrite: F refuses an existing destination
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyExists, newPath)
+	}
+	return statusError(resp)
+}
+
+// SetModTime records the modification time of a file or collection
+// WebDAV servers don't let clients set getlastmodified, so it is stored as a
+// dead property along with the resource's current version (ETag); listings
+// report it only while that version is unchanged, so an edit made outside
+// Syncrules still shows its own time. Servers that refuse dead properties
+// keep the upload time
+func (a *Adapter) SetModTime(ctx context.Context, p string, modTime time.Time) error {
+	fullPath, err := a.resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	entries, err := a.propfind(ctx, fullPath, "0")
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return domain.ErrNotFound
+	}
+
+	var version bytes.Buffer
+	if err := xml.EscapeText(&version, []byte(entries[0].version())); err != nil {
+		return err
+	}
+	body := fmt.Sprintf(proppatchBody, modTime.UnixNano(), version.String())
+	header := http.Header{"Content-Type": {"application/xml; charset=utf-8"}}
+	resp, err := a.do(ctx, "PROPPATCH", fullPath, strings.NewReader(body), header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	stored := false
+	switch resp.StatusCode {
+	case http.StatusMultiStatus:
+		var ms multistatus
+		if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+			return fmt.Errorf("invalid PROPPATCH response: %w", err)
+		}
+		stored = len(ms.Responses) > 0
+		for _, r := range ms.Responses {
+			for _, ps := range r.Propstats {
+				stored = stored && strings.Contains(ps.Status, " 200")
+			}
+		}
+	case http.StatusOK, http.StatusNoContent:
+		stored = true
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+	default:
+		return statusError(resp)
+	}
+
+	if !stored {
+		a.warnNoProps.Do(func() {
+			logger.Get().Warn("webdav server does not store modification times; copies keep the upload time", "root", a.Root())
+		})
+	}
+	return nil
+}
+
+// Capabilities describes the WebDAV backend
+func (a *Adapter) Capabilities() adapter.Capabilities {
+	return adapter.Capabilities{
+		SetModTime:  true,
+		Rename:      true,
+		AtomicWrite: true,
+		ETags:       true,
+	}
+}
+
+// Exists checks if a path exists
+func (a *Adapter) Exists(ctx context.Context, p string) (bool, error) {
+	_, err := a.Stat(ctx, p)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Close releases idle connections
+func (a *Adapter) Close() error {
+	a.client.CloseIdleConnections()
+	return nil
+}
+
+// Root returns the root collection URL of this adapter
+func (a *Adapter) Root() string {
+	return a.urlFor(a.root)
+}
+
+// urlFor builds the escaped request URL for a server path
+func (a *Adapter) urlFor(serverPath string) string {
+	u := *a.base
+	u.Path = serverPath
+	u.RawPath = ""
+	return u.String()
+}
+
+// do sends a request with credentials
+// Transport failures are reported as domain.ErrNetworkError
+func (a *Adapter) do(ctx context.Context, method, serverPath string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.urlFor(serverPath), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if a.user != "" || a.password != "" {
+		req.SetBasicAuth(a.user, a.password)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", domain.ErrNetworkError, err)
+	}
+	return resp, nil
+}
+
+// davEntry is one resource from a PROPFIND response
+type davEntry struct {
+	path       string // Decoded server path without trailing slash
+	collection bool
+	size       int64
+	modTime    time.Time
+	etag       string
+}
+
+// version identifies the current content of the resource: its ETag, or its
+// server modification time if the server sends none
+func (e davEntry) version() string {
+	if e.etag != "" {
+		return e.etag
+	}
+	return e.modTime.UTC().Format(http.TimeFormat)
+}
+
+func (e davEntry) fileInfo(p string) domain.FileInfo {
+	info := domain.FileInfo{
+		Path:    p,
+		Type:    domain.FileTypeRegular,
+		Size:    e.size,
+		ModTime: e.modTime,
+		ETag:    e.etag,
+	}
+	if e.collection {
+		info.Type = domain.FileTypeDirectory
+		info.Size = 0
+	}
+	return info
+}
+
+// multistatus is the body of a 207 Multi-Status response
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ETag          string `xml:"DAV: getetag"`
+				MTime         string `xml:"urn:syncrules:webdav mtime"`
+				MTimeVersion  string `xml:"urn:syncrules:webdav mtime-version"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// propfind lists a resource (depth "0") or a collection and its children (depth "1")
+func (a *Adapter) propfind(ctx context.Context, serverPath, depth string) ([]davEntry, error) {
+	header := http.Header{
+		"Depth":        {depth},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	}
+	resp, err := a.do(ctx, "PROPFIND", serverPath, bytes.NewReader([]byte(propfindBody)), header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError(resp)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("invalid PROPFIND response: %w", err)
+	}
+
+	entries := make([]davEntry, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		entry := davEntry{path: path.Clean("/" + href.Path)}
+
+		var mtime, mtimeVersion string
+		for _, ps := range r.Propstats {
+			// Properties the server lacks are reported under a 404 propstat
+			if !strings.Contains(ps.Status, " 200") {
+				continue
+			}
+			entry.collection = ps.Prop.ResourceType.Collection != nil
+			if ps.Prop.ContentLength != "" {
+				entry.size, _ = strconv.ParseInt(strings.TrimSpace(ps.Prop.ContentLength), 10, 64)
+			}
+			if ps.Prop.LastModified != "" {
+				entry.modTime, _ = http.ParseTime(strings.TrimSpace(ps.Prop.LastModified))
+			}
+			entry.etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(ps.Prop.ETag), "W/"), `"`)
+			mtime, mtimeVersion = strings.TrimSpace(ps.Prop.MTime), strings.TrimSpace(ps.Prop.MTimeVersion)
+		}
+
+		// A modification time stored by SetModTime holds until the content changes
+		if nanos, err := strconv.ParseInt(mtime, 10, 64); err == nil && mtimeVersion == entry.version() {
+			entry.modTime = time.Unix(0, nanos)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// statusError converts an unexpected HTTP status to a domain error
+func statusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusConflict:
+		// 409 means a parent collection is missing
+		return domain.ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s %s", domain.ErrPermissionDenied, resp.Request.Method, resp.Status)
+	case http.StatusInsufficientStorage:
+		return domain.ErrQuotaExceeded
+	case http.StatusPreconditionFailed:
+		return domain.ErrVersionConflict
+	case http.StatusLocked:
+		return fmt.Errorf("%w: resource is locked", domain.ErrPermissionDenied)
+	}
+
+	return fmt.Errorf("webdav %s %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status)
+}
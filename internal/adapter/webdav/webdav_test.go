@@ -0,0 +1,477 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	davserver "golang.org/x/net/webdav"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/adaptertest"
+	"github.com/Ning0612/Syncrules/internal/adapter/local"
+	"github.com/Ning0612/Syncrules/internal/core/rule"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// propFS keeps dead properties of a davserver.FileSystem in memory, as
+// servers like Nextcloud do; davserver.Dir alone refuses them
+// Like those servers, it keeps properties when a file is overwritten in place
+type propFS struct {
+	davserver.FileSystem
+
+	mu    sync.Mutex
+	props map[string]map[xml.Name]davserver.Property
+}
+
+func newPropFS(fs davserver.FileSystem) *propFS {
+	return &propFS{FileSystem: fs, props: make(map[string]map[xml.Name]davserver.Property)}
+}
+
+func (fs *propFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (davserver.File, error) {
+	// PROPPATCH opens resources for writing, which fails on directories
+	if flag == os.O_RDWR {
+		if info, err := fs.FileSystem.Stat(ctx, name); err == nil && info.IsDir() {
+			flag = os.O_RDONLY
+		}
+	}
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &propFile{File: f, fs: fs, name: path.Clean(name)}, nil
+}
+
+func (fs *propFS) RemoveAll(ctx context.Context, name string) error {
+	fs.moveProps(path.Clean(name), "")
+	return fs.FileSystem.RemoveAll(ctx, name)
+}
+
+func (fs *propFS) Rename(ctx context.Context, oldName, newName string) error {
+	if err := fs.FileSystem.Rename(ctx, oldName, newName); err != nil {
+		return err
+	}
+	fs.moveProps(path.Clean(oldName), path.Clean(newName))
+	return nil
+}
+
+// moveProps moves the properties of name and everything beneath it to
+// newName, or drops them if newName is empty
+func (fs *propFS) moveProps(name, newName string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for p, props := range fs.props {
+		if p != name && !strings.HasPrefix(p, name+"/") {
+			continue
+		}
+		delete(fs.props, p)
+		if newName != "" {
+			fs.props[newName+strings.TrimPrefix(p, name)] = props
+		}
+	}
+}
+
+// propFile implements davserver.DeadPropsHolder for propFS
+type propFile struct {
+	davserver.File
+	fs   *propFS
+	name string
+}
+
+func (f *propFile) DeadProps() (map[xml.Name]davserver.Property, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	props := make(map[xml.Name]davserver.Property)
+	for name, prop := range f.fs.props[f.name] {
+		props[name] = prop
+	}
+	return props, nil
+}
+
+func (f *propFile) Patch(patches []davserver.Proppatch) ([]davserver.Propstat, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	props := f.fs.props[f.name]
+	if props == nil {
+		props = make(map[xml.Name]davserver.Property)
+		f.fs.props[f.name] = props
+	}
+	stat := davserver.Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			if patch.Remove {
+				delete(props, prop.XMLName)
+			} else {
+				props[prop.XMLName] = prop
+			}
+			stat.Props = append(stat.Props, davserver.Property{XMLName: prop.XMLName})
+		}
+	}
+	return []davserver.Propstat{stat}, nil
+}
+
+// newTestServer serves dir over WebDAV under /dav, requiring alice:secret
+// wrap, if set, can intercept requests before the WebDAV handler
+func newTestServer(t *testing.T, dir string, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	var handler http.Handler = &davserver.Handler{
+		Prefix:     "/dav",
+		FileSystem: newPropFS(davserver.Dir(dir)),
+		LockSystem: davserver.NewMemLS(),
+	}
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAdapter(t *testing.T, wrap func(http.Handler) http.Handler) (*Adapter, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, dir, wrap)
+
+	a, err := New(context.Background(), Options{
+		URL:      server.URL + "/dav",
+		User:     "alice",
+		Password: "secret",
+	}, "notes")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+
+	return a, filepath.Join(dir, "notes")
+}
+
+func TestAdapter_Operations(t *testing.T) {
+	a, root := newTestAdapter(t, nil)
+	ctx := context.Background()
+
+	// Parent collections are created on demand
+	if err := a.Write(ctx, "deep/dir/file with space.md", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "deep", "dir", "file with space.md"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("file content = %q, %v", data, err)
+	}
+	if err := a.Write(ctx, "top.md", strings.NewReader("top")); err != nil {
+		t.Fatalf("Write top failed: %v", err)
+	}
+
+	r, err := a.Read(ctx, "deep/dir/file with space.md")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "hello" {
+		t.Errorf("Read = %q, want hello", got)
+	}
+
+	info, err := a.Stat(ctx, "deep/dir/file with space.md")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Path != "deep/dir/file with space.md" || !info.IsFile() || info.Size != 5 || info.ETag == "" || info.ModTime.IsZero() {
+		t.Errorf("Stat = %+v", info)
+	}
+
+	// Rewriting changes the ETag
+	if err := a.Write(ctx, "deep/dir/file with space.md", strings.NewReader("hello again")); err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	info2, _ := a.Stat(ctx, "deep/dir/file with space.md")
+	if info2.ETag == info.ETag {
+		t.Errorf("ETag unchanged after rewrite: %s", info2.ETag)
+	}
+
+	if err := a.Mkdir(ctx, "empty/nested"); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if err := a.Mkdir(ctx, "empty/nested"); err != nil {
+		t.Fatalf("Mkdir on existing dir failed: %v", err)
+	}
+	if err := a.Mkdir(ctx, "top.md"); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("Mkdir over file error = %v, want ErrNotDirectory", err)
+	}
+
+	entries, err := a.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var listed []string
+	for _, e := range entries {
+		kind := "file"
+		if e.IsDir() {
+			kind = "dir"
+		}
+		listed = append(listed, e.Path+":"+kind)
+	}
+	sort.Strings(listed)
+	want := "deep:dir,empty:dir,top.md:file"
+	if strings.Join(listed, ",") != want {
+		t.Errorf("List = %v, want %s", listed, want)
+	}
+
+	entries, err = a.List(ctx, "deep/dir")
+	if err != nil || len(entries) != 1 || entries[0].Path != "deep/dir/file with space.md" || entries[0].ETag == "" {
+		t.Errorf("List deep/dir = %+v, %v", entries, err)
+	}
+
+	if _, err := a.Read(ctx, "deep"); !errors.Is(err, domain.ErrNotFile) {
+		t.Errorf("Read dir error = %v, want ErrNotFile", err)
+	}
+	if _, err := a.List(ctx, "top.md"); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("List file error = %v, want ErrNotDirectory", err)
+	}
+	if _, err := a.Stat(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Stat missing error = %v, want ErrNotFound", err)
+	}
+
+	// Non-empty collections are not deleted recursively
+	if err := a.Delete(ctx, "deep"); !errors.Is(err, domain.ErrDirectoryNotEmpty) {
+		t.Errorf("Delete non-empty dir error = %v, want ErrDirectoryNotEmpty", err)
+	}
+	if err := a.Delete(ctx, "deep/dir/file with space.md"); err != nil {
+		t.Fatalf("Delete file failed: %v", err)
+	}
+	if err := a.Delete(ctx, "empty/nested"); err != nil {
+		t.Fatalf("Delete empty dir failed: %v", err)
+	}
+	if exists, err := a.Exists(ctx, "empty/nested"); err != nil || exists {
+		t.Errorf("Exists after delete = %v, %v", exists, err)
+	}
+	if err := a.Delete(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Delete missing error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdapter_LeftoverUpload(t *testing.T) {
+	a, root := newTestAdapter(t, nil)
+	ctx := context.Background()
+
+	// An aborted upload the server committed after Write gave up on it
+	if err := os.WriteFile(filepath.Join(root, "a.md"+tempFileSuffix), []byte("part"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := a.List(ctx, ""); err != nil || len(entries) != 0 {
+		t.Errorf("List = %+v, %v, want leftover upload hidden", entries, err)
+	}
+
+	// The next write of the file moves it into place
+	if err := a.Write(ctx, "a.md", strings.NewReader("full")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.md"+tempFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("leftover upload still present: %v", err)
+	}
+}
+
+func TestAdapter_ModTime(t *testing.T) {
+	a, root := newTestAdapter(t, nil)
+	ctx := context.Background()
+	modTime := time.Date(2020, 5, 6, 7, 8, 9, 123456789, time.UTC)
+
+	if err := a.Write(ctx, "a.md", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := a.SetModTime(ctx, "a.md", modTime); err != nil {
+		t.Fatalf("SetModTime failed: %v", err)
+	}
+	entries, err := a.List(ctx, "")
+	if err != nil || len(entries) != 1 || !entries[0].ModTime.Equal(modTime) {
+		t.Errorf("List = %+v, %v, want mtime %v", entries, err, modTime)
+	}
+
+	// An edit made on the server itself is reported with its own time
+	if err := os.WriteFile(filepath.Join(root, "a.md"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := a.Stat(ctx, "a.md")
+	if err != nil || info.ModTime.Equal(modTime) {
+		t.Errorf("Stat after edit = %+v, %v, want the server's mtime", info, err)
+	}
+
+	// A rewrite through the adapter drops the stored time
+	if err := a.SetModTime(ctx, "a.md", modTime); err != nil {
+		t.Fatalf("SetModTime failed: %v", err)
+	}
+	if err := a.Write(ctx, "a.md", strings.NewReader("rewritten")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if info, err := a.Stat(ctx, "a.md"); err != nil || info.ModTime.Equal(modTime) {
+		t.Errorf("Stat after rewrite = %+v, %v, want the upload time", info, err)
+	}
+}
+
+func TestAdapter_ModTimeUnsupported(t *testing.T) {
+	a, _ := newTestAdapter(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "PROPPATCH" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+
+	if err := a.Write(ctx, "a.md", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// The copy itself succeeded; only its time is not kept
+	if err := a.SetModTime(ctx, "a.md", time.Unix(0, 0)); err != nil {
+		t.Errorf("SetModTime error = %v, want nil", err)
+	}
+	if err := a.SetModTime(ctx, "missing.md", time.Unix(0, 0)); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("SetModTime(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAdapter_SecondPushIsNoop(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := map[string]string{"a.md": "hello", "notes/b.md": "world", "notes/c.bin": strings.Repeat("x", 4096)}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source, err := local.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := newTestAdapter(t, nil)
+
+	executor := rule.NewDefaultExecutor()
+	push := &domain.SyncRule{Name: "push", Mode: domain.SyncModeOneWayPush}
+	plan, err := executor.Plan(ctx, push, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	for _, action := range plan.Actions {
+		switch action.Type {
+		case domain.ActionMkdir:
+			err = target.Mkdir(ctx, action.Path)
+		case domain.ActionCopy:
+			var r io.ReadCloser
+			if r, err = source.Read(ctx, action.Path); err != nil {
+				break
+			}
+			err = target.Write(ctx, action.Path, r)
+			r.Close()
+			if err == nil {
+				err = target.SetModTime(ctx, action.Path, action.SourceInfo.ModTime)
+			}
+		}
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", action.Type, action.Path, err)
+		}
+	}
+	if plan.Stats.FilesToCopy != len(files) {
+		t.Fatalf("First push copied %d files, want %d", plan.Stats.FilesToCopy, len(files))
+	}
+
+	plan, err = executor.Plan(ctx, push, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Actions) != 0 {
+		t.Errorf("Second push of an unchanged tree planned %+v", plan.Actions)
+	}
+	for name := range files {
+		src, _ := source.Stat(ctx, name)
+		tgt, err := target.Stat(ctx, name)
+		if err != nil || !tgt.ModTime.Equal(src.ModTime) {
+			t.Errorf("%s: target mtime = %v, %v, want %v", name, tgt.ModTime, err, src.ModTime)
+		}
+	}
+}
+
+func TestAdapter_ErrorMapping(t *testing.T) {
+	// Reject uploads to quota/ and anything under locked/
+	a, _ := newTestAdapter(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/quota/"):
+				w.WriteHeader(http.StatusInsufficientStorage)
+			case strings.Contains(r.URL.Path, "/locked"):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	})
+	ctx := context.Background()
+
+	if err := a.Write(ctx, "quota/big.bin", strings.NewReader("data")); !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Errorf("Write over quota error = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := a.Stat(ctx, "locked"); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("Stat forbidden error = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := a.Stat(ctx, "../outside"); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("Stat escape error = %v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644)
+	server := newTestServer(t, dir, nil)
+	ctx := context.Background()
+	opts := Options{URL: server.URL + "/dav", User: "alice", Password: "secret"}
+
+	if _, err := New(ctx, opts, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("missing root error = %v, want ErrNotFound", err)
+	}
+	if _, err := New(ctx, opts, "file.txt"); !errors.Is(err, domain.ErrNotDirectory) {
+		t.Errorf("file root error = %v, want ErrNotDirectory", err)
+	}
+
+	opts.Password = "wrong"
+	if _, err := New(ctx, opts, ""); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("bad credentials error = %v, want ErrPermissionDenied", err)
+	}
+
+	if _, err := New(ctx, Options{URL: "not a url"}, ""); err == nil {
+		t.Error("invalid url should error")
+	}
+}
+
+func TestAdapter_Conformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) adapter.Adapter {
+		a, _ := newTestAdapter(t, nil)
+		return a
+	})
+}
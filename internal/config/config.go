@@ -6,7 +6,12 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	// Validate checks transports against the registered backends
+	_ "github.com/Ning0612/Syncrules/internal/adapter/builtin"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
 	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/scheduler"
 )
 
 // Config represents the complete configuration for syncrules
@@ -55,6 +60,14 @@ type SchedulerConfig struct {
 
 	// DefaultInterval is the default sync interval (e.g., "5m", "1h")
 	DefaultInterval string `mapstructure:"default_interval"`
+
+	// Mode selects how syncs are triggered ("interval" or "watch", default: "interval")
+	// In watch mode, rules whose endpoints are all local run on filesystem changes;
+	// other rules fall back to interval polling
+	Mode string `mapstructure:"mode"`
+
+	// Debounce is the quiet period after the last change before a watched rule runs (e.g., "2s")
+	Debounce string `mapstructure:"debounce"`
 }
 
 // LoggingConfig contains logging configuration
@@ -116,8 +129,12 @@ func (c *Config) Validate() error {
 		if transportNames[t.Name] {
 			return fmt.Errorf("%w: duplicate transport name: %s", domain.ErrConfigInvalid, t.Name)
 		}
-		if !t.Type.IsValid() {
-			return fmt.Errorf("%w: invalid transport type: %s", domain.ErrConfigInvalid, t.Type)
+		if err := adapter.Default().Validate(t); err != nil {
+			return fmt.Errorf("%w: transport %s: %v", domain.ErrConfigInvalid, t.Name, err)
+		}
+		if t.Concurrency < 0 {
+			return fmt.Errorf("%w: transport %s has negative concurrency: %d",
+				domain.ErrConfigInvalid, t.Name, t.Concurrency)
 		}
 		transportNames[t.Name] = true
 	}
@@ -161,12 +178,45 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("%w: rule %s references unknown target endpoint: %s",
 				domain.ErrEndpointNotFound, r.Name, r.TargetEndpoint)
 		}
+		if r.Concurrency < 0 {
+			return fmt.Errorf("%w: rule %s has negative concurrency: %d",
+				domain.ErrConfigInvalid, r.Name, r.Concurrency)
+		}
+		if _, err := domain.ParseDeleteLimit(r.MaxDeletes); err != nil {
+			return fmt.Errorf("%w: rule %s: %v", domain.ErrConfigInvalid, r.Name, err)
+		}
+		if _, err := r.FileFilter(); err != nil {
+			return fmt.Errorf("%w: rule %s: %v", domain.ErrConfigInvalid, r.Name, err)
+		}
+		if r.Trash != nil {
+			if r.Trash.MaxCount < 0 {
+				return fmt.Errorf("%w: rule %s has negative trash.max_count: %d",
+					domain.ErrConfigInvalid, r.Name, r.Trash.MaxCount)
+			}
+			if r.Trash.MaxAge != "" {
+				d, err := time.ParseDuration(r.Trash.MaxAge)
+				if err != nil {
+					return fmt.Errorf("%w: invalid trash.max_age '%s' for rule '%s': %v",
+						domain.ErrConfigInvalid, r.Trash.MaxAge, r.Name, err)
+				}
+				if d <= 0 {
+					return fmt.Errorf("%w: trash.max_age must be positive for rule '%s', got '%s'",
+						domain.ErrConfigInvalid, r.Name, r.Trash.MaxAge)
+				}
+			}
+		}
 		if err := r.Validate(); err != nil {
 			return fmt.Errorf("rule %s: %w", r.Name, err)
 		}
 		ruleNames[r.Name] = true
 	}
 
+	// Validate checksum algorithm
+	if c.Settings.ChecksumAlgorithm != "" && !checksum.IsSupported(checksum.Algorithm(c.Settings.ChecksumAlgorithm)) {
+		return fmt.Errorf("%w: invalid settings.checksum_algorithm '%s' (must be 'md5' or 'sha256')",
+			domain.ErrConfigInvalid, c.Settings.ChecksumAlgorithm)
+	}
+
 	// Validate scheduler configuration
 	if c.Scheduler.DefaultInterval != "" {
 		_, err := time.ParseDuration(c.Scheduler.DefaultInterval)
@@ -176,15 +226,50 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	// Validate rule-level schedule intervals
+	switch c.Scheduler.Mode {
+	case "", "interval", "watch":
+	default:
+		return fmt.Errorf("%w: invalid scheduler.mode '%s' (must be 'interval' or 'watch')",
+			domain.ErrConfigInvalid, c.Scheduler.Mode)
+	}
+
+	if c.Scheduler.Debounce != "" {
+		d, err := time.ParseDuration(c.Scheduler.Debounce)
+		if err != nil {
+			return fmt.Errorf("%w: invalid scheduler.debounce '%s': %v",
+				domain.ErrConfigInvalid, c.Scheduler.Debounce, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%w: scheduler.debounce must be positive, got '%s'",
+				domain.ErrConfigInvalid, c.Scheduler.Debounce)
+		}
+	}
+
+	// Validate rule-level schedule intervals and cron expressions
 	for _, rule := range c.Rules {
-		if rule.Schedule != nil && rule.Schedule.Interval != "" {
+		if rule.Schedule == nil {
+			continue
+		}
+		if rule.Schedule.Interval != "" {
 			_, err := time.ParseDuration(rule.Schedule.Interval)
 			if err != nil {
 				return fmt.Errorf("%w: invalid schedule.interval '%s' for rule '%s': %v",
 					domain.ErrConfigInvalid, rule.Schedule.Interval, rule.Name, err)
 			}
 		}
+		if rule.Schedule.Cron != "" {
+			if rule.Schedule.Interval != "" {
+				return fmt.Errorf("%w: schedule.interval and schedule.cron are mutually exclusive for rule '%s'",
+					domain.ErrConfigInvalid, rule.Name)
+			}
+			if _, err := scheduler.ParseCron(rule.Schedule.Cron, rule.Schedule.Timezone); err != nil {
+				return fmt.Errorf("%w: invalid schedule for rule '%s': %v",
+					domain.ErrConfigInvalid, rule.Name, err)
+			}
+		} else if rule.Schedule.Timezone != "" {
+			return fmt.Errorf("%w: schedule.timezone requires schedule.cron for rule '%s'",
+				domain.ErrConfigInvalid, rule.Name)
+		}
 	}
 
 	return nil
@@ -318,6 +403,15 @@ func (c *Config) GetLockPath() string {
 	return filepath.Join(configDir, "syncrules", "locks")
 }
 
+// GetChecksumAlgorithm returns the checksum algorithm, using sha256 if not configured
+// All adapters of a sync session use the same algorithm so checksums are comparable
+func (c *Config) GetChecksumAlgorithm() checksum.Algorithm {
+	if c.Settings.ChecksumAlgorithm != "" {
+		return checksum.Algorithm(c.Settings.ChecksumAlgorithm)
+	}
+	return checksum.SHA256
+}
+
 // GetLogPath returns the log file path, using default if not configured
 func (c *Config) GetLogPath() string {
 	if c.Logging.File.Path != "" {
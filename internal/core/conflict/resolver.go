@@ -1,21 +1,41 @@
 package conflict
 
-import "github.com/Ning0612/Syncrules/internal/domain"
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// conflictTimeFormat is the timestamp layout used in conflict copy names
+const conflictTimeFormat = "20060102-150405"
 
 // Resolver resolves conflicts according to a strategy
 type Resolver interface {
 	// Resolve determines the appropriate action for a conflict
 	// Returns a SyncAction with the resolution decision
+	// An ActionRename moves the losing version aside; the planner follows it
+	// with a copy of the winning version
 	Resolve(strategy domain.ConflictStrategy, path string, src, tgt *domain.FileInfo) domain.SyncAction
 }
 
 // DefaultResolver implements standard conflict resolution strategies
 // Migrated from service/sync.go line 327-379
-type DefaultResolver struct{}
+type DefaultResolver struct {
+	// Host is recorded in conflict copy names
+	Host string
+}
 
 // NewDefaultResolver creates a new DefaultResolver
 func NewDefaultResolver() *DefaultResolver {
-	return &DefaultResolver{}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &DefaultResolver{Host: host}
 }
 
 // Resolve implements the Resolver interface
@@ -56,8 +76,8 @@ func (r *DefaultResolver) Resolve(strategy domain.ConflictStrategy, path string,
 		}
 
 	case domain.ConflictKeepNewest:
-		// Phase 2: Priority check - if both have checksums and they match, skip sync
-		if src.Checksum != "" && tgt.Checksum != "" {
+		// Phase 2: Priority check - if both have comparable checksums and they match, skip sync
+		if src.ChecksumComparable(*tgt) {
 			if src.Checksum == tgt.Checksum {
 				return domain.SyncAction{
 					Type:       domain.ActionSkip,
@@ -114,6 +134,9 @@ func (r *DefaultResolver) Resolve(strategy domain.ConflictStrategy, path string,
 			}
 		}
 
+	case domain.ConflictKeepBoth:
+		return r.resolveKeepBoth(path, src, tgt)
+
 	default: // ConflictManual or unknown
 		// Require manual resolution
 		return domain.SyncAction{
@@ -126,3 +149,149 @@ func (r *DefaultResolver) Resolve(strategy domain.ConflictStrategy, path string,
 		}
 	}
 }
+
+// resolveKeepBoth renames the older version to a conflict copy on its own
+// side so the newer one can be synced over it. Source wins on a tie
+func (r *DefaultResolver) resolveKeepBoth(path string, src, tgt *domain.FileInfo) domain.SyncAction {
+	if src.ChecksumComparable(*tgt) && src.Checksum == tgt.Checksum {
+		return domain.SyncAction{
+			Type:       domain.ActionSkip,
+			Direction:  domain.DirSourceToTarget,
+			Path:       path,
+			SourceInfo: src,
+			TargetInfo: tgt,
+			Reason:     "identical content (checksum match)",
+		}
+	}
+	if src.ModTime.Equal(tgt.ModTime) && src.Size == tgt.Size {
+		return domain.SyncAction{
+			Type:       domain.ActionSkip,
+			Direction:  domain.DirSourceToTarget,
+			Path:       path,
+			SourceInfo: src,
+			TargetInfo: tgt,
+			Reason:     "identical modification time and size",
+		}
+	}
+
+	// The rename happens on the side the winner is copied to
+	direction, loser := domain.DirSourceToTarget, tgt
+	if tgt.ModTime.After(src.ModTime) {
+		direction, loser = domain.DirTargetToSource, src
+	}
+	return domain.SyncAction{
+		Type:       domain.ActionRename,
+		Direction:  direction,
+		Path:       path,
+		NewPath:    ConflictCopyPath(path, r.Host, loser.ModTime),
+		SourceInfo: src,
+		TargetInfo: tgt,
+		Reason:     "keeping both versions (conflict strategy)",
+	}
+}
+
+// ApplyResolution returns the actions that carry out a user's resolution of a
+// conflict action. Returns domain.ErrInvalidResolution if it cannot be applied,
+// e.g. keeping both sides of a deletion or taking one side of a file/directory mismatch
+func (r *DefaultResolver) ApplyResolution(resolution domain.ConflictResolution, conflict domain.SyncAction) ([]domain.SyncAction, error) {
+	src, tgt := conflict.SourceInfo, conflict.TargetInfo
+	if resolution == domain.ResolveIgnore {
+		return []domain.SyncAction{{
+			Type:       domain.ActionSkip,
+			Direction:  conflict.Direction,
+			Path:       conflict.Path,
+			SourceInfo: src,
+			TargetInfo: tgt,
+			Reason:     "conflict ignored",
+		}}, nil
+	}
+
+	if src == nil || tgt == nil {
+		return nil, fmt.Errorf("%w: %s has no file info", domain.ErrInvalidResolution, conflict.Path)
+	}
+	if !src.IsDeleted && !tgt.IsDeleted && src.Type != tgt.Type {
+		return nil, fmt.Errorf("%w: %s is a file on one side and a directory on the other, it can only be ignored",
+			domain.ErrInvalidResolution, conflict.Path)
+	}
+
+	switch resolution {
+	case domain.ResolveTakeSource:
+		return []domain.SyncAction{takeSide(conflict, domain.DirSourceToTarget, src, "resolved: take source")}, nil
+
+	case domain.ResolveTakeTarget:
+		return []domain.SyncAction{takeSide(conflict, domain.DirTargetToSource, tgt, "resolved: take target")}, nil
+
+	case domain.ResolveKeepBoth:
+		if src.IsDeleted || tgt.IsDeleted {
+			return nil, fmt.Errorf("%w: %s was deleted on one side, take source or target instead",
+				domain.ErrInvalidResolution, conflict.Path)
+		}
+		if src.IsDir() {
+			return nil, fmt.Errorf("%w: %s is a directory", domain.ErrInvalidResolution, conflict.Path)
+		}
+		rename := r.resolveKeepBoth(conflict.Path, src, tgt)
+		if rename.Type != domain.ActionRename {
+			return []domain.SyncAction{rename}, nil
+		}
+		rename.Reason = "resolved: keep both"
+		return []domain.SyncAction{rename, {
+			Type:       domain.ActionCopy,
+			Direction:  rename.Direction,
+			Path:       conflict.Path,
+			SourceInfo: src,
+			TargetInfo: tgt,
+			Reason:     "resolved: keep both",
+		}}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrInvalidResolution, resolution)
+	}
+}
+
+// takeSide syncs the winning side in the given direction: a copy (or mkdir) if
+// it exists, or a delete if the conflict was a deletion on that side
+func takeSide(conflict domain.SyncAction, direction domain.SyncDirection, winner *domain.FileInfo, reason string) domain.SyncAction {
+	action := domain.SyncAction{
+		Type:       domain.ActionCopy,
+		Direction:  direction,
+		Path:       conflict.Path,
+		SourceInfo: conflict.SourceInfo,
+		TargetInfo: conflict.TargetInfo,
+		Reason:     reason,
+	}
+	switch {
+	case winner.IsDeleted:
+		action.Type = domain.ActionDelete
+	case winner.IsDir():
+		action.Type = domain.ActionMkdir
+	}
+	return action
+}
+
+// ConflictCopyPath returns the name a losing version is kept under:
+// name.conflict-<host>-<timestamp>.ext, in the same directory
+func ConflictCopyPath(p, host string, modTime time.Time) string {
+	dir, base := path.Split(p)
+	ext := path.Ext(base)
+	if ext == base {
+		// Dotfiles such as .bashrc have no extension
+		ext = ""
+	}
+	name := strings.TrimSuffix(base, ext)
+	return dir + name + ".conflict-" + sanitizeHost(host) + "-" + modTime.UTC().Format(conflictTimeFormat) + ext
+}
+
+// sanitizeHost keeps a host name safe for use in a file name
+func sanitizeHost(host string) string {
+	host = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, host)
+	if host == "" {
+		return "unknown"
+	}
+	return host
+}
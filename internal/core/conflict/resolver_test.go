@@ -1,6 +1,7 @@
 package conflict
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -259,3 +260,129 @@ func TestResolve_KeepNewest_OnlyOneHasChecksum(t *testing.T) {
 		t.Errorf("Expected time-based reason, got %v", action.Reason)
 	}
 }
+
+func TestResolve_KeepBoth_TargetNewer(t *testing.T) {
+	resolver := &DefaultResolver{Host: "laptop"}
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	src := &domain.FileInfo{Path: "conf/app.yaml", Size: 100, ModTime: now}
+	tgt := &domain.FileInfo{Path: "conf/app.yaml", Size: 200, ModTime: now.Add(time.Hour)}
+
+	action := resolver.Resolve(domain.ConflictKeepBoth, "conf/app.yaml", src, tgt)
+
+	if action.Type != domain.ActionRename {
+		t.Fatalf("Expected ActionRename, got %v", action.Type)
+	}
+	// The older source version is renamed on the source side
+	if action.Direction != domain.DirTargetToSource {
+		t.Errorf("Expected DirTargetToSource, got %v", action.Direction)
+	}
+	if action.NewPath != "conf/app.conflict-laptop-20240501-103000.yaml" {
+		t.Errorf("Unexpected conflict copy path: %s", action.NewPath)
+	}
+}
+
+func TestResolve_KeepBoth_SourceWinsTie(t *testing.T) {
+	resolver := &DefaultResolver{Host: "laptop"}
+	now := time.Now()
+
+	src := &domain.FileInfo{Path: "test.txt", Size: 100, ModTime: now}
+	tgt := &domain.FileInfo{Path: "test.txt", Size: 200, ModTime: now}
+
+	action := resolver.Resolve(domain.ConflictKeepBoth, "test.txt", src, tgt)
+
+	if action.Type != domain.ActionRename || action.Direction != domain.DirSourceToTarget {
+		t.Errorf("Expected the target version to be renamed, got %v %v", action.Type, action.Direction)
+	}
+}
+
+func TestResolve_KeepBoth_Identical(t *testing.T) {
+	resolver := &DefaultResolver{Host: "laptop"}
+	now := time.Now()
+
+	src := &domain.FileInfo{Path: "test.txt", Size: 100, ModTime: now, Checksum: "abc", ChecksumAlgorithm: "sha256"}
+	tgt := &domain.FileInfo{Path: "test.txt", Size: 100, ModTime: now.Add(time.Hour), Checksum: "abc", ChecksumAlgorithm: "sha256"}
+
+	action := resolver.Resolve(domain.ConflictKeepBoth, "test.txt", src, tgt)
+
+	if action.Type != domain.ActionSkip {
+		t.Errorf("Expected ActionSkip for identical content, got %v", action.Type)
+	}
+}
+
+func TestConflictCopyPath(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		path string
+		host string
+		want string
+	}{
+		{"notes.txt", "laptop", "notes.conflict-laptop-20240501-103000.txt"},
+		{"dir/archive.tar.gz", "laptop", "dir/archive.tar.conflict-laptop-20240501-103000.gz"},
+		{".bashrc", "laptop", ".bashrc.conflict-laptop-20240501-103000"},
+		{"Makefile", "my host.local", "Makefile.conflict-my-host-local-20240501-103000"},
+		{"a.txt", "", "a.conflict-unknown-20240501-103000.txt"},
+	}
+
+	for _, tt := range tests {
+		if got := ConflictCopyPath(tt.path, tt.host, modTime); got != tt.want {
+			t.Errorf("ConflictCopyPath(%q, %q) = %q, want %q", tt.path, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestApplyResolution(t *testing.T) {
+	resolver := &DefaultResolver{Host: "laptop"}
+	now := time.Now()
+
+	src := &domain.FileInfo{Path: "a.txt", Size: 100, ModTime: now}
+	tgt := &domain.FileInfo{Path: "a.txt", Size: 200, ModTime: now.Add(time.Hour)}
+	deleted := src.Tombstone()
+	dir := &domain.FileInfo{Path: "a.txt", Type: domain.FileTypeDirectory, ModTime: now}
+
+	conflictOf := func(s, t *domain.FileInfo) domain.SyncAction {
+		return domain.SyncAction{Type: domain.ActionConflict, Path: "a.txt", SourceInfo: s, TargetInfo: t}
+	}
+
+	tests := []struct {
+		name       string
+		resolution domain.ConflictResolution
+		conflict   domain.SyncAction
+		want       []domain.ActionType
+		direction  domain.SyncDirection
+		wantErr    bool
+	}{
+		{"take source", domain.ResolveTakeSource, conflictOf(src, tgt), []domain.ActionType{domain.ActionCopy}, domain.DirSourceToTarget, false},
+		{"take target", domain.ResolveTakeTarget, conflictOf(src, tgt), []domain.ActionType{domain.ActionCopy}, domain.DirTargetToSource, false},
+		{"take deleted source", domain.ResolveTakeSource, conflictOf(&deleted, tgt), []domain.ActionType{domain.ActionDelete}, domain.DirSourceToTarget, false},
+		{"keep both", domain.ResolveKeepBoth, conflictOf(src, tgt), []domain.ActionType{domain.ActionRename, domain.ActionCopy}, domain.DirTargetToSource, false},
+		{"keep both of a deletion", domain.ResolveKeepBoth, conflictOf(&deleted, tgt), nil, 0, true},
+		{"take side of a type mismatch", domain.ResolveTakeSource, conflictOf(src, dir), nil, 0, true},
+		{"ignore type mismatch", domain.ResolveIgnore, conflictOf(src, dir), []domain.ActionType{domain.ActionSkip}, domain.DirSourceToTarget, false},
+		{"unknown", domain.ConflictResolution("merge"), conflictOf(src, tgt), nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := resolver.ApplyResolution(tt.resolution, tt.conflict)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidResolution) {
+					t.Fatalf("Expected ErrInvalidResolution, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyResolution failed: %v", err)
+			}
+			if len(actions) != len(tt.want) {
+				t.Fatalf("Expected %v, got %+v", tt.want, actions)
+			}
+			for i, action := range actions {
+				if action.Type != tt.want[i] || action.Direction != tt.direction || action.Path != "a.txt" {
+					t.Errorf("Action %d: expected %v in direction %v, got %+v", i, tt.want[i], tt.direction, action)
+				}
+			}
+		})
+	}
+}
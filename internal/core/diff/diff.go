@@ -26,8 +26,8 @@ type Comparer interface {
 // DefaultComparer uses mtime + size comparison
 // This is the default strategy as specified in CLAUDE.md line 139
 //
-// Future enhancement: Support optional checksum/hash comparison
-// for detecting content changes when size is unchanged
+// When mtimes differ, checksums are compared if both files carry
+// checksums produced by the same algorithm
 type DefaultComparer struct{}
 
 // NewDefaultComparer creates a new DefaultComparer
@@ -65,8 +65,8 @@ func (c *DefaultComparer) Compare(src, tgt *domain.FileInfo) DiffResult {
 		// Size same, check mtime
 		// Use ModTime.Equal() to handle platform-specific precision
 		if !src.ModTime.Equal(tgt.ModTime) {
-			// Phase 2: If both have checksums, use content comparison
-			if src.Checksum != "" && tgt.Checksum != "" {
+			// Phase 2: If both have checksums from the same algorithm, use content comparison
+			if src.ChecksumComparable(*tgt) {
 				if src.Checksum == tgt.Checksum {
 					// Content is identical despite different mtime
 					return FilesIdentical
@@ -75,12 +75,13 @@ func (c *DefaultComparer) Compare(src, tgt *domain.FileInfo) DiffResult {
 				return FileModified
 			}
 
-			// No checksums available (e.g., large files exceeding MaxSize)
+			// No comparable checksums available (e.g., large files exceeding MaxSize,
+			// or adapters exposing different algorithms)
 			// For large files, if size matches, assume content is identical
 			// Rationale: Large files rarely have same size but different content
 			// This prevents unnecessary copying of large files
-			if src.Checksum == "" && tgt.Checksum == "" {
-				// Both lack checksums - use size as heuristic
+			if (src.Checksum == "") == (tgt.Checksum == "") {
+				// Both lack comparable checksums - use size as heuristic
 				// Size already matched (we're in the "size same" branch)
 				return FilesIdentical
 			}
@@ -296,3 +296,58 @@ func TestDefaultComparer_OnlySourceHasChecksum(t *testing.T) {
 		t.Errorf("Expected FileModified when only one file has checksum, got %v", result)
 	}
 }
+
+func TestDefaultComparer_ChecksumAlgorithmMismatch(t *testing.T) {
+	comparer := NewDefaultComparer()
+	now := time.Now()
+
+	// Same content hashed with different algorithms must not be compared
+	src := &domain.FileInfo{
+		Path:              "test.txt",
+		Type:              domain.FileTypeRegular,
+		Size:              100,
+		ModTime:           now,
+		Checksum:          "9f86d081884c7d65",
+		ChecksumAlgorithm: "sha256",
+	}
+	tgt := &domain.FileInfo{
+		Path:              "test.txt",
+		Type:              domain.FileTypeRegular,
+		Size:              100,
+		ModTime:           now.Add(1 * time.Hour),
+		Checksum:          "098f6bcd4621d373",
+		ChecksumAlgorithm: "md5",
+	}
+
+	result := comparer.Compare(src, tgt)
+	if result != FilesIdentical {
+		t.Errorf("Expected FilesIdentical when checksum algorithms differ and size matches, got %v", result)
+	}
+}
+
+func TestDefaultComparer_ChecksumAlgorithmMatch(t *testing.T) {
+	comparer := NewDefaultComparer()
+	now := time.Now()
+
+	src := &domain.FileInfo{
+		Path:              "test.txt",
+		Type:              domain.FileTypeRegular,
+		Size:              100,
+		ModTime:           now,
+		Checksum:          "abc123",
+		ChecksumAlgorithm: "md5",
+	}
+	tgt := &domain.FileInfo{
+		Path:              "test.txt",
+		Type:              domain.FileTypeRegular,
+		Size:              100,
+		ModTime:           now.Add(1 * time.Hour),
+		Checksum:          "def456",
+		ChecksumAlgorithm: "md5",
+	}
+
+	result := comparer.Compare(src, tgt)
+	if result != FileModified {
+		t.Errorf("Expected FileModified when same-algorithm checksums differ, got %v", result)
+	}
+}
@@ -0,0 +1,197 @@
+// Package ignore decides which paths a sync leaves alone, with gitignore semantics
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// FileName is the name of ignore files read from the source tree
+// Their patterns apply to the directory they are in and everything beneath it
+const FileName = ".syncignore"
+
+// Matcher holds ignore rules in order of precedence, lowest first
+// Like git, the last rule matching a path decides, "!" re-includes, and a path
+// inside an ignored directory is ignored whatever its own rules say
+type Matcher struct {
+	rules []pattern
+}
+
+// pattern is one parsed ignore rule
+type pattern struct {
+	base     string   // Directory the rule applies beneath ("" = root)
+	segments []string // Pattern split at "/"
+	negate   bool     // "!pattern" re-includes matching paths
+	dirOnly  bool     // "pattern/" only matches directories
+	anchored bool     // Contains a "/", so it matches from base rather than any level
+}
+
+// New creates a matcher for patterns that apply to the whole tree
+func New(patterns []string) *Matcher {
+	m := &Matcher{}
+	m.AddPatterns("", patterns)
+	return m
+}
+
+// AddPatterns adds patterns that apply beneath base, a slash-separated
+// directory relative to the root ("" for the root itself)
+// They take precedence over the rules added before them
+func (m *Matcher) AddPatterns(base string, patterns []string) {
+	base = normalize(base)
+	for _, line := range patterns {
+		if p, ok := parse(line); ok {
+			p.base = base
+			m.rules = append(m.rules, p)
+		}
+	}
+}
+
+// AddFile adds the patterns of an ignore file found in directory base
+func (m *Matcher) AddFile(base string, r io.Reader) error {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", path.Join(base, FileName), err)
+	}
+	m.AddPatterns(base, lines)
+	return nil
+}
+
+// Ignored reports whether a slash-separated path relative to the root is ignored
+// isDir tells whether the path is a directory, for patterns ending in "/"
+func (m *Matcher) Ignored(p string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	p = normalize(p)
+	if p == "" {
+		return false
+	}
+
+	segments := strings.Split(p, "/")
+	for i := 1; i < len(segments); i++ {
+		if m.match(segments[:i], true) {
+			return true
+		}
+	}
+	return m.match(segments, isDir)
+}
+
+// match applies the rules to a path alone, without looking at its parents
+func (m *Matcher) match(segments []string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.matches(segments, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the rule matches a path given as segments from the root
+func (p pattern) matches(segments []string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		base := strings.Split(p.base, "/")
+		if len(segments) <= len(base) {
+			return false
+		}
+		for i, seg := range base {
+			if segments[i] != seg {
+				return false
+			}
+		}
+		segments = segments[len(base):]
+	}
+
+	if !p.anchored {
+		ok, err := path.Match(p.segments[0], segments[len(segments)-1])
+		return err == nil && ok
+	}
+	return matchSegments(p.segments, segments)
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// stands for any number of directories
+func matchSegments(pat, segments []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			if len(pat) == 1 {
+				// "dir/**" matches everything inside dir, not dir itself
+				return len(segments) > 0
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pat[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		ok, err := path.Match(pat[0], segments[0])
+		if err != nil || !ok {
+			return false
+		}
+		pat, segments = pat[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// parse turns a line of an ignore file into a rule
+// Blank lines and comments yield no rule
+func parse(line string) (pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are dropped unless escaped
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, "\\") && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	line = trimmed
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+
+	var p pattern
+	switch {
+	case strings.HasPrefix(line, "!"):
+		p.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	p.anchored = strings.Contains(line, "/")
+	line = strings.TrimLeft(line, "/")
+	if line == "" {
+		return pattern{}, false
+	}
+
+	p.segments = strings.Split(line, "/")
+	return p, true
+}
+
+// normalize returns a path with "/" separators and no leading "./" or "/"
+// Backslashes are treated as separators, as in paths from Windows
+func normalize(p string) string {
+	p = strings.ReplaceAll(p, "\\", "/")
+	p = strings.TrimPrefix(p, "./")
+	return strings.Trim(p, "/")
+}
@@ -0,0 +1,84 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatcher_Patterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"name at any depth", []string{"*.log"}, "a/b/error.log", false, true},
+		{"name does not match substring", []string{"*.log"}, "a/error.log.txt", false, false},
+		{"parent directory excludes children", []string{"node_modules"}, "src/node_modules/lib/x.js", false, true},
+		{"anchored to root", []string{"/build"}, "build", true, true},
+		{"anchored does not match nested", []string{"/build"}, "src/build", true, false},
+		{"middle slash anchors", []string{"docs/*.md"}, "docs/a.md", false, true},
+		{"middle slash anchors nested", []string{"docs/*.md"}, "x/docs/a.md", false, false},
+		{"star stops at separator", []string{"docs/*.md"}, "docs/sub/a.md", false, false},
+		{"directory only matches directory", []string{"cache/"}, "cache", true, true},
+		{"directory only skips files", []string{"cache/"}, "cache", false, false},
+		{"directory only excludes contents", []string{"cache/"}, "a/cache/file", false, true},
+		{"leading double star", []string{"**/tmp"}, "a/b/tmp", true, true},
+		{"middle double star", []string{"a/**/z.txt"}, "a/b/c/z.txt", false, true},
+		{"middle double star matches zero dirs", []string{"a/**/z.txt"}, "a/z.txt", false, true},
+		{"trailing double star", []string{"vendor/**"}, "vendor/x/y.go", false, true},
+		{"trailing double star not dir itself", []string{"vendor/**"}, "vendor", true, false},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"last match wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"negation cannot escape ignored parent", []string{"logs/", "!logs/keep.log"}, "logs/keep.log", false, true},
+		{"negation inside unignored parent", []string{"logs/*", "!logs/keep.log"}, "logs/keep.log", false, false},
+		{"comment and blank lines", []string{"# *.log", "", "   "}, "a.log", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"character class", []string{"file[0-9].txt"}, "file7.txt", false, true},
+		{"backslash separators", []string{".system"}, `.system\back\slash`, false, true},
+		{"no patterns", nil, "a.txt", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.patterns).Ignored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Ignored(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_AddFile(t *testing.T) {
+	m := New([]string{"*.tmp"})
+	if err := m.AddFile("", strings.NewReader("*.log\r\nbuild/\n")); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+	if err := m.AddFile("docs", strings.NewReader("!keep.log\n/draft.md\n!notes.tmp\n")); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"docs/keep.log", false, false},
+		{"docs/sub/keep.log", false, false},
+		{"other/keep.log", false, true},
+		{"docs/draft.md", false, true},
+		{"docs/sub/draft.md", false, false},
+		{"draft.md", false, false},
+		{"docs/notes.tmp", false, false},
+		{"notes.tmp", false, true},
+		{"docs/build", true, true},
+		{"docs", true, false},
+	}
+	for _, tt := range tests {
+		if got := m.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// ApplyFilters records the paths a rule's filters left out of a plan, planned
+// from listings without them, and updates its actions, baseline and stats
+// Directories are not deleted while filtered paths remain beneath them, and
+// not created when only filtered paths are beneath them. In two-way plans,
+// filtered paths keep their baseline entry, so deletions are still detected
+// once they pass the filters again
+func ApplyFilters(plan *domain.SyncPlan, filtered []domain.FilteredPath, baseline map[string]domain.FileInfo) {
+	if len(filtered) == 0 {
+		return
+	}
+
+	hasFiltered := make(map[string]bool)
+	for _, f := range filtered {
+		markParents(hasFiltered, f.Path)
+		if base, ok := baseline[f.Path]; ok && plan.Baseline != nil {
+			plan.Baseline[f.Path] = base
+		}
+	}
+
+	// Directories with synced paths beneath them, found deepest first
+	hasSynced := make(map[string]bool)
+	for _, action := range plan.Actions {
+		if action.Type != domain.ActionMkdir {
+			for _, p := range actionPaths(action) {
+				markParents(hasSynced, p)
+			}
+		}
+	}
+	mkdirs := make([]domain.SyncAction, 0)
+	for _, action := range plan.Actions {
+		if action.Type == domain.ActionMkdir {
+			mkdirs = append(mkdirs, action)
+		}
+	}
+	sort.SliceStable(mkdirs, func(i, j int) bool {
+		return pathDepth(mkdirs[i].Path) > pathDepth(mkdirs[j].Path)
+	})
+	emptyDirs := make(map[string]bool)
+	for _, action := range mkdirs {
+		if hasFiltered[action.Path] && !hasSynced[action.Path] {
+			emptyDirs[action.Path] = true
+			continue
+		}
+		markParents(hasSynced, action.Path)
+	}
+
+	actions := make([]domain.SyncAction, 0, len(plan.Actions))
+	for _, action := range plan.Actions {
+		switch {
+		case action.Type == domain.ActionMkdir && emptyDirs[action.Path]:
+			filtered = append(filtered, domain.FilteredPath{Path: action.Path, Reason: "only filtered paths beneath"})
+		case action.Type == domain.ActionDelete && hasFiltered[action.Path]:
+			filtered = append(filtered, domain.FilteredPath{Path: action.Path, Reason: "filtered paths beneath"})
+			if base, ok := baseline[action.Path]; ok && plan.Baseline != nil {
+				plan.Baseline[action.Path] = base
+			}
+		default:
+			actions = append(actions, action)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Path < filtered[j].Path
+	})
+	plan.Actions = actions
+	plan.Filtered = filtered
+	plan.Conflicts = nil
+	plan.Stats = domain.SyncPlanStats{TrackedPaths: plan.Stats.TrackedPaths, Filtered: len(filtered)}
+	calculateStats(plan)
+}
+
+// markParents marks every directory above a path
+func markParents(dirs map[string]bool, path string) {
+	for i := strings.LastIndex(path, "/"); i > 0; i = strings.LastIndex(path, "/") {
+		path = path[:i]
+		if dirs[path] {
+			return
+		}
+		dirs[path] = true
+	}
+}
+
+// actionPaths returns the paths an action changes
+func actionPaths(action domain.SyncAction) []string {
+	if action.NewPath != "" {
+		return []string{action.Path, action.NewPath}
+	}
+	return []string{action.Path}
+}
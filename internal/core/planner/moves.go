@@ -0,0 +1,93 @@
+package planner
+
+import (
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// moveKey identifies file content moving in one direction
+type moveKey struct {
+	direction domain.SyncDirection
+	size      int64
+	algorithm string
+	checksum  string
+}
+
+// detectMoves replaces a copy of a new file and a delete of a file with the
+// same size and checksum, in the same direction, by a move of the deleted
+// file to the new path, so a rename on one side is replayed on the other
+// instead of transferring the content again
+// Content shared by several new or several deleted files is ambiguous and
+// left as copies and deletes, as are empty files and files without checksums
+func detectMoves(actions []domain.SyncAction) []domain.SyncAction {
+	copies := make(map[moveKey][]int)
+	deletes := make(map[moveKey][]int)
+	for i, action := range actions {
+		switch action.Type {
+		case domain.ActionCopy:
+			if action.DestinationInfo() != nil {
+				continue
+			}
+			if key, ok := moveKeyOf(action.Direction, action.OriginInfo()); ok {
+				copies[key] = append(copies[key], i)
+			}
+		case domain.ActionDelete:
+			if key, ok := moveKeyOf(action.Direction, action.DestinationInfo()); ok {
+				deletes[key] = append(deletes[key], i)
+			}
+		}
+	}
+
+	// The copy index of each pair is replaced by the move, the delete dropped
+	moves := make(map[int]domain.SyncAction)
+	dropped := make(map[int]bool)
+	for key, copyIdx := range copies {
+		deleteIdx := deletes[key]
+		if len(copyIdx) != 1 || len(deleteIdx) != 1 {
+			continue
+		}
+
+		cp, del := actions[copyIdx[0]], actions[deleteIdx[0]]
+		move := domain.SyncAction{
+			Type:      domain.ActionMove,
+			Direction: key.direction,
+			Path:      del.Path,
+			NewPath:   cp.Path,
+			Reason:    "moved to " + cp.Path,
+		}
+		if key.direction == domain.DirSourceToTarget {
+			move.SourceInfo, move.TargetInfo = cp.SourceInfo, del.TargetInfo
+		} else {
+			move.SourceInfo, move.TargetInfo = del.SourceInfo, cp.TargetInfo
+		}
+		moves[copyIdx[0]] = move
+		dropped[deleteIdx[0]] = true
+	}
+	if len(moves) == 0 {
+		return actions
+	}
+
+	result := make([]domain.SyncAction, 0, len(actions)-len(dropped))
+	for i, action := range actions {
+		if dropped[i] {
+			continue
+		}
+		if move, ok := moves[i]; ok {
+			action = move
+		}
+		result = append(result, action)
+	}
+	return result
+}
+
+// moveKeyOf returns the key of a file that may take part in a move
+func moveKeyOf(direction domain.SyncDirection, info *domain.FileInfo) (moveKey, bool) {
+	if info == nil || !info.IsFile() || info.IsDeleted || info.Size == 0 || info.Checksum == "" {
+		return moveKey{}, false
+	}
+	return moveKey{
+		direction: direction,
+		size:      info.Size,
+		algorithm: info.ChecksumAlgorithm,
+		checksum:  info.Checksum,
+	}, true
+}
@@ -1,12 +1,12 @@
 package planner
 
 import (
-	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/Ning0612/Syncrules/internal/core/conflict"
 	"github.com/Ning0612/Syncrules/internal/core/diff"
+	"github.com/Ning0612/Syncrules/internal/core/ignore"
 	"github.com/Ning0612/Syncrules/internal/domain"
 )
 
@@ -14,6 +14,7 @@ import (
 type Planner interface {
 	PlanOneWay(fromMap, toMap map[string]domain.FileInfo, rule *domain.SyncRule, direction domain.SyncDirection) *domain.SyncPlan
 	PlanTwoWay(sourceMap, targetMap map[string]domain.FileInfo, rule *domain.SyncRule) *domain.SyncPlan
+	PlanTwoWayWithBaseline(sourceMap, targetMap, baseline map[string]domain.FileInfo, rule *domain.SyncRule) *domain.SyncPlan
 }
 
 // DefaultPlanner uses diff and conflict modules
@@ -33,27 +34,34 @@ func NewDefaultPlanner() *DefaultPlanner {
 
 // PlanOneWay generates actions for one-way sync
 // Migrated from service/sync.go line 182-247
+// Action infos are stored by endpoint, as in two-way plans: SourceInfo
+// describes the source endpoint even when direction is DirTargetToSource
 func (p *DefaultPlanner) PlanOneWay(fromMap, toMap map[string]domain.FileInfo, rule *domain.SyncRule, direction domain.SyncDirection) *domain.SyncPlan {
 	plan := &domain.SyncPlan{
 		RuleName: rule.Name,
 		Actions:  make([]domain.SyncAction, 0),
 	}
 
+	ignored := ignore.New(rule.IgnorePatterns)
+
 	// Files to copy: in "from" but not in "to", or different
 	for path, fromInfo := range fromMap {
-		if ShouldIgnore(path, rule.IgnorePatterns) {
+		if ignored.Ignored(path, fromInfo.IsDir()) {
 			continue
 		}
+		plan.Stats.TrackedPaths++
 
 		fromCopy := fromInfo
 		toInfo, exists := toMap[path]
 		if !exists {
+			srcInfo, tgtInfo := endpointInfo(direction, &fromCopy, nil)
 			if fromInfo.IsDir() {
 				plan.Actions = append(plan.Actions, domain.SyncAction{
 					Type:       domain.ActionMkdir,
 					Direction:  direction,
 					Path:       path,
-					SourceInfo: &fromCopy,
+					SourceInfo: srcInfo,
+					TargetInfo: tgtInfo,
 					Reason:     "directory does not exist",
 				})
 			} else {
@@ -61,19 +69,21 @@ func (p *DefaultPlanner) PlanOneWay(fromMap, toMap map[string]domain.FileInfo, r
 					Type:       domain.ActionCopy,
 					Direction:  direction,
 					Path:       path,
-					SourceInfo: &fromCopy,
+					SourceInfo: srcInfo,
+					TargetInfo: tgtInfo,
 					Reason:     "file does not exist",
 				})
 			}
 		} else if fromInfo.Type != toInfo.Type {
 			// Mixed type conflict (file vs directory)
 			toCopy := toInfo
+			srcInfo, tgtInfo := endpointInfo(direction, &fromCopy, &toCopy)
 			plan.Actions = append(plan.Actions, domain.SyncAction{
 				Type:       domain.ActionConflict,
 				Direction:  direction,
 				Path:       path,
-				SourceInfo: &fromCopy,
-				TargetInfo: &toCopy,
+				SourceInfo: srcInfo,
+				TargetInfo: tgtInfo,
 				Reason:     "type mismatch: file vs directory",
 			})
 		} else if fromInfo.IsFile() && toInfo.IsFile() {
@@ -81,12 +91,13 @@ func (p *DefaultPlanner) PlanOneWay(fromMap, toMap map[string]domain.FileInfo, r
 			result := p.Differ.Compare(&fromInfo, &toInfo)
 			if result == diff.FileModified {
 				toCopy := toInfo
+				srcInfo, tgtInfo := endpointInfo(direction, &fromCopy, &toCopy)
 				plan.Actions = append(plan.Actions, domain.SyncAction{
 					Type:       domain.ActionCopy,
 					Direction:  direction,
 					Path:       path,
-					SourceInfo: &fromCopy,
-					TargetInfo: &toCopy,
+					SourceInfo: srcInfo,
+					TargetInfo: tgtInfo,
 					Reason:     "file modified",
 				})
 			}
@@ -95,23 +106,29 @@ func (p *DefaultPlanner) PlanOneWay(fromMap, toMap map[string]domain.FileInfo, r
 
 	// Files to delete: in "to" but not in "from"
 	for path, toInfo := range toMap {
-		if ShouldIgnore(path, rule.IgnorePatterns) {
+		if ignored.Ignored(path, toInfo.IsDir()) {
 			continue
 		}
 
 		if _, exists := fromMap[path]; !exists {
+			plan.Stats.TrackedPaths++
 			toCopy := toInfo
+			srcInfo, tgtInfo := endpointInfo(direction, nil, &toCopy)
 			plan.Actions = append(plan.Actions, domain.SyncAction{
 				Type:       domain.ActionDelete,
 				Direction:  direction,
 				Path:       path,
-				TargetInfo: &toCopy,
+				SourceInfo: srcInfo,
+				TargetInfo: tgtInfo,
 				Reason:     "file does not exist on source",
 			})
 		}
 	}
 
-	// Sort actions: Mkdir first, then Copy, then Delete
+	// Renamed files are moved on the destination instead of copied again
+	plan.Actions = detectMoves(plan.Actions)
+
+	// Sort actions: Mkdir first, then Move, then Copy, then Delete
 	sortActions(plan.Actions)
 
 	calculateStats(plan)
@@ -121,9 +138,18 @@ func (p *DefaultPlanner) PlanOneWay(fromMap, toMap map[string]domain.FileInfo, r
 // PlanTwoWay generates actions for bidirectional sync
 // Migrated from service/sync.go line 249-324
 func (p *DefaultPlanner) PlanTwoWay(sourceMap, targetMap map[string]domain.FileInfo, rule *domain.SyncRule) *domain.SyncPlan {
+	return p.PlanTwoWayWithBaseline(sourceMap, targetMap, nil, rule)
+}
+
+// PlanTwoWayWithBaseline generates actions for bidirectional sync using the
+// tree recorded after the previous successful sync. A path missing on one side
+// but present in the baseline was deleted there, so the deletion is propagated
+// instead of the file being copied back. A nil baseline behaves like a first sync.
+func (p *DefaultPlanner) PlanTwoWayWithBaseline(sourceMap, targetMap, baseline map[string]domain.FileInfo, rule *domain.SyncRule) *domain.SyncPlan {
 	plan := &domain.SyncPlan{
 		RuleName: rule.Name,
 		Actions:  make([]domain.SyncAction, 0),
+		Baseline: make(map[string]domain.FileInfo),
 	}
 
 	allPaths := make(map[string]bool)
@@ -134,27 +160,110 @@ func (p *DefaultPlanner) PlanTwoWay(sourceMap, targetMap map[string]domain.FileI
 		allPaths[path] = true
 	}
 
-	for path := range allPaths {
-		if ShouldIgnore(path, rule.IgnorePatterns) {
-			continue
-		}
+	// Directory deletions are decided after all files, since a directory
+	// can only be removed if nothing beneath it survives
+	var dirDeletes []domain.SyncAction
+	deleting := map[domain.SyncDirection]map[string]bool{
+		domain.DirSourceToTarget: make(map[string]bool),
+		domain.DirTargetToSource: make(map[string]bool),
+	}
 
+	ignored := ignore.New(rule.IgnorePatterns)
+	for path := range allPaths {
 		srcInfo, srcExists := sourceMap[path]
 		tgtInfo, tgtExists := targetMap[path]
+		baseInfo, baseExists := baseline[path]
+
+		if ignored.Ignored(path, srcInfo.IsDir() || tgtInfo.IsDir()) {
+			continue
+		}
+		plan.Stats.TrackedPaths++
 
 		switch {
+		case srcExists && !tgtExists && baseExists:
+			// Synced before and now missing on target -> deleted on target
+			srcCopy := srcInfo
+			tombstone := baseInfo.Tombstone()
+			if srcInfo.IsDir() && baseInfo.IsDir() {
+				deleting[domain.DirTargetToSource][path] = true
+				dirDeletes = append(dirDeletes, domain.SyncAction{
+					Type:       domain.ActionDelete,
+					Direction:  domain.DirTargetToSource,
+					Path:       path,
+					SourceInfo: &srcCopy,
+					TargetInfo: &tombstone,
+					Reason:     "directory deleted on target",
+				})
+			} else if !p.changedSince(&srcInfo, &baseInfo) {
+				deleting[domain.DirTargetToSource][path] = true
+				plan.Actions = append(plan.Actions, domain.SyncAction{
+					Type:       domain.ActionDelete,
+					Direction:  domain.DirTargetToSource,
+					Path:       path,
+					SourceInfo: &srcCopy,
+					TargetInfo: &tombstone,
+					Reason:     "file deleted on target",
+				})
+			} else {
+				plan.Actions = append(plan.Actions, domain.SyncAction{
+					Type:       domain.ActionConflict,
+					Direction:  domain.DirSourceToTarget,
+					Path:       path,
+					SourceInfo: &srcCopy,
+					TargetInfo: &tombstone,
+					Reason:     "modified on source, deleted on target",
+				})
+				plan.Baseline[path] = baseInfo
+			}
+
+		case !srcExists && tgtExists && baseExists:
+			// Synced before and now missing on source -> deleted on source
+			tgtCopy := tgtInfo
+			tombstone := baseInfo.Tombstone()
+			if tgtInfo.IsDir() && baseInfo.IsDir() {
+				deleting[domain.DirSourceToTarget][path] = true
+				dirDeletes = append(dirDeletes, domain.SyncAction{
+					Type:       domain.ActionDelete,
+					Direction:  domain.DirSourceToTarget,
+					Path:       path,
+					SourceInfo: &tombstone,
+					TargetInfo: &tgtCopy,
+					Reason:     "directory deleted on source",
+				})
+			} else if !p.changedSince(&tgtInfo, &baseInfo) {
+				deleting[domain.DirSourceToTarget][path] = true
+				plan.Actions = append(plan.Actions, domain.SyncAction{
+					Type:       domain.ActionDelete,
+					Direction:  domain.DirSourceToTarget,
+					Path:       path,
+					SourceInfo: &tombstone,
+					TargetInfo: &tgtCopy,
+					Reason:     "file deleted on source",
+				})
+			} else {
+				plan.Actions = append(plan.Actions, domain.SyncAction{
+					Type:       domain.ActionConflict,
+					Direction:  domain.DirSourceToTarget,
+					Path:       path,
+					SourceInfo: &tombstone,
+					TargetInfo: &tgtCopy,
+					Reason:     "deleted on source, modified on target",
+				})
+				plan.Baseline[path] = baseInfo
+			}
+
 		case srcExists && !tgtExists:
 			// Only on source -> copy to target
+			srcCopy := srcInfo
 			if srcInfo.IsDir() {
 				plan.Actions = append(plan.Actions, domain.SyncAction{
 					Type:       domain.ActionMkdir,
 					Direction:  domain.DirSourceToTarget,
 					Path:       path,
-					SourceInfo: &srcInfo,
+					SourceInfo: &srcCopy,
 					Reason:     "directory only exists on source",
 				})
 			} else {
-				srcCopy := srcInfo
 				plan.Actions = append(plan.Actions, domain.SyncAction{
 					Type:       domain.ActionCopy,
 					Direction:  domain.DirSourceToTarget,
@@ -163,19 +272,20 @@ func (p *DefaultPlanner) PlanTwoWay(sourceMap, targetMap map[string]domain.FileI
 					Reason:     "file only exists on source",
 				})
 			}
+			plan.Baseline[path] = srcInfo
 
 		case !srcExists && tgtExists:
 			// Only on target -> copy to source (reverse direction)
+			tgtCopy := tgtInfo
 			if tgtInfo.IsDir() {
 				plan.Actions = append(plan.Actions, domain.SyncAction{
 					Type:       domain.ActionMkdir,
 					Direction:  domain.DirTargetToSource,
 					Path:       path,
-					TargetInfo: &tgtInfo,
+					TargetInfo: &tgtCopy,
 					Reason:     "directory only exists on target",
 				})
 			} else {
-				tgtCopy := tgtInfo
 				plan.Actions = append(plan.Actions, domain.SyncAction{
 					Type:       domain.ActionCopy,
 					Direction:  domain.DirTargetToSource,
@@ -184,6 +294,7 @@ func (p *DefaultPlanner) PlanTwoWay(sourceMap, targetMap map[string]domain.FileI
 					Reason:     "file only exists on target",
 				})
 			}
+			plan.Baseline[path] = tgtInfo
 
 		case srcExists && tgtExists && srcInfo.Type != tgtInfo.Type:
 			// Mixed type conflict in two-way sync
@@ -197,19 +308,118 @@ func (p *DefaultPlanner) PlanTwoWay(sourceMap, targetMap map[string]domain.FileI
 				TargetInfo: &tgtCopy,
 				Reason:     "type mismatch: file vs directory",
 			})
+			if baseExists {
+				plan.Baseline[path] = baseInfo
+			}
 
 		case srcExists && tgtExists && srcInfo.IsFile() && tgtInfo.IsFile():
 			// Both exist - check for conflict using differ
 			result := p.Differ.Compare(&srcInfo, &tgtInfo)
-			if result == diff.FileModified {
-				srcCopy := srcInfo
-				tgtCopy := tgtInfo
-				action := p.Resolver.Resolve(rule.ConflictStrategy, path, &srcCopy, &tgtCopy)
-				plan.Actions = append(plan.Actions, action)
+			if result != diff.FileModified {
+				plan.Baseline[path] = srcInfo
+				continue
 			}
+			srcCopy := srcInfo
+			tgtCopy := tgtInfo
+
+			// Only one side changed since the last sync -> copy it over the other
+			if baseExists && baseInfo.IsFile() {
+				srcChanged := p.changedSince(&srcInfo, &baseInfo)
+				tgtChanged := p.changedSince(&tgtInfo, &baseInfo)
+				if srcChanged && !tgtChanged {
+					plan.Actions = append(plan.Actions, domain.SyncAction{
+						Type:       domain.ActionCopy,
+						Direction:  domain.DirSourceToTarget,
+						Path:       path,
+						SourceInfo: &srcCopy,
+						TargetInfo: &tgtCopy,
+						Reason:     "file modified on source",
+					})
+					plan.Baseline[path] = srcInfo
+					continue
+				}
+				if tgtChanged && !srcChanged {
+					plan.Actions = append(plan.Actions, domain.SyncAction{
+						Type:       domain.ActionCopy,
+						Direction:  domain.DirTargetToSource,
+						Path:       path,
+						SourceInfo: &srcCopy,
+						TargetInfo: &tgtCopy,
+						Reason:     "file modified on target",
+					})
+					plan.Baseline[path] = tgtInfo
+					continue
+				}
+			}
+
+			// Both changed, or there is no baseline to tell -> a real conflict
+			action := p.Resolver.Resolve(rule.ConflictStrategy, path, &srcCopy, &tgtCopy)
+			plan.Actions = append(plan.Actions, action)
+			if action.Type == domain.ActionRename {
+				// The loser is out of the way; sync the winner over it
+				// The conflict copy stays out of the baseline so it is synced as a new file
+				plan.Actions = append(plan.Actions, domain.SyncAction{
+					Type:       domain.ActionCopy,
+					Direction:  action.Direction,
+					Path:       path,
+					SourceInfo: &srcCopy,
+					TargetInfo: &tgtCopy,
+					Reason:     "newer version of a kept conflict",
+				})
+			}
+			switch {
+			case (action.Type == domain.ActionCopy || action.Type == domain.ActionRename) && action.Direction == domain.DirSourceToTarget:
+				plan.Baseline[path] = srcInfo
+			case action.Type == domain.ActionCopy || action.Type == domain.ActionRename:
+				plan.Baseline[path] = tgtInfo
+			case action.Type == domain.ActionSkip:
+				plan.Baseline[path] = tgtInfo
+			case baseExists:
+				plan.Baseline[path] = baseInfo
+			}
+
+		case srcExists && tgtExists:
+			// Both sides have the directory
+			plan.Baseline[path] = srcInfo
 		}
 	}
 
+	// Deepest directories first so nested decisions are known to their parents
+	sort.Slice(dirDeletes, func(i, j int) bool {
+		return pathDepth(dirDeletes[i].Path) > pathDepth(dirDeletes[j].Path)
+	})
+	for _, action := range dirDeletes {
+		fromMap := sourceMap
+		if action.Direction == domain.DirSourceToTarget {
+			fromMap = targetMap
+		}
+		if !hasSurvivingChild(action.Path, fromMap, deleting[action.Direction], ignored) {
+			plan.Actions = append(plan.Actions, action)
+			continue
+		}
+
+		// Something beneath the directory is kept, so recreate it on the other side
+		delete(deleting[action.Direction], action.Path)
+		mkdir := domain.SyncAction{
+			Type:   domain.ActionMkdir,
+			Path:   action.Path,
+			Reason: "directory deleted but contains changes",
+		}
+		if action.Direction == domain.DirTargetToSource {
+			mkdir.Direction = domain.DirSourceToTarget
+			mkdir.SourceInfo = action.SourceInfo
+			plan.Baseline[action.Path] = *action.SourceInfo
+		} else {
+			mkdir.Direction = domain.DirTargetToSource
+			mkdir.TargetInfo = action.TargetInfo
+			plan.Baseline[action.Path] = *action.TargetInfo
+		}
+		plan.Actions = append(plan.Actions, mkdir)
+	}
+
+	// Renames on either side are replayed as moves on the other
+	plan.Actions = detectMoves(plan.Actions)
+
 	// Sort actions for two-way sync as well
 	sortActions(plan.Actions)
 
@@ -217,11 +427,89 @@ func (p *DefaultPlanner) PlanTwoWay(sourceMap, targetMap map[string]domain.FileI
 	return plan
 }
 
+// ApplyResolutions replaces conflict actions of a plan with the actions that
+// resolve them, keyed by path, and updates the baseline and stats to match
+func ApplyResolutions(plan *domain.SyncPlan, resolved map[string][]domain.SyncAction) {
+	actions := make([]domain.SyncAction, 0, len(plan.Actions))
+	for _, action := range plan.Actions {
+		replacement, ok := resolved[action.Path]
+		if action.Type != domain.ActionConflict || !ok {
+			actions = append(actions, action)
+			continue
+		}
+		actions = append(actions, replacement...)
+
+		if plan.Baseline == nil {
+			continue
+		}
+		for _, r := range replacement {
+			switch {
+			case r.Type == domain.ActionDelete:
+				delete(plan.Baseline, r.Path)
+			case r.Type == domain.ActionSkip:
+				// Ignored conflicts keep their previous baseline entry
+			case r.Direction == domain.DirSourceToTarget && r.SourceInfo != nil:
+				plan.Baseline[r.Path] = *r.SourceInfo
+			case r.Direction == domain.DirTargetToSource && r.TargetInfo != nil:
+				plan.Baseline[r.Path] = *r.TargetInfo
+			}
+		}
+	}
+
+	plan.Actions = actions
+	plan.Conflicts = nil
+	plan.Stats = domain.SyncPlanStats{TrackedPaths: plan.Stats.TrackedPaths, Filtered: plan.Stats.Filtered}
+	sortActions(plan.Actions)
+	calculateStats(plan)
+}
+
+// changedSince reports whether a file differs from its baseline entry
+func (p *DefaultPlanner) changedSince(current, base *domain.FileInfo) bool {
+	if current.Type != base.Type {
+		return true
+	}
+	if current.IsDir() {
+		return false
+	}
+	return p.Differ.Compare(current, base) == diff.FileModified
+}
+
+// endpointInfo maps the infos of the side a one-way action reads from and
+// the side it writes to onto the source and target endpoints
+func endpointInfo(direction domain.SyncDirection, from, to *domain.FileInfo) (source, target *domain.FileInfo) {
+	if direction == domain.DirTargetToSource {
+		return to, from
+	}
+	return from, to
+}
+
+// hasSurvivingChild reports whether any non-ignored entry beneath dir
+// is not scheduled for deletion
+func hasSurvivingChild(dir string, files map[string]domain.FileInfo, deleting map[string]bool, ignored *ignore.Matcher) bool {
+	prefix := dir + "/"
+	for path, info := range files {
+		if !strings.HasPrefix(path, prefix) || ignored.Ignored(path, info.IsDir()) {
+			continue
+		}
+		if !deleting[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// pathDepth returns the number of separators in a relative path
+func pathDepth(path string) int {
+	return strings.Count(path, "/") + strings.Count(path, "\\")
+}
+
 // sortActions sorts actions to ensure correct execution order
 // 1. Mkdir (create directories first, sorted by depth shallow->deep)
-// 2. Copy (copy files)
-// 3. Delete (delete last)
-// 4. Conflicts (flagged for manual resolution)
+// 2. Rename (move conflict losers aside before they are overwritten)
+// 3. Move (replay renames into the created directories)
+// 4. Copy (copy files)
+// 5. Delete (delete last)
+// 6. Conflicts (flagged for manual resolution)
 func sortActions(actions []domain.SyncAction) {
 	sort.Slice(actions, func(i, j int) bool {
 		typeOrderI := actionTypeOrder(actions[i].Type)
@@ -233,8 +521,8 @@ func sortActions(actions []domain.SyncAction) {
 		}
 
 		// Within same type, sort by path depth (shallow first)
-		depthI := strings.Count(actions[i].Path, "/") + strings.Count(actions[i].Path, "\\")
-		depthJ := strings.Count(actions[j].Path, "/") + strings.Count(actions[j].Path, "\\")
+		depthI := pathDepth(actions[i].Path)
+		depthJ := pathDepth(actions[j].Path)
 
 		if depthI != depthJ {
 			// For Delete, reverse order (deep first)
@@ -254,83 +542,27 @@ func actionTypeOrder(t domain.ActionType) int {
 	switch t {
 	case domain.ActionMkdir:
 		return 1
-	case domain.ActionCopy:
+	case domain.ActionRename:
 		return 2
-	case domain.ActionDelete:
+	case domain.ActionMove:
 		return 3
-	case domain.ActionConflict:
+	case domain.ActionCopy:
 		return 4
-	case domain.ActionSkip:
+	case domain.ActionDelete:
 		return 5
+	case domain.ActionConflict:
+		return 6
+	case domain.ActionSkip:
+		return 7
 	default:
 		return 99
 	}
 }
 
-// ShouldIgnore checks if a path matches any ignore pattern
-// Migrated from service/sync.go line 530-541
+// ShouldIgnore checks if a path matches any ignore pattern, with gitignore semantics
+// The path's type is unknown, so directory-only patterns ("build/") match it too
 func ShouldIgnore(path string, patterns []string) bool {
-	if len(patterns) == 0 {
-		return false
-	}
-
-	// Normalize path to use / for consistent matching
-	normalizedPath := filepath.ToSlash(path)
-	parts := strings.Split(normalizedPath, "/")
-
-	for _, pattern := range patterns {
-		normalizedPattern := filepath.ToSlash(pattern)
-		// Remove trailing slash for directory matching
-		trimmedPattern := strings.TrimSuffix(normalizedPattern, "/")
-
-		// Case 1: Match against the full path
-		matched, err := filepath.Match(normalizedPattern, normalizedPath)
-		if err == nil && matched {
-			return true
-		}
-
-		// Case 2: Match against the trimmed full path (handles directory patterns with trailing slash)
-		if normalizedPattern != trimmedPattern {
-			matched, err = filepath.Match(trimmedPattern, normalizedPath)
-			if err == nil && matched {
-				return true
-			}
-		}
-
-		// Case 3: Match against any component of the path (e.g., "node_modules", "*.tmp")
-		for _, part := range parts {
-			matched, err = filepath.Match(normalizedPattern, part)
-			if err == nil && matched {
-				return true
-			}
-			if normalizedPattern != trimmedPattern {
-				matched, err = filepath.Match(trimmedPattern, part)
-				if err == nil && matched {
-					return true
-				}
-			}
-		}
-
-		// Case 4: Match against any prefix of the path (e.g., if ".system" is ignored, ".system/config" is ignored)
-		currentPrefix := ""
-		for i, part := range parts {
-			if i > 0 {
-				currentPrefix += "/"
-			}
-			currentPrefix += part
-			matched, err = filepath.Match(normalizedPattern, currentPrefix)
-			if err == nil && matched {
-				return true
-			}
-			if normalizedPattern != trimmedPattern {
-				matched, err = filepath.Match(trimmedPattern, currentPrefix)
-				if err == nil && matched {
-					return true
-				}
-			}
-		}
-	}
-	return false
+	return ignore.New(patterns).Ignored(path, true)
 }
 
 // calculateStats computes summary statistics for a plan
@@ -341,13 +573,13 @@ func calculateStats(plan *domain.SyncPlan) {
 		switch action.Type {
 		case domain.ActionCopy:
 			plan.Stats.FilesToCopy++
-			if action.SourceInfo != nil {
-				plan.Stats.BytesToSync += action.SourceInfo.Size
-			} else if action.TargetInfo != nil {
-				plan.Stats.BytesToSync += action.TargetInfo.Size
+			if info := action.OriginInfo(); info != nil {
+				plan.Stats.BytesToSync += info.Size
 			}
 		case domain.ActionDelete:
 			plan.Stats.FilesToDelete++
+		case domain.ActionMove:
+			plan.Stats.FilesToMove++
 		case domain.ActionMkdir:
 			plan.Stats.DirsToCreate++
 		case domain.ActionConflict:
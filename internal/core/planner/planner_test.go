@@ -182,27 +182,168 @@ func TestPlanTwoWay_Conflict(t *testing.T) {
 	}
 }
 
+func TestPlanTwoWay_KeepBoth(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	sourceMap := map[string]domain.FileInfo{
+		"conflict.txt": {Path: "conflict.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now.Add(2 * time.Hour)},
+		"other.txt":    {Path: "other.txt", Type: domain.FileTypeRegular, Size: 10, ModTime: now},
+	}
+	targetMap := map[string]domain.FileInfo{
+		"conflict.txt": {Path: "conflict.txt", Type: domain.FileTypeRegular, Size: 200, ModTime: now.Add(1 * time.Hour)},
+	}
+
+	rule := &domain.SyncRule{
+		Name:             "test",
+		ConflictStrategy: domain.ConflictKeepBoth,
+	}
+
+	plan := planner.PlanTwoWay(sourceMap, targetMap, rule)
+
+	if len(plan.Actions) != 3 {
+		t.Fatalf("Expected rename, copy and copy, got %+v", plan.Actions)
+	}
+	rename := plan.Actions[0]
+	if rename.Type != domain.ActionRename || rename.Path != "conflict.txt" || rename.NewPath == "" {
+		t.Errorf("Expected the target version to be renamed first, got %+v", rename)
+	}
+	if rename.Direction != domain.DirSourceToTarget {
+		t.Errorf("Expected the rename on the target side")
+	}
+	for _, action := range plan.Actions[1:] {
+		if action.Type != domain.ActionCopy || action.Direction != domain.DirSourceToTarget {
+			t.Errorf("Expected source to target copies after the rename, got %+v", action)
+		}
+	}
+	if plan.Baseline["conflict.txt"].Size != 100 {
+		t.Errorf("Expected the winning version in the baseline")
+	}
+	if _, ok := plan.Baseline[rename.NewPath]; ok {
+		t.Errorf("Conflict copy must not be in the baseline, or it would be deleted next run")
+	}
+}
+
+func TestPlanTwoWayWithBaseline_KeepBothOneSidedEdit(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	base := domain.FileInfo{Path: "doc.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now}
+	sourceMap := map[string]domain.FileInfo{"doc.txt": {Path: "doc.txt", Type: domain.FileTypeRegular, Size: 150, ModTime: now.Add(time.Hour)}}
+	targetMap := map[string]domain.FileInfo{"doc.txt": base}
+	baseline := map[string]domain.FileInfo{"doc.txt": base}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepBoth}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	// An ordinary edit is copied; no conflict copy is made
+	if len(plan.Actions) != 1 {
+		t.Fatalf("Expected a single copy, got %+v", plan.Actions)
+	}
+	if action := plan.Actions[0]; action.Type != domain.ActionCopy || action.Direction != domain.DirSourceToTarget {
+		t.Errorf("Expected a source to target copy, got %+v", action)
+	}
+}
+
+func TestApplyResolutions(t *testing.T) {
+	now := time.Now()
+	src := domain.FileInfo{Path: "a.txt", Size: 1, ModTime: now}
+	tgt := domain.FileInfo{Path: "a.txt", Size: 2, ModTime: now}
+	base := domain.FileInfo{Path: "a.txt", Size: 3, ModTime: now.Add(-time.Hour)}
+	gone := domain.FileInfo{Path: "b.txt", Size: 3}.Tombstone()
+
+	plan := &domain.SyncPlan{
+		Actions: []domain.SyncAction{
+			{Type: domain.ActionConflict, Path: "a.txt", SourceInfo: &src, TargetInfo: &tgt},
+			{Type: domain.ActionConflict, Path: "b.txt", SourceInfo: &gone, TargetInfo: &tgt},
+			{Type: domain.ActionConflict, Path: "c.txt", SourceInfo: &src, TargetInfo: &tgt},
+		},
+		Baseline: map[string]domain.FileInfo{"a.txt": base, "b.txt": base},
+	}
+	calculateStats(plan)
+
+	ApplyResolutions(plan, map[string][]domain.SyncAction{
+		"a.txt": {{Type: domain.ActionCopy, Direction: domain.DirTargetToSource, Path: "a.txt", SourceInfo: &src, TargetInfo: &tgt}},
+		"b.txt": {{Type: domain.ActionDelete, Direction: domain.DirSourceToTarget, Path: "b.txt", SourceInfo: &gone, TargetInfo: &tgt}},
+	})
+
+	if len(plan.Actions) != 3 || plan.Actions[0].Type != domain.ActionCopy || plan.Actions[1].Type != domain.ActionDelete {
+		t.Fatalf("Expected copy, delete, then the unresolved conflict, got %+v", plan.Actions)
+	}
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0].Path != "c.txt" {
+		t.Errorf("Expected only c.txt to remain a conflict, got %+v", plan.Conflicts)
+	}
+	if plan.Stats.Conflicts != 1 || plan.Stats.FilesToCopy != 1 || plan.Stats.FilesToDelete != 1 {
+		t.Errorf("Stats not recalculated: %+v", plan.Stats)
+	}
+	if plan.Baseline["a.txt"].Size != 2 {
+		t.Errorf("Expected the taken target version in the baseline")
+	}
+	if _, ok := plan.Baseline["b.txt"]; ok {
+		t.Errorf("Expected the deleted path to leave the baseline")
+	}
+}
+
+func TestApplyFilters_TwoWay(t *testing.T) {
+	now := time.Now()
+	dir := domain.FileInfo{Path: "old", Type: domain.FileTypeDirectory, ModTime: now}
+	notes := domain.FileInfo{Path: "old/notes.txt", Type: domain.FileTypeRegular, Size: 5, ModTime: now}
+	large := domain.FileInfo{Path: "old/large.iso", Type: domain.FileTypeRegular, Size: 5000, ModTime: now}
+	baseline := map[string]domain.FileInfo{"old": dir, "old/notes.txt": notes, "old/large.iso": large}
+
+	// old was deleted on the source; large.iso is filtered and left out of the listings
+	plan := NewDefaultPlanner().PlanTwoWayWithBaseline(
+		map[string]domain.FileInfo{},
+		map[string]domain.FileInfo{"old": dir, "old/notes.txt": notes},
+		baseline,
+		&domain.SyncRule{Name: "two-way", Mode: domain.SyncModeTwoWay},
+	)
+	ApplyFilters(plan, []domain.FilteredPath{{Path: "old/large.iso", Reason: "size 5000 bytes is above max_size 1KB"}}, baseline)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != domain.ActionDelete || plan.Actions[0].Path != "old/notes.txt" {
+		t.Fatalf("Expected only notes.txt to be deleted, got %+v", plan.Actions)
+	}
+	if len(plan.Filtered) != 2 || plan.Filtered[0].Path != "old" || plan.Stats.Filtered != 2 || plan.Stats.FilesToDelete != 1 {
+		t.Errorf("Expected old and large.iso to be filtered, got %+v (stats %+v)", plan.Filtered, plan.Stats)
+	}
+	if _, ok := plan.Baseline["old/large.iso"]; !ok {
+		t.Errorf("Expected the filtered file to keep its baseline entry")
+	}
+	if _, ok := plan.Baseline["old"]; !ok {
+		t.Errorf("Expected the kept directory to keep its baseline entry")
+	}
+	if _, ok := plan.Baseline["old/notes.txt"]; ok {
+		t.Errorf("Expected the deleted file to leave the baseline")
+	}
+}
+
 func TestCalculateStats(t *testing.T) {
 	plan := &domain.SyncPlan{
 		Actions: []domain.SyncAction{
 			{Type: domain.ActionCopy, SourceInfo: &domain.FileInfo{Size: 100}},
 			{Type: domain.ActionCopy, SourceInfo: &domain.FileInfo{Size: 200}},
+			{Type: domain.ActionCopy, Direction: domain.DirTargetToSource, SourceInfo: &domain.FileInfo{Size: 1000}, TargetInfo: &domain.FileInfo{Size: 50}},
 			{Type: domain.ActionDelete},
 			{Type: domain.ActionMkdir},
+			{Type: domain.ActionMove, SourceInfo: &domain.FileInfo{Size: 400}},
 			{Type: domain.ActionConflict},
 		},
 	}
 
 	calculateStats(plan)
 
-	if plan.Stats.TotalFiles != 5 {
-		t.Errorf("Expected TotalFiles=5, got %d", plan.Stats.TotalFiles)
+	if plan.Stats.TotalFiles != 7 {
+		t.Errorf("Expected TotalFiles=7, got %d", plan.Stats.TotalFiles)
 	}
-	if plan.Stats.FilesToCopy != 2 {
-		t.Errorf("Expected FilesToCopy=2, got %d", plan.Stats.FilesToCopy)
+	if plan.Stats.FilesToCopy != 3 {
+		t.Errorf("Expected FilesToCopy=3, got %d", plan.Stats.FilesToCopy)
 	}
-	if plan.Stats.BytesToSync != 300 {
-		t.Errorf("Expected BytesToSync=300, got %d", plan.Stats.BytesToSync)
+	if plan.Stats.BytesToSync != 350 {
+		t.Errorf("Expected BytesToSync=350, got %d", plan.Stats.BytesToSync)
+	}
+	if plan.Stats.FilesToMove != 1 {
+		t.Errorf("Expected FilesToMove=1, got %d", plan.Stats.FilesToMove)
 	}
 	if plan.Stats.FilesToDelete != 1 {
 		t.Errorf("Expected FilesToDelete=1, got %d", plan.Stats.FilesToDelete)
@@ -526,3 +667,375 @@ func TestShouldIgnore_DirectoryRecursion(t *testing.T) {
 		}
 	}
 }
+
+func TestPlanTwoWayWithBaseline_DeletedOnTarget(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	file := domain.FileInfo{Path: "gone.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now}
+	sourceMap := map[string]domain.FileInfo{"gone.txt": file}
+	targetMap := map[string]domain.FileInfo{}
+	baseline := map[string]domain.FileInfo{"gone.txt": file}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 1 {
+		t.Fatalf("Expected 1 action, got %d", len(plan.Actions))
+	}
+	action := plan.Actions[0]
+	if action.Type != domain.ActionDelete {
+		t.Fatalf("Expected ActionDelete, got %v", action.Type)
+	}
+	if action.Direction != domain.DirTargetToSource {
+		t.Errorf("Expected deletion to propagate to source")
+	}
+	if action.TargetInfo == nil || !action.TargetInfo.IsDeleted {
+		t.Errorf("Expected target tombstone")
+	}
+	if _, ok := plan.Baseline["gone.txt"]; ok {
+		t.Errorf("Deleted file should not remain in baseline")
+	}
+}
+
+func TestPlanTwoWayWithBaseline_DeletedOnSource(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	file := domain.FileInfo{Path: "gone.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now}
+	sourceMap := map[string]domain.FileInfo{}
+	targetMap := map[string]domain.FileInfo{"gone.txt": file}
+	baseline := map[string]domain.FileInfo{"gone.txt": file}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 1 {
+		t.Fatalf("Expected 1 action, got %d", len(plan.Actions))
+	}
+	if plan.Actions[0].Type != domain.ActionDelete {
+		t.Fatalf("Expected ActionDelete, got %v", plan.Actions[0].Type)
+	}
+	if plan.Actions[0].Direction != domain.DirSourceToTarget {
+		t.Errorf("Expected deletion to propagate to target")
+	}
+	if plan.Stats.FilesToDelete != 1 {
+		t.Errorf("Expected FilesToDelete=1, got %d", plan.Stats.FilesToDelete)
+	}
+}
+
+func TestPlanTwoWayWithBaseline_DeleteModifyConflict(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	base := domain.FileInfo{Path: "doc.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now}
+	modified := domain.FileInfo{Path: "doc.txt", Type: domain.FileTypeRegular, Size: 150, ModTime: now.Add(time.Hour)}
+	sourceMap := map[string]domain.FileInfo{"doc.txt": modified}
+	targetMap := map[string]domain.FileInfo{}
+	baseline := map[string]domain.FileInfo{"doc.txt": base}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 1 {
+		t.Fatalf("Expected 1 action, got %d", len(plan.Actions))
+	}
+	if plan.Actions[0].Type != domain.ActionConflict {
+		t.Fatalf("Expected ActionConflict, got %v", plan.Actions[0].Type)
+	}
+	if plan.Stats.Conflicts != 1 {
+		t.Errorf("Expected Conflicts=1, got %d", plan.Stats.Conflicts)
+	}
+	// Baseline keeps the old entry so the conflict is reported again
+	if got, ok := plan.Baseline["doc.txt"]; !ok || got.Size != 100 {
+		t.Errorf("Expected previous baseline entry to be kept, got %+v", got)
+	}
+}
+
+func TestPlanTwoWayWithBaseline_ModifiedOnOneSide(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	base := domain.FileInfo{Path: "doc.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now}
+	edited := domain.FileInfo{Path: "doc.txt", Type: domain.FileTypeRegular, Size: 150, ModTime: now.Add(time.Hour)}
+	baseline := map[string]domain.FileInfo{"doc.txt": base}
+
+	tests := []struct {
+		name      string
+		source    domain.FileInfo
+		target    domain.FileInfo
+		direction domain.SyncDirection
+	}{
+		{"source edited", edited, base, domain.DirSourceToTarget},
+		{"target edited", base, edited, domain.DirTargetToSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceMap := map[string]domain.FileInfo{"doc.txt": tt.source}
+			targetMap := map[string]domain.FileInfo{"doc.txt": tt.target}
+			rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictManual}
+
+			plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+			if len(plan.Actions) != 1 {
+				t.Fatalf("Expected 1 action, got %+v", plan.Actions)
+			}
+			action := plan.Actions[0]
+			if action.Type != domain.ActionCopy || action.Direction != tt.direction {
+				t.Errorf("Expected a %v copy, got %v %v", tt.direction, action.Type, action.Direction)
+			}
+			if plan.Stats.Conflicts != 0 {
+				t.Errorf("Expected no conflicts, got %d", plan.Stats.Conflicts)
+			}
+			if got := plan.Baseline["doc.txt"]; got.Size != 150 {
+				t.Errorf("Expected the edited version in the baseline, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestPlanTwoWayWithBaseline_ModifiedOnBothSides(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	base := domain.FileInfo{Path: "doc.txt", Type: domain.FileTypeRegular, Size: 100, ModTime: now}
+	sourceMap := map[string]domain.FileInfo{"doc.txt": {Path: "doc.txt", Type: domain.FileTypeRegular, Size: 150, ModTime: now.Add(time.Hour)}}
+	targetMap := map[string]domain.FileInfo{"doc.txt": {Path: "doc.txt", Type: domain.FileTypeRegular, Size: 200, ModTime: now.Add(2 * time.Hour)}}
+	baseline := map[string]domain.FileInfo{"doc.txt": base}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictManual}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != domain.ActionConflict {
+		t.Fatalf("Expected a single conflict, got %+v", plan.Actions)
+	}
+}
+
+func TestPlanTwoWayWithBaseline_CreatedOnSource(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	sourceMap := map[string]domain.FileInfo{
+		"new.txt": {Path: "new.txt", Type: domain.FileTypeRegular, Size: 10, ModTime: now},
+	}
+	targetMap := map[string]domain.FileInfo{}
+	baseline := map[string]domain.FileInfo{}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != domain.ActionCopy {
+		t.Fatalf("Expected a single copy action, got %+v", plan.Actions)
+	}
+	if _, ok := plan.Baseline["new.txt"]; !ok {
+		t.Errorf("Copied file should be recorded in baseline")
+	}
+}
+
+func TestPlanTwoWayWithBaseline_DeletedDirectory(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	dir := domain.FileInfo{Path: "dir", Type: domain.FileTypeDirectory, ModTime: now}
+	file := domain.FileInfo{Path: "dir/a.txt", Type: domain.FileTypeRegular, Size: 10, ModTime: now}
+	sourceMap := map[string]domain.FileInfo{"dir": dir, "dir/a.txt": file}
+	targetMap := map[string]domain.FileInfo{}
+	baseline := map[string]domain.FileInfo{"dir": dir, "dir/a.txt": file}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(plan.Actions))
+	}
+	// Deletes run deepest first
+	if plan.Actions[0].Path != "dir/a.txt" || plan.Actions[1].Path != "dir" {
+		t.Errorf("Unexpected delete order: %s, %s", plan.Actions[0].Path, plan.Actions[1].Path)
+	}
+	for _, action := range plan.Actions {
+		if action.Type != domain.ActionDelete || action.Direction != domain.DirTargetToSource {
+			t.Errorf("Expected delete on source for %s, got %v", action.Path, action.Type)
+		}
+	}
+}
+
+func TestPlanTwoWayWithBaseline_DeletedDirectoryWithNewFile(t *testing.T) {
+	planner := NewDefaultPlanner()
+	now := time.Now()
+
+	dir := domain.FileInfo{Path: "dir", Type: domain.FileTypeDirectory, ModTime: now}
+	sourceMap := map[string]domain.FileInfo{
+		"dir":         dir,
+		"dir/new.txt": {Path: "dir/new.txt", Type: domain.FileTypeRegular, Size: 10, ModTime: now},
+	}
+	targetMap := map[string]domain.FileInfo{}
+	baseline := map[string]domain.FileInfo{"dir": dir}
+
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(plan.Actions))
+	}
+	if plan.Actions[0].Type != domain.ActionMkdir || plan.Actions[0].Direction != domain.DirSourceToTarget {
+		t.Errorf("Expected directory to be recreated on target, got %v", plan.Actions[0].Type)
+	}
+	if plan.Actions[1].Type != domain.ActionCopy {
+		t.Errorf("Expected new file to be copied, got %v", plan.Actions[1].Type)
+	}
+}
+
+// hashed returns a file with a checksum, as listed by most adapters
+func hashed(path string, size int64, sum string) domain.FileInfo {
+	return domain.FileInfo{
+		Path:              path,
+		Type:              domain.FileTypeRegular,
+		Size:              size,
+		ModTime:           time.Now(),
+		Checksum:          sum,
+		ChecksumAlgorithm: "sha256",
+	}
+}
+
+func TestPlanOneWay_MovedDirectory(t *testing.T) {
+	planner := NewDefaultPlanner()
+	rule := &domain.SyncRule{Name: "test"}
+
+	fromMap := map[string]domain.FileInfo{
+		"new":       {Path: "new", Type: domain.FileTypeDirectory},
+		"new/a.bin": hashed("new/a.bin", 100, "aaa"),
+		"new/b.bin": hashed("new/b.bin", 200, "bbb"),
+	}
+	toMap := map[string]domain.FileInfo{
+		"old":       {Path: "old", Type: domain.FileTypeDirectory},
+		"old/a.bin": hashed("old/a.bin", 100, "aaa"),
+		"old/b.bin": hashed("old/b.bin", 200, "bbb"),
+	}
+
+	plan := planner.PlanOneWay(fromMap, toMap, rule, domain.DirSourceToTarget)
+
+	want := []struct {
+		actionType domain.ActionType
+		path       string
+		newPath    string
+	}{
+		{domain.ActionMkdir, "new", ""},
+		{domain.ActionMove, "old/a.bin", "new/a.bin"},
+		{domain.ActionMove, "old/b.bin", "new/b.bin"},
+		{domain.ActionDelete, "old", ""},
+	}
+	if len(plan.Actions) != len(want) {
+		t.Fatalf("Expected %d actions, got %+v", len(want), plan.Actions)
+	}
+	for i, w := range want {
+		got := plan.Actions[i]
+		if got.Type != w.actionType || got.Path != w.path || got.NewPath != w.newPath {
+			t.Errorf("Action %d = %s %s -> %q, want %s %s -> %q", i, got.Type, got.Path, got.NewPath, w.actionType, w.path, w.newPath)
+		}
+	}
+
+	move := plan.Actions[1]
+	if move.SourceInfo == nil || move.SourceInfo.Path != "new/a.bin" || move.TargetInfo == nil || move.TargetInfo.Path != "old/a.bin" {
+		t.Errorf("Move infos = %+v, %+v", move.SourceInfo, move.TargetInfo)
+	}
+	if plan.Stats.FilesToMove != 2 || plan.Stats.FilesToCopy != 0 || plan.Stats.BytesToSync != 0 || plan.Stats.TrackedPaths != 6 {
+		t.Errorf("Stats = %+v", plan.Stats)
+	}
+}
+
+func TestPlanOneWay_AmbiguousMove(t *testing.T) {
+	planner := NewDefaultPlanner()
+	rule := &domain.SyncRule{Name: "test"}
+
+	fromMap := map[string]domain.FileInfo{
+		"copy1.bin": hashed("copy1.bin", 100, "aaa"),
+		"copy2.bin": hashed("copy2.bin", 100, "aaa"),
+		"empty.txt": hashed("empty.txt", 0, "e3b0"),
+		"plain.bin": {Path: "plain.bin", Type: domain.FileTypeRegular, Size: 300},
+	}
+	toMap := map[string]domain.FileInfo{
+		"orig.bin":  hashed("orig.bin", 100, "aaa"),
+		"gone.txt":  hashed("gone.txt", 0, "e3b0"),
+		"other.bin": {Path: "other.bin", Type: domain.FileTypeRegular, Size: 300},
+	}
+
+	plan := planner.PlanOneWay(fromMap, toMap, rule, domain.DirSourceToTarget)
+
+	if plan.Stats.FilesToMove != 0 {
+		t.Errorf("Expected no moves, got %+v", plan.Actions)
+	}
+	if plan.Stats.FilesToCopy != 4 || plan.Stats.FilesToDelete != 3 {
+		t.Errorf("Stats = %+v", plan.Stats)
+	}
+}
+
+func TestPlanOneWay_PullInfosByEndpoint(t *testing.T) {
+	planner := NewDefaultPlanner()
+	rule := &domain.SyncRule{Name: "test"}
+
+	// Pull plans read from the target endpoint
+	fromMap := map[string]domain.FileInfo{
+		"new.bin":   hashed("new.bin", 10, "nnn"),
+		"moved.bin": hashed("moved.bin", 20, "mmm"),
+	}
+	toMap := map[string]domain.FileInfo{
+		"renamed.bin": hashed("renamed.bin", 20, "mmm"),
+	}
+
+	plan := planner.PlanOneWay(fromMap, toMap, rule, domain.DirTargetToSource)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %+v", plan.Actions)
+	}
+	move, cp := plan.Actions[0], plan.Actions[1]
+	if move.Type != domain.ActionMove || move.Path != "renamed.bin" || move.NewPath != "moved.bin" {
+		t.Errorf("Expected move of renamed.bin to moved.bin, got %+v", move)
+	}
+	if move.TargetInfo == nil || move.TargetInfo.Path != "moved.bin" || move.SourceInfo == nil || move.SourceInfo.Path != "renamed.bin" {
+		t.Errorf("Move infos = %+v, %+v", move.SourceInfo, move.TargetInfo)
+	}
+	if cp.Type != domain.ActionCopy || cp.SourceInfo != nil || cp.TargetInfo == nil || cp.OriginInfo().Path != "new.bin" {
+		t.Errorf("Expected copy with the target endpoint's info, got %+v", cp)
+	}
+}
+
+func TestPlanTwoWayWithBaseline_MovedOnTarget(t *testing.T) {
+	planner := NewDefaultPlanner()
+	rule := &domain.SyncRule{Name: "test", ConflictStrategy: domain.ConflictKeepNewest}
+
+	baseline := map[string]domain.FileInfo{
+		"report.pdf": hashed("report.pdf", 500, "rrr"),
+	}
+	sourceMap := map[string]domain.FileInfo{
+		"report.pdf": baseline["report.pdf"],
+	}
+	targetMap := map[string]domain.FileInfo{
+		"docs":            {Path: "docs", Type: domain.FileTypeDirectory},
+		"docs/report.pdf": hashed("docs/report.pdf", 500, "rrr"),
+	}
+
+	plan := planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("Expected mkdir and move, got %+v", plan.Actions)
+	}
+	move := plan.Actions[1]
+	if move.Type != domain.ActionMove || move.Direction != domain.DirTargetToSource ||
+		move.Path != "report.pdf" || move.NewPath != "docs/report.pdf" {
+		t.Errorf("Expected move of report.pdf to docs/report.pdf on source, got %+v", move)
+	}
+	if _, ok := plan.Baseline["report.pdf"]; ok {
+		t.Error("Old path should leave the baseline")
+	}
+	if _, ok := plan.Baseline["docs/report.pdf"]; !ok {
+		t.Error("New path should enter the baseline")
+	}
+}
@@ -3,8 +3,11 @@ package rule
 import (
 	"context"
 	"fmt"
+	"path"
+	"time"
 
 	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/core/ignore"
 	"github.com/Ning0612/Syncrules/internal/core/planner"
 	"github.com/Ning0612/Syncrules/internal/domain"
 )
@@ -12,6 +15,7 @@ import (
 // Executor orchestrates the sync planning process
 type Executor interface {
 	Plan(ctx context.Context, rule *domain.SyncRule, sourceAdapter, targetAdapter adapter.Adapter) (*domain.SyncPlan, error)
+	PlanWithBaseline(ctx context.Context, rule *domain.SyncRule, sourceAdapter, targetAdapter adapter.Adapter, baseline map[string]domain.FileInfo) (*domain.SyncPlan, error)
 }
 
 // DefaultExecutor implements the planning orchestration
@@ -30,18 +34,31 @@ func NewDefaultExecutor() *DefaultExecutor {
 // Plan creates a sync plan for a rule
 // This is the core orchestration logic migrated from service.PlanSync
 func (e *DefaultExecutor) Plan(ctx context.Context, rule *domain.SyncRule, sourceAdapter, targetAdapter adapter.Adapter) (*domain.SyncPlan, error) {
-	// List source files
-	sourceFiles, err := listAllFiles(ctx, sourceAdapter, "", rule.IgnorePatterns)
+	return e.PlanWithBaseline(ctx, rule, sourceAdapter, targetAdapter, nil)
+}
+
+// PlanWithBaseline creates a sync plan for a rule using the tree recorded
+// after its previous successful sync (only used by two-way mode)
+func (e *DefaultExecutor) PlanWithBaseline(ctx context.Context, rule *domain.SyncRule, sourceAdapter, targetAdapter adapter.Adapter, baseline map[string]domain.FileInfo) (*domain.SyncPlan, error) {
+	// List source files, collecting the .syncignore files of the source tree
+	ignored := ignore.New(rule.IgnorePatterns)
+	sourceFiles, err := listAllFiles(ctx, sourceAdapter, "", ignored, true)
 	if err != nil {
 		return nil, fmt.Errorf("listing source files: %w", err)
 	}
 
-	// List target files
-	targetFiles, err := listAllFiles(ctx, targetAdapter, "", rule.IgnorePatterns)
+	// List target files with the same rules, so ignored paths on the target are left alone
+	targetFiles, err := listAllFiles(ctx, targetAdapter, "", ignored, false)
 	if err != nil {
 		return nil, fmt.Errorf("listing target files: %w", err)
 	}
 
+	// The listings are already filtered; filtering again by the rule's patterns
+	// alone would drop paths a .syncignore re-includes with "!"
+	listed := *rule
+	listed.IgnorePatterns = nil
+	rule = &listed
+
 	// Convert to maps for efficient lookup
 	sourceMap := make(map[string]domain.FileInfo)
 	for _, f := range sourceFiles {
@@ -53,6 +70,17 @@ func (e *DefaultExecutor) Plan(ctx context.Context, rule *domain.SyncRule, sourc
 		targetMap[f.Path] = f
 	}
 
+	// Leave out the files the rule's filters exclude before fillChecksums
+	// hashes any; listings carry only the checksums that cost no reads
+	filtered, err := filterPaths(rule, time.Now(), sourceMap, targetMap, baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fillChecksums(ctx, sourceMap, targetMap, baseline, sourceAdapter, targetAdapter); err != nil {
+		return nil, err
+	}
+
 	// Generate plan based on sync mode
 	var plan *domain.SyncPlan
 	switch rule.Mode {
@@ -64,24 +92,178 @@ func (e *DefaultExecutor) Plan(ctx context.Context, rule *domain.SyncRule, sourc
 		plan = e.Planner.PlanOneWay(targetMap, sourceMap, rule, domain.DirTargetToSource)
 	case domain.SyncModeTwoWay:
 		// Bidirectional sync
-		plan = e.Planner.PlanTwoWay(sourceMap, targetMap, rule)
+		plan = e.Planner.PlanTwoWayWithBaseline(sourceMap, targetMap, baseline, rule)
 	default:
 		return nil, fmt.Errorf("unsupported sync mode: %s", rule.Mode)
 	}
 
+	planner.ApplyFilters(plan, filtered, baseline)
 	return plan, nil
 }
 
+// fillChecksums computes missing checksums on demand where they decide a
+// comparison: files of equal size but different mtime on both sides, or
+// against their baseline entry, without comparable checksums. Adapters that
+// read file contents to hash them are listed without checksums, so files are
+// only hashed where the other side can compare the result
+// Only adapters implementing adapter.ChecksumStater are asked; files that
+// cannot be hashed keep their listed info
+func fillChecksums(ctx context.Context, sourceMap, targetMap, baseline map[string]domain.FileInfo, sourceAdapter, targetAdapter adapter.Adapter) error {
+	// Hashing a file on both sides only pays off if both produce the same
+	// algorithm; stop trying after the first pair that doesn't. The side with
+	// server checksums is asked first, so files it has none for are not read
+	_, srcStater := sourceAdapter.(adapter.ChecksumStater)
+	_, tgtStater := targetAdapter.(adapter.ChecksumStater)
+	bothSides := srcStater && tgtStater
+	targetFirst := adapter.CapabilitiesOf(targetAdapter).ServerChecksums &&
+		!adapter.CapabilitiesOf(sourceAdapter).ServerChecksums
+
+	for path, src := range sourceMap {
+		tgt, ok := targetMap[path]
+		if !ok || !needsChecksum(src, tgt) {
+			continue
+		}
+
+		var err error
+		switch {
+		case src.Checksum == "" && tgt.Checksum != "":
+			sourceMap[path], err = statChecksum(ctx, sourceAdapter, src, tgt.ChecksumAlgorithm)
+		case tgt.Checksum == "" && src.Checksum != "":
+			targetMap[path], err = statChecksum(ctx, targetAdapter, tgt, src.ChecksumAlgorithm)
+		case src.Checksum == "" && tgt.Checksum == "" && bothSides:
+			if targetFirst {
+				tgt, src, bothSides, err = statPair(ctx, targetAdapter, sourceAdapter, tgt, src)
+			} else {
+				src, tgt, bothSides, err = statPair(ctx, sourceAdapter, targetAdapter, src, tgt)
+			}
+			sourceMap[path], targetMap[path] = src, tgt
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := fillFromBaseline(ctx, sourceMap, baseline, sourceAdapter); err != nil {
+		return err
+	}
+	if err := fillFromBaseline(ctx, targetMap, baseline, targetAdapter); err != nil {
+		return err
+	}
+
+	// A file only on one side and one of equal size only on the other may be
+	// the two ends of a rename; the planner pairs them by checksum
+	if err := fillUnpaired(ctx, sourceMap, targetMap, sourceAdapter); err != nil {
+		return err
+	}
+	return fillUnpaired(ctx, targetMap, sourceMap, targetAdapter)
+}
+
+// statPair computes checksums of two versions of a file, asking adapter a
+// first and b for the same algorithm. Both are returned unchanged unless both
+// get one, since a checksum on one side alone would count as a modification
+// ok is false if b cannot produce the checksum a did
+func statPair(ctx context.Context, a, b adapter.Adapter, aInfo, bInfo domain.FileInfo) (domain.FileInfo, domain.FileInfo, bool, error) {
+	aSum, err := statChecksum(ctx, a, aInfo, "")
+	if err != nil || aSum.Checksum == "" {
+		return aInfo, bInfo, true, err
+	}
+	bSum, err := statChecksum(ctx, b, bInfo, aSum.ChecksumAlgorithm)
+	if err != nil || bSum.Checksum == "" {
+		return aInfo, bInfo, false, err
+	}
+	return aSum, bSum, true, nil
+}
+
+// needsChecksum reports whether two versions of a file can only be told
+// apart by their contents
+func needsChecksum(a, b domain.FileInfo) bool {
+	return a.IsFile() && b.IsFile() && a.Size == b.Size &&
+		!a.ModTime.Equal(b.ModTime) && !a.ChecksumComparable(b)
+}
+
+// fillFromBaseline computes missing checksums of files whose baseline entry
+// has one, so a file touched since the last sync is not taken for an edit
+func fillFromBaseline(ctx context.Context, files, baseline map[string]domain.FileInfo, adp adapter.Adapter) error {
+	for path, info := range files {
+		base, ok := baseline[path]
+		if !ok || info.Checksum != "" || base.Checksum == "" || !needsChecksum(info, base) {
+			continue
+		}
+		filled, err := statChecksum(ctx, adp, info, base.ChecksumAlgorithm)
+		if err != nil {
+			return err
+		}
+		files[path] = filled
+	}
+	return nil
+}
+
+// fillUnpaired computes missing checksums of files in files but not in other
+// whose size matches a file only in other
+func fillUnpaired(ctx context.Context, files, other map[string]domain.FileInfo, adp adapter.Adapter) error {
+	sizes := make(map[int64]bool)
+	for path, info := range other {
+		if _, ok := files[path]; !ok && info.IsFile() && info.Size > 0 {
+			sizes[info.Size] = true
+		}
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	for path, info := range files {
+		if _, ok := other[path]; ok || !info.IsFile() || info.Checksum != "" || !sizes[info.Size] {
+			continue
+		}
+		filled, err := statChecksum(ctx, adp, info, "")
+		if err != nil {
+			return err
+		}
+		files[path] = filled
+	}
+	return nil
+}
+
+// statChecksum returns info with a checksum of the given algorithm (any, if
+// empty) if the adapter can compute one, or info unchanged
+// Only context errors are returned
+func statChecksum(ctx context.Context, adp adapter.Adapter, info domain.FileInfo, algorithm string) (domain.FileInfo, error) {
+	stater, ok := adp.(adapter.ChecksumStater)
+	if !ok {
+		return info, nil
+	}
+
+	withSum, err := stater.StatWithChecksum(ctx, info.Path)
+	if err != nil {
+		return info, ctx.Err()
+	}
+	if withSum.Checksum == "" || (algorithm != "" && withSum.ChecksumAlgorithm != algorithm) {
+		return info, nil
+	}
+	info.Checksum = withSum.Checksum
+	info.ChecksumAlgorithm = withSum.ChecksumAlgorithm
+	return info, nil
+}
+
 // listAllFiles recursively lists all files from an adapter
+// Ignored directories are skipped without being listed. With readIgnoreFiles,
+// the .syncignore file of each listed directory is added to ignored before
+// its entries are filtered
 // Migrated from service/sync.go line 499-527
-func listAllFiles(ctx context.Context, adp adapter.Adapter, prefix string, ignorePatterns []string) ([]domain.FileInfo, error) {
+func listAllFiles(ctx context.Context, adp adapter.Adapter, prefix string, ignored *ignore.Matcher, readIgnoreFiles bool) ([]domain.FileInfo, error) {
 	var allFiles []domain.FileInfo
 
-	items, err := adp.List(ctx, prefix)
+	items, err := listDir(ctx, adp, prefix)
 	if err != nil {
 		return nil, err
 	}
 
+	if readIgnoreFiles {
+		if err := readIgnoreFile(ctx, adp, prefix, items, ignored); err != nil {
+			return nil, err
+		}
+	}
+
 	for _, item := range items {
 		// Check context cancellation
 		select {
@@ -90,14 +272,14 @@ func listAllFiles(ctx context.Context, adp adapter.Adapter, prefix string, ignor
 		default:
 		}
 
-		// Skip ignored files and directories
-		if planner.ShouldIgnore(item.Path, ignorePatterns) {
+		// Skip ignored files and directories, and the endpoint's trash
+		if item.Path == domain.TrashDir || ignored.Ignored(item.Path, item.IsDir()) {
 			continue
 		}
 
 		if item.IsDir() {
 			allFiles = append(allFiles, item)
-			subFiles, err := listAllFiles(ctx, adp, item.Path, ignorePatterns)
+			subFiles, err := listAllFiles(ctx, adp, item.Path, ignored, readIgnoreFiles)
 			if err != nil {
 				return nil, err
 			}
@@ -109,3 +291,29 @@ func listAllFiles(ctx context.Context, adp adapter.Adapter, prefix string, ignor
 
 	return allFiles, nil
 }
+
+// listDir lists a directory without the checksums an adapter would read file
+// contents for; fillChecksums computes the ones a comparison needs
+func listDir(ctx context.Context, adp adapter.Adapter, dir string) ([]domain.FileInfo, error) {
+	if lazy, ok := adp.(adapter.LazyChecksumLister); ok {
+		return lazy.ListWithoutChecksums(ctx, dir)
+	}
+	return adp.List(ctx, dir)
+}
+
+// readIgnoreFile adds the .syncignore file among a directory's entries, if any
+func readIgnoreFile(ctx context.Context, adp adapter.Adapter, dir string, items []domain.FileInfo, ignored *ignore.Matcher) error {
+	name := path.Join(dir, ignore.FileName)
+	for _, item := range items {
+		if item.Path != name || item.IsDir() {
+			continue
+		}
+		r, err := adp.Read(ctx, item.Path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", item.Path, err)
+		}
+		defer r.Close()
+		return ignored.AddFile(dir, r)
+	}
+	return nil
+}
@@ -5,10 +5,15 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Ning0612/Syncrules/internal/adapter/local"
+	"github.com/Ning0612/Syncrules/internal/adapter/memory"
+	"github.com/Ning0612/Syncrules/internal/core/ignore"
 	"github.com/Ning0612/Syncrules/internal/domain"
 )
 
@@ -399,10 +404,359 @@ func TestListAllFiles_ContextCancellation(t *testing.T) {
 
 	// listAllFiles should respect context cancellation
 	// Note: with small dataset, may complete before cancellation is checked
-	_, err := listAllFiles(ctx, adapter, "", []string{})
+	_, err := listAllFiles(ctx, adapter, "", ignore.New(nil), false)
 
 	// Could be either nil (completed fast) or context.Canceled
 	if err != nil && err != context.Canceled {
 		t.Errorf("Expected nil or context.Canceled, got %v", err)
 	}
 }
+
+// checksumAdapter computes checksums only on demand, like local files over
+// the listing size limit
+type checksumAdapter struct {
+	mockAdapter
+	sums  map[string]string
+	stats int
+}
+
+func (m *checksumAdapter) StatWithChecksum(ctx context.Context, path string) (domain.FileInfo, error) {
+	m.stats++
+	info, _ := m.Stat(ctx, path)
+	info.Checksum = m.sums[path]
+	info.ChecksumAlgorithm = "md5"
+	return info, nil
+}
+
+func TestExecutor_ChecksumsOnDemand(t *testing.T) {
+	executor := NewDefaultExecutor()
+	now := time.Now()
+	file := func(path, sum string, modTime time.Time) domain.FileInfo {
+		info := domain.FileInfo{Path: path, Type: domain.FileTypeRegular, Size: 100, ModTime: modTime}
+		if sum != "" {
+			info.Checksum = sum
+			info.ChecksumAlgorithm = "md5"
+		}
+		return info
+	}
+
+	source := &checksumAdapter{
+		mockAdapter: mockAdapter{files: []domain.FileInfo{
+			file("same.bin", "", now),
+			file("changed.bin", "", now),
+			file("untouched.bin", "", now),
+		}},
+		sums: map[string]string{"same.bin": "aaa", "changed.bin": "bbb", "untouched.bin": "ccc"},
+	}
+	target := &mockAdapter{files: []domain.FileInfo{
+		file("same.bin", "aaa", now.Add(-time.Hour)),
+		file("changed.bin", "old", now.Add(-time.Hour)),
+		file("untouched.bin", "ccc", now), // Same mtime: no hashing needed
+	}}
+
+	rule := &domain.SyncRule{Name: "push", Mode: domain.SyncModeOneWayPush}
+	plan, err := executor.Plan(context.Background(), rule, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var copied []string
+	for _, action := range plan.Actions {
+		if action.Type == domain.ActionCopy {
+			copied = append(copied, action.Path)
+		}
+	}
+	if len(copied) != 1 || copied[0] != "changed.bin" {
+		t.Errorf("Expected only changed.bin to be copied, got %v", copied)
+	}
+	if source.stats != 2 {
+		t.Errorf("Expected 2 on-demand checksums, got %d", source.stats)
+	}
+}
+
+func TestExecutor_LocalHashesOnlyComparableFiles(t *testing.T) {
+	executor := NewDefaultExecutor()
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	newLocal := func(files map[string]string) *local.Adapter {
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, past, past); err != nil {
+				t.Fatal(err)
+			}
+		}
+		adp, err := local.New(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return adp
+	}
+	rule := &domain.SyncRule{Name: "push", Mode: domain.SyncModeOneWayPush}
+
+	// A target without checksums: the local side is not hashed, and files of
+	// equal size are not taken for modified
+	source := newLocal(map[string]string{"a.txt": "hello"})
+	target := &mockAdapter{files: []domain.FileInfo{
+		{Path: "a.txt", Type: domain.FileTypeRegular, Size: 5, ModTime: time.Now()},
+	}}
+	plan, err := executor.Plan(context.Background(), rule, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Actions) != 0 {
+		t.Errorf("Expected no actions against a target without checksums, got %+v", plan.Actions)
+	}
+
+	// Two local endpoints: files of equal size but different mtime are hashed
+	// on both sides and compared by content
+	source = newLocal(map[string]string{"same.txt": "hello", "changed.txt": "hello"})
+	dst := newLocal(map[string]string{"same.txt": "hello", "changed.txt": "world"})
+	for _, name := range []string{"same.txt", "changed.txt"} {
+		if err := dst.SetModTime(context.Background(), name, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan, err = executor.Plan(context.Background(), rule, source, dst)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Path != "changed.txt" {
+		t.Errorf("Expected only changed.txt to be copied, got %+v", plan.Actions)
+	}
+}
+
+func TestExecutor_ChecksumsFromBaseline(t *testing.T) {
+	executor := NewDefaultExecutor()
+	now := time.Now()
+	file := func(path, sum string, modTime time.Time) domain.FileInfo {
+		info := domain.FileInfo{Path: path, Type: domain.FileTypeRegular, Size: 100, ModTime: modTime}
+		if sum != "" {
+			info.Checksum = sum
+			info.ChecksumAlgorithm = "md5"
+		}
+		return info
+	}
+
+	// touched.bin was touched on the source and deleted on the target
+	source := &checksumAdapter{
+		mockAdapter: mockAdapter{files: []domain.FileInfo{file("touched.bin", "", now)}},
+		sums:        map[string]string{"touched.bin": "aaa"},
+	}
+	target := &checksumAdapter{}
+	baseline := map[string]domain.FileInfo{
+		"touched.bin": file("touched.bin", "aaa", now.Add(-time.Hour)),
+	}
+
+	rule := &domain.SyncRule{Name: "two-way", Mode: domain.SyncModeTwoWay}
+	plan, err := executor.PlanWithBaseline(context.Background(), rule, source, target, baseline)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != domain.ActionDelete {
+		t.Errorf("Expected the unchanged file to be deleted, got %+v", plan.Actions)
+	}
+	if source.stats != 1 {
+		t.Errorf("Expected 1 on-demand checksum, got %d", source.stats)
+	}
+}
+
+func TestExecutor_ChecksumsForMoves(t *testing.T) {
+	executor := NewDefaultExecutor()
+	now := time.Now()
+	file := func(path string, size int64) domain.FileInfo {
+		return domain.FileInfo{Path: path, Type: domain.FileTypeRegular, Size: size, ModTime: now}
+	}
+
+	source := &checksumAdapter{
+		mockAdapter: mockAdapter{files: []domain.FileInfo{file("renamed.iso", 300), file("other.bin", 10)}},
+		sums:        map[string]string{"renamed.iso": "iso", "other.bin": "oth"},
+	}
+	target := &checksumAdapter{
+		mockAdapter: mockAdapter{files: []domain.FileInfo{file("original.iso", 300)}},
+		sums:        map[string]string{"original.iso": "iso"},
+	}
+
+	rule := &domain.SyncRule{Name: "push", Mode: domain.SyncModeOneWayPush}
+	plan, err := executor.Plan(context.Background(), rule, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if plan.Stats.FilesToMove != 1 || plan.Stats.FilesToCopy != 1 || plan.Stats.FilesToDelete != 0 {
+		t.Errorf("Expected a move and a copy, got %+v", plan.Actions)
+	}
+	// other.bin has no counterpart of its size and is not hashed
+	if source.stats != 1 || target.stats != 1 {
+		t.Errorf("Expected 1 on-demand checksum per side, got %d and %d", source.stats, target.stats)
+	}
+}
+
+func TestExecutor_SyncIgnoreFiles(t *testing.T) {
+	fs := memory.NewFS()
+	source, _ := fs.Adapter("src")
+	target, _ := fs.Adapter("dst")
+	now := time.Now()
+	source.WriteFile(".syncignore", []byte("# build output\n*.log\nbuild/\n!important.tmp\n"), now)
+	source.WriteFile("app.log", []byte("log"), now)
+	source.WriteFile("build/out.bin", []byte("bin"), now)
+	source.WriteFile("scratch.tmp", []byte("tmp"), now)
+	source.WriteFile("important.tmp", []byte("tmp"), now)
+	source.WriteFile("docs/.syncignore", []byte("!keep.log\n/draft.md\n"), now)
+	source.WriteFile("docs/keep.log", []byte("kept"), now)
+	source.WriteFile("docs/draft.md", []byte("draft"), now)
+	source.WriteFile("docs/readme.md", []byte("readme"), now)
+	source.WriteFile("docs/sub/draft.md", []byte("nested draft"), now)
+	source.WriteFile("other/keep.log", []byte("not kept"), now)
+	target.WriteFile("cache.log", []byte("target only"), now)
+
+	// Ignored directories are never listed
+	source.FailOn(memory.OpList, "build", errors.New("listed an ignored directory"))
+
+	rule := &domain.SyncRule{Name: "push", Mode: domain.SyncModeOneWayPush, IgnorePatterns: []string{"*.tmp"}}
+	plan, err := NewDefaultExecutor().Plan(context.Background(), rule, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	got := make(map[string]domain.ActionType)
+	for _, action := range plan.Actions {
+		got[action.Path] = action.Type
+	}
+	want := map[string]domain.ActionType{
+		".syncignore":       domain.ActionCopy,
+		"important.tmp":     domain.ActionCopy,
+		"docs":              domain.ActionMkdir,
+		"docs/.syncignore":  domain.ActionCopy,
+		"docs/keep.log":     domain.ActionCopy,
+		"docs/readme.md":    domain.ActionCopy,
+		"docs/sub":          domain.ActionMkdir,
+		"docs/sub/draft.md": domain.ActionCopy,
+		"other":             domain.ActionMkdir,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d actions, got %v", len(want), got)
+	}
+	for path, typ := range want {
+		if got[path] != typ {
+			t.Errorf("%s: expected %s, got %q", path, typ, got[path])
+		}
+	}
+}
+
+func TestExecutor_Filters(t *testing.T) {
+	fs := memory.NewFS()
+	source, _ := fs.Adapter("src")
+	target, _ := fs.Adapter("dst")
+	now := time.Now()
+	old := now.Add(-60 * 24 * time.Hour)
+	big := bytes.Repeat([]byte("x"), 2048)
+	source.WriteFile("docs/a.md", []byte("notes"), now)
+	source.WriteFile("big.md", big, now)
+	source.WriteFile("old.yaml", []byte("old"), old)
+	source.WriteFile("edited.yaml", []byte("edited"), now)
+	source.WriteFile("image.png", []byte("png"), now)
+	source.WriteFile("assets/logo.png", []byte("png"), now)
+	target.WriteFile("edited.yaml", []byte("before"), old)
+	target.WriteFile("stale/notes.md", []byte("stale notes"), now)
+	target.WriteFile("stale/data.bin", []byte("data"), now)
+	target.WriteFile("grown.md", big, now)
+	source.WriteFile("shrunk.md", []byte("short"), now)
+	target.WriteFile("shrunk.md", big, old)
+
+	rule := &domain.SyncRule{
+		Name:            "push",
+		Mode:            domain.SyncModeOneWayPush,
+		IncludePatterns: []string{"*.md", "*.yaml"},
+		MaxSize:         "1KB",
+		MaxAge:          "720h",
+	}
+	plan, err := NewDefaultExecutor().Plan(context.Background(), rule, source, target)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	got := make(map[string]domain.ActionType)
+	for _, action := range plan.Actions {
+		got[action.Path] = action.Type
+	}
+	want := map[string]domain.ActionType{
+		"docs":           domain.ActionMkdir,
+		"docs/a.md":      domain.ActionCopy,
+		"edited.yaml":    domain.ActionCopy,
+		"shrunk.md":      domain.ActionCopy, // Only the source version is read
+		"stale/notes.md": domain.ActionDelete,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d actions, got %v", len(want), got)
+	}
+	for path, typ := range want {
+		if got[path] != typ {
+			t.Errorf("%s: expected %s, got %q", path, typ, got[path])
+		}
+	}
+
+	reasons := make(map[string]string)
+	for _, f := range plan.Filtered {
+		reasons[f.Path] = f.Reason
+	}
+	wantReasons := map[string]string{
+		"assets":          "only filtered paths beneath",
+		"assets/logo.png": "not matched by include patterns",
+		"big.md":          "above max_size",
+		"grown.md":        "above max_size",
+		"image.png":       "not matched by include patterns",
+		"old.yaml":        "longer ago than max_age",
+		"stale":           "filtered paths beneath",
+		"stale/data.bin":  "not matched by include patterns",
+	}
+	if len(reasons) != len(wantReasons) || plan.Stats.Filtered != len(wantReasons) {
+		t.Errorf("Expected %d filtered paths, got %v", len(wantReasons), plan.Filtered)
+	}
+	for path, reason := range wantReasons {
+		if !strings.Contains(reasons[path], reason) {
+			t.Errorf("%s: expected reason containing %q, got %q", path, reason, reasons[path])
+		}
+	}
+}
+
+func TestExecutor_FiltersTwoWay(t *testing.T) {
+	fs := memory.NewFS()
+	source, _ := fs.Adapter("src")
+	target, _ := fs.Adapter("dst")
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	big := bytes.Repeat([]byte("x"), 2048)
+
+	// Edited on one side since the last sync: only the edit is held to max_size
+	source.WriteFile("trimmed.log", []byte("short"), now)
+	target.WriteFile("trimmed.log", big, old)
+	source.WriteFile("grown.log", []byte("short"), old)
+	target.WriteFile("grown.log", big, now)
+	// No baseline entry to tell which side changed
+	source.WriteFile("new.log", []byte("short"), now)
+	target.WriteFile("new.log", big, now)
+
+	baseline := map[string]domain.FileInfo{
+		"trimmed.log": {Path: "trimmed.log", Type: domain.FileTypeRegular, Size: int64(len(big)), ModTime: old},
+		"grown.log":   {Path: "grown.log", Type: domain.FileTypeRegular, Size: 5, ModTime: old},
+	}
+	rule := &domain.SyncRule{Name: "two-way", Mode: domain.SyncModeTwoWay, MaxSize: "1KB"}
+	plan, err := NewDefaultExecutor().PlanWithBaseline(context.Background(), rule, source, target, baseline)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Path != "trimmed.log" || plan.Actions[0].Direction != domain.DirSourceToTarget {
+		t.Errorf("Expected trimmed.log to be copied to the target, got %+v", plan.Actions)
+	}
+	filtered := make(map[string]bool)
+	for _, f := range plan.Filtered {
+		filtered[f.Path] = true
+	}
+	if len(filtered) != 2 || !filtered["grown.log"] || !filtered["new.log"] {
+		t.Errorf("Expected grown.log and new.log to be filtered, got %v", plan.Filtered)
+	}
+}
@@ -0,0 +1,118 @@
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/core/ignore"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// filterPaths removes the files excluded by the rule's include, size and age
+// filters from both listings, and returns them with the reason
+// A path is judged as a whole so both endpoints leave it alone. The size
+// limits apply to the version a sync would read: the source of a one-way
+// rule, or in two-way mode the side changed since the baseline (both, if
+// that can't be told); a path on one side only is judged by that version.
+// The age limits apply to the latest modification of its versions, so a
+// recent edit on one side still syncs. Directories are never filtered here
+func filterPaths(rule *domain.SyncRule, now time.Time, sourceMap, targetMap, baseline map[string]domain.FileInfo) ([]domain.FilteredPath, error) {
+	limits, err := rule.FileFilter()
+	if err != nil {
+		return nil, err
+	}
+	if len(rule.IncludePatterns) == 0 && limits.IsZero() {
+		return nil, nil
+	}
+	included := ignore.New(rule.IncludePatterns)
+
+	var filtered []domain.FilteredPath
+	judge := func(path string) {
+		src, srcExists := sourceMap[path]
+		tgt, tgtExists := targetMap[path]
+		if src.IsDir() || tgt.IsDir() {
+			return
+		}
+
+		var versions []domain.FileInfo
+		if srcExists {
+			versions = append(versions, src)
+		}
+		if tgtExists {
+			versions = append(versions, tgt)
+		}
+		sized := versions
+		if srcExists && tgtExists {
+			base, baseExists := baseline[path]
+			switch {
+			case rule.Mode == domain.SyncModeOneWayPush:
+				sized = versions[:1]
+			case rule.Mode == domain.SyncModeOneWayPull:
+				sized = versions[1:]
+			case baseExists && changedSince(src, base) && !changedSince(tgt, base):
+				sized = versions[:1]
+			case baseExists && changedSince(tgt, base) && !changedSince(src, base):
+				sized = versions[1:]
+			}
+		}
+		reason := filterReason(rule, limits, included, now, path, versions, sized)
+		if reason == "" {
+			return
+		}
+		delete(sourceMap, path)
+		delete(targetMap, path)
+		filtered = append(filtered, domain.FilteredPath{Path: path, Reason: reason})
+	}
+
+	for path := range sourceMap {
+		judge(path)
+	}
+	for path := range targetMap {
+		judge(path)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].Path < filtered[j].Path
+	})
+	return filtered, nil
+}
+
+// filterReason returns why the versions of a file are filtered, or "" if
+// they are synced; only the versions in sized are held to the size limits
+func filterReason(rule *domain.SyncRule, limits domain.FileFilter, included *ignore.Matcher, now time.Time, path string, versions, sized []domain.FileInfo) string {
+	if len(rule.IncludePatterns) > 0 && !included.Ignored(path, false) {
+		return "not matched by include patterns"
+	}
+
+	for _, v := range sized {
+		if limits.MinSize > 0 && v.Size < limits.MinSize {
+			return fmt.Sprintf("size %d bytes is below min_size %s", v.Size, rule.MinSize)
+		}
+		if limits.MaxSize > 0 && v.Size > limits.MaxSize {
+			return fmt.Sprintf("size %d bytes is above max_size %s", v.Size, rule.MaxSize)
+		}
+	}
+
+	var latest time.Time
+	for _, v := range versions {
+		if v.ModTime.After(latest) {
+			latest = v.ModTime
+		}
+	}
+
+	age := now.Sub(latest)
+	if limits.MinAge > 0 && age < limits.MinAge {
+		return fmt.Sprintf("modified %s, more recently than min_age %s", latest.Format(time.RFC3339), rule.MinAge)
+	}
+	if limits.MaxAge > 0 && age > limits.MaxAge {
+		return fmt.Sprintf("modified %s, longer ago than max_age %s", latest.Format(time.RFC3339), rule.MaxAge)
+	}
+	return ""
+}
+
+// changedSince reports whether a listed file differs from its baseline entry
+// by size or mtime; checksums are not computed yet when filtering
+func changedSince(current, base domain.FileInfo) bool {
+	return current.Size != base.Size || !current.ModTime.Equal(base.ModTime)
+}
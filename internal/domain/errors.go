@@ -16,6 +16,9 @@ var (
 	// ErrNotDirectory indicates expected a directory but got a file
 	ErrNotDirectory = errors.New("not a directory")
 
+	// ErrDirectoryNotEmpty indicates a directory still has entries and cannot be deleted
+	ErrDirectoryNotEmpty = errors.New("directory not empty")
+
 	// ErrNotFile indicates expected a file but got a directory
 	ErrNotFile = errors.New("not a file")
 
@@ -37,6 +40,9 @@ var (
 	// ErrSyncConflict indicates an unresolved sync conflict
 	ErrSyncConflict = errors.New("sync conflict")
 
+	// ErrInvalidResolution indicates a resolution that cannot be applied to a conflict
+	ErrInvalidResolution = errors.New("invalid conflict resolution")
+
 	// ErrInvalidRule indicates a malformed sync rule
 	ErrInvalidRule = errors.New("invalid sync rule")
 
@@ -45,6 +51,15 @@ var (
 
 	// ErrSyncInProgress indicates another sync is already running
 	ErrSyncInProgress = errors.New("sync already in progress")
+
+	// ErrTooManyDeletions indicates a plan deletes more than its rule allows
+	ErrTooManyDeletions = errors.New("too many deletions")
+
+	// ErrInvalidPlan indicates a saved plan that cannot be read or was altered
+	ErrInvalidPlan = errors.New("invalid plan document")
+
+	// ErrPlanStale indicates an endpoint changed since a saved plan was made
+	ErrPlanStale = errors.New("plan is out of date")
 )
 
 // Config errors - 設定檔錯誤
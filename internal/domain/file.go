@@ -26,9 +26,12 @@ type FileInfo struct {
 	ModTime time.Time
 
 	// Checksum is the content hash (empty for directories)
-	// Algorithm should be consistent within a sync session
 	Checksum string
 
+	// ChecksumAlgorithm names the algorithm that produced Checksum (e.g., "sha256", "md5")
+	// Checksums are only comparable when produced by the same algorithm
+	ChecksumAlgorithm string
+
 	// ETag is the remote version identifier (for cloud adapters)
 	ETag string
 
@@ -36,6 +39,20 @@ type FileInfo struct {
 	IsDeleted bool
 }
 
+// ChecksumComparable reports whether both files carry checksums
+// produced by the same algorithm
+func (f FileInfo) ChecksumComparable(other FileInfo) bool {
+	return f.Checksum != "" && other.Checksum != "" &&
+		f.ChecksumAlgorithm == other.ChecksumAlgorithm
+}
+
+// Tombstone returns a copy of the file info marked as deleted
+// Used to describe the missing side of a deletion in a sync action
+func (f FileInfo) Tombstone() FileInfo {
+	f.IsDeleted = true
+	return f
+}
+
 // IsDir returns true if this is a directory
 func (f FileInfo) IsDir() bool {
 	return f.Type == FileTypeDirectory
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilteredPath is a path a plan leaves alone on both endpoints because of
+// its rule's include, size or age filters
+type FilteredPath struct {
+	// Path is relative to the endpoint roots
+	Path string
+
+	// Reason explains which filter excluded the path
+	Reason string
+}
+
+// FileFilter is the parsed size and age filters of a rule
+// Zero values mean no limit
+type FileFilter struct {
+	MinSize int64
+	MaxSize int64
+	MinAge  time.Duration
+	MaxAge  time.Duration
+}
+
+// IsZero reports whether the filter has no limits
+func (f FileFilter) IsZero() bool {
+	return f == FileFilter{}
+}
+
+// FileFilter parses the rule's size and age filters
+func (r SyncRule) FileFilter() (FileFilter, error) {
+	var f FileFilter
+	var err error
+	if f.MinSize, err = ParseSize(r.MinSize); err != nil {
+		return FileFilter{}, fmt.Errorf("invalid min_size: %w", err)
+	}
+	if f.MaxSize, err = ParseSize(r.MaxSize); err != nil {
+		return FileFilter{}, fmt.Errorf("invalid max_size: %w", err)
+	}
+	if f.MaxSize > 0 && f.MinSize > f.MaxSize {
+		return FileFilter{}, fmt.Errorf("min_size %s is above max_size %s", r.MinSize, r.MaxSize)
+	}
+	if f.MinAge, err = parseAge(r.MinAge); err != nil {
+		return FileFilter{}, fmt.Errorf("invalid min_age: %w", err)
+	}
+	if f.MaxAge, err = parseAge(r.MaxAge); err != nil {
+		return FileFilter{}, fmt.Errorf("invalid max_age: %w", err)
+	}
+	if f.MaxAge > 0 && f.MinAge >= f.MaxAge {
+		return FileFilter{}, fmt.Errorf("min_age %s is not below max_age %s", r.MinAge, r.MaxAge)
+	}
+	return f, nil
+}
+
+// sizeUnits maps size suffixes to their multiplier, in powers of 1024
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes ("4096") or with a unit ("512KB", "1.5GB")
+// Units are powers of 1024. Returns 0 for an empty string
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	number, multiplier := strings.ToUpper(s), int64(1)
+	for _, unit := range sizeUnits {
+		if n, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, multiplier = strings.TrimSpace(n), unit.bytes
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which no longer fits
+	bytes := value * float64(multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("%q is too large", s)
+	}
+	return int64(bytes), nil
+}
+
+// parseAge parses a positive duration; returns 0 for an empty string
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q is not positive", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"4096", 4096, false},
+		{"512KB", 512 << 10, false},
+		{"1.5GB", 3 << 29, false},
+		{"2 mib", 2 << 20, false},
+		{"10b", 10, false},
+		{"8388607TB", 8388607 << 40, false},
+		{"-1KB", 0, true},
+		{"KB", 0, true},
+		{"ten", 0, true},
+		{"NaN", 0, true},
+		{"NaNKB", 0, true},
+		{"Inf", 0, true},
+		{"+InfGB", 0, true},
+		{"1e30GB", 0, true},
+		{"8388608TB", 0, true}, // 2^63 bytes
+		{"9223372036854775808", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSize(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PlanFormatVersion is the version of the plan document format written by
+// this build; documents of other versions are rejected
+const PlanFormatVersion = 1
+
+// PlanDocument is a sync plan saved for review, to be applied later
+// It records the endpoints and the state of every path the plan touches at
+// planning time, so the plan can be checked against the endpoints again
+// before each action runs
+type PlanDocument struct {
+	// Version is the format version (PlanFormatVersion)
+	Version int
+
+	// CreatedAt is when the document was exported
+	CreatedAt time.Time
+
+	// Source and Target snapshot the rule's endpoints
+	Source EndpointSnapshot
+	Target EndpointSnapshot
+
+	// Plan is the plan to apply
+	Plan SyncPlan
+
+	// Fingerprint is the hex SHA-256 of the document with an empty Fingerprint
+	// It changes with any edit, so it identifies the plan that was approved
+	Fingerprint string
+}
+
+// EndpointSnapshot is the state of an endpoint that a plan was made against
+type EndpointSnapshot struct {
+	// Endpoint is the endpoint name
+	Endpoint string
+
+	// Files holds the metadata of the paths the plan's actions read or change
+	// Paths the plan expects not to exist are not listed
+	Files map[string]FileInfo
+}
+
+// NewPlanDocument creates a document for a plan of a rule between the named endpoints
+func NewPlanDocument(plan *SyncPlan, source, target string, createdAt time.Time) *PlanDocument {
+	doc := &PlanDocument{
+		Version:   PlanFormatVersion,
+		CreatedAt: createdAt.UTC(),
+		Source:    EndpointSnapshot{Endpoint: source, Files: make(map[string]FileInfo)},
+		Target:    EndpointSnapshot{Endpoint: target, Files: make(map[string]FileInfo)},
+		Plan:      *plan,
+	}
+	for _, action := range plan.Actions {
+		if action.SourceInfo != nil && !action.SourceInfo.IsDeleted {
+			doc.Source.Files[action.SourceInfo.Path] = *action.SourceInfo
+		}
+		if action.TargetInfo != nil && !action.TargetInfo.IsDeleted {
+			doc.Target.Files[action.TargetInfo.Path] = *action.TargetInfo
+		}
+	}
+	doc.Fingerprint = doc.ComputeFingerprint()
+	return doc
+}
+
+// ComputeFingerprint returns the fingerprint of the document's content
+func (d *PlanDocument) ComputeFingerprint() string {
+	unsigned := *d
+	unsigned.Fingerprint = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		// Documents consist of plain data; marshalling cannot fail
+		panic(fmt.Sprintf("marshal plan document: %v", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Encode writes the document as indented JSON
+func (d *PlanDocument) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// DecodePlanDocument reads a document written by Encode
+// Returns ErrInvalidPlan if the version is unsupported or the content does
+// not match its fingerprint
+func DecodePlanDocument(r io.Reader) (*PlanDocument, error) {
+	var doc PlanDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	if doc.Version != PlanFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d (want %d)", ErrInvalidPlan, doc.Version, PlanFormatVersion)
+	}
+	if doc.Fingerprint == "" || doc.ComputeFingerprint() != doc.Fingerprint {
+		return nil, fmt.Errorf("%w: content does not match fingerprint", ErrInvalidPlan)
+	}
+	return &doc, nil
+}
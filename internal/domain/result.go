@@ -0,0 +1,65 @@
+package domain
+
+import "fmt"
+
+// ActionOutcome pairs an action with the error it failed or was skipped with
+type ActionOutcome struct {
+	Action SyncAction
+	Err    error
+}
+
+// SyncResult summarizes the execution of a sync plan
+// Plan entries that need no work (skip, conflict) are not listed
+type SyncResult struct {
+	// RuleName identifies which rule was executed
+	RuleName string
+
+	// Succeeded actions completed without error
+	Succeeded []SyncAction
+
+	// Failed actions were attempted and returned an error
+	Failed []ActionOutcome
+
+	// Skipped actions were not attempted, e.g. because a parent directory failed
+	// or execution was cancelled; Err explains why
+	Skipped []ActionOutcome
+
+	// FilesSynced and BytesSynced count the copies that actually completed
+	FilesSynced int
+	BytesSynced int64
+}
+
+// HasFailures reports whether any action failed or was skipped because of a failure
+func (r *SyncResult) HasFailures() bool {
+	return len(r.Failed) > 0 || len(r.Skipped) > 0
+}
+
+// Status returns the execution status: "success", "partial" or "failed"
+// A run is partial when some actions failed but others made progress
+func (r *SyncResult) Status() string {
+	switch {
+	case !r.HasFailures():
+		return "success"
+	case len(r.Succeeded) > 0:
+		return "partial"
+	default:
+		return "failed"
+	}
+}
+
+// Summary describes the failures of the run ("" if none)
+func (r *SyncResult) Summary() string {
+	if !r.HasFailures() {
+		return ""
+	}
+
+	msg := fmt.Sprintf("%d action(s) failed", len(r.Failed))
+	if len(r.Skipped) > 0 {
+		msg += fmt.Sprintf(", %d skipped", len(r.Skipped))
+	}
+	if len(r.Failed) > 0 {
+		first := r.Failed[0]
+		msg += fmt.Sprintf("; first error: %s %s: %v", first.Action.Type, first.Action.Path, first.Err)
+	}
+	return msg
+}
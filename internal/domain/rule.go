@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // SyncRule defines a synchronization relationship between two endpoints
 type SyncRule struct {
 	// Name is the unique identifier for this rule
@@ -17,6 +23,20 @@ type SyncRule struct {
 	// IgnorePatterns glob patterns to exclude
 	IgnorePatterns []string `mapstructure:"ignore"`
 
+	// IncludePatterns limit the sync to files matching one of them, in the
+	// same syntax as IgnorePatterns (empty = all files)
+	IncludePatterns []string `mapstructure:"include"`
+
+	// MinSize and MaxSize skip files smaller or larger than a size such as
+	// "512KB" or "10MB" (empty = no limit)
+	MinSize string `mapstructure:"min_size"`
+	MaxSize string `mapstructure:"max_size"`
+
+	// MinAge skips files modified more recently than a duration ago (e.g., "10m"),
+	// MaxAge files last modified longer ago (e.g., "720h"); empty = no limit
+	MinAge string `mapstructure:"min_age"`
+	MaxAge string `mapstructure:"max_age"`
+
 	// ConflictStrategy how to handle conflicts
 	ConflictStrategy ConflictStrategy `mapstructure:"conflict"`
 
@@ -25,6 +45,33 @@ type SyncRule struct {
 
 	// Schedule defines rule-specific scheduling configuration
 	Schedule *ScheduleConfig `mapstructure:"schedule"`
+
+	// Concurrency is the maximum number of actions executed at once
+	// (0 = use the transports' concurrency)
+	Concurrency int `mapstructure:"concurrency"`
+
+	// Trash keeps deleted and overwritten files in the endpoint's trash
+	// instead of discarding them (nil = disabled)
+	Trash *TrashConfig `mapstructure:"trash"`
+
+	// MaxDeletes limits the deletions a plan may contain, as a count ("100")
+	// or a percentage of the paths it compares ("10%"); empty = no limit
+	// Plans over the limit are refused unless execution is forced
+	MaxDeletes string `mapstructure:"max_deletes"`
+}
+
+// TrashConfig contains rule-level safe-delete configuration
+// Files a sync deletes or overwrites are moved to TrashDir/<timestamp>/ on
+// the endpoint they are removed from, one timestamp directory per run
+type TrashConfig struct {
+	// Enabled turns on the trash for this rule
+	Enabled bool `mapstructure:"enabled"`
+
+	// MaxAge removes trash runs older than this after each sync (e.g., "720h"; empty = keep)
+	MaxAge string `mapstructure:"max_age"`
+
+	// MaxCount keeps at most this many trash runs per endpoint (0 = unlimited)
+	MaxCount int `mapstructure:"max_count"`
 }
 
 // ScheduleConfig contains rule-level scheduling configuration
@@ -34,6 +81,13 @@ type ScheduleConfig struct {
 
 	// Interval overrides the global default interval for this rule (e.g., "10m", "2h")
 	Interval string `mapstructure:"interval"`
+
+	// Cron schedules the rule with a standard 5-field cron expression (e.g., "0 2 * * *")
+	// Mutually exclusive with Interval
+	Cron string `mapstructure:"cron"`
+
+	// Timezone is the IANA timezone Cron is evaluated in (e.g., "Asia/Taipei"; empty = local)
+	Timezone string `mapstructure:"timezone"`
 }
 
 // Validate checks if the rule is properly configured
@@ -53,26 +107,87 @@ func (r SyncRule) Validate() error {
 	if r.ConflictStrategy != "" && !r.ConflictStrategy.IsValid() {
 		return ErrInvalidRule
 	}
+	if _, err := ParseDeleteLimit(r.MaxDeletes); err != nil {
+		return ErrInvalidRule
+	}
+	if _, err := r.FileFilter(); err != nil {
+		return ErrInvalidRule
+	}
 	return nil
 }
 
+// CheckDeletions returns ErrTooManyDeletions if a plan with these stats
+// deletes more than the rule's MaxDeletes allows
+func (r SyncRule) CheckDeletions(stats SyncPlanStats) error {
+	limit, err := ParseDeleteLimit(r.MaxDeletes)
+	if err != nil {
+		return err
+	}
+	if limit == nil || limit.Allows(stats.FilesToDelete, stats.TrackedPaths) {
+		return nil
+	}
+	return fmt.Errorf("%w: plan deletes %d of %d paths, rule %s allows %s",
+		ErrTooManyDeletions, stats.FilesToDelete, stats.TrackedPaths, r.Name, limit)
+}
+
+// DeleteLimit is a parsed SyncRule.MaxDeletes
+type DeleteLimit struct {
+	// Count is the maximum number of deletions, used if Percent is 0
+	Count int
+
+	// Percent is the maximum share of compared paths that may be deleted
+	Percent float64
+}
+
+// ParseDeleteLimit parses a count ("100") or a percentage ("10%")
+// Returns nil for an empty string, which means no limit
+func ParseDeleteLimit(s string) (*DeleteLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	if number, ok := strings.CutSuffix(s, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid deletion limit %q: percentage must be in (0, 100]", s)
+		}
+		return &DeleteLimit{Percent: percent}, nil
+	}
+
+	count, err := strconv.Atoi(s)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid deletion limit %q: must be a count or a percentage", s)
+	}
+	return &DeleteLimit{Count: count}, nil
+}
+
+// Allows reports whether deleting deletes of tracked paths is within the limit
+func (l DeleteLimit) Allows(deletes, tracked int) bool {
+	if l.Percent > 0 {
+		return float64(deletes)*100 <= l.Percent*float64(tracked)
+	}
+	return deletes <= l.Count
+}
+
+// String returns the limit as written in the config
+func (l DeleteLimit) String() string {
+	if l.Percent > 0 {
+		return strconv.FormatFloat(l.Percent, 'f', -1, 64) + "%"
+	}
+	return strconv.Itoa(l.Count)
+}
+
 // TransportType identifies the storage backend type
+// Backends register the types they handle with the adapter registry
 type TransportType string
 
+// Built-in transport types the service treats specially
 const (
 	TransportLocal  TransportType = "local"
 	TransportGDrive TransportType = "gdrive"
 )
 
-// IsValid checks if the transport type is a known value
-func (t TransportType) IsValid() bool {
-	switch t {
-	case TransportLocal, TransportGDrive:
-		return true
-	}
-	return false
-}
-
 // Transport defines a storage backend configuration
 type Transport struct {
 	// Name is the unique identifier
@@ -86,7 +201,12 @@ type Transport struct {
 
 	// Config holds transport-specific configuration
 	// For gdrive: client_id, client_secret, token_path
+	// Other backends document their keys with their adapter factory
 	Config map[string]string `mapstructure:"config"`
+
+	// Concurrency is the maximum number of actions executed at once by rules
+	// using this transport (0 = sequential)
+	Concurrency int `mapstructure:"concurrency"`
 }
 
 // Endpoint defines a specific location within a transport
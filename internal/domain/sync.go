@@ -38,12 +38,42 @@ const (
 
 	// ConflictManual requires user intervention
 	ConflictManual ConflictStrategy = "manual"
+
+	// ConflictKeepBoth syncs the newer version and keeps the older one
+	// as a conflict copy next to it
+	ConflictKeepBoth ConflictStrategy = "keep_both"
 )
 
 // IsValid checks if the conflict strategy is a known value
 func (s ConflictStrategy) IsValid() bool {
 	switch s {
-	case ConflictKeepLocal, ConflictKeepRemote, ConflictKeepNewest, ConflictManual:
+	case ConflictKeepLocal, ConflictKeepRemote, ConflictKeepNewest, ConflictManual, ConflictKeepBoth:
+		return true
+	}
+	return false
+}
+
+// ConflictResolution is a user's decision on a recorded manual conflict
+type ConflictResolution string
+
+const (
+	// ResolveTakeSource syncs the source version (or deletion) to the target
+	ResolveTakeSource ConflictResolution = "take_source"
+
+	// ResolveTakeTarget syncs the target version (or deletion) to the source
+	ResolveTakeTarget ConflictResolution = "take_target"
+
+	// ResolveKeepBoth keeps the older version as a conflict copy, like ConflictKeepBoth
+	ResolveKeepBoth ConflictResolution = "keep_both"
+
+	// ResolveIgnore leaves both versions as they are and stops reporting the conflict
+	ResolveIgnore ConflictResolution = "ignore"
+)
+
+// IsValid checks if the conflict resolution is a known value
+func (r ConflictResolution) IsValid() bool {
+	switch r {
+	case ResolveTakeSource, ResolveTakeTarget, ResolveKeepBoth, ResolveIgnore:
 		return true
 	}
 	return false
@@ -66,10 +96,30 @@ type SyncAction struct {
 	// TargetInfo file metadata from target (nil for create)
 	TargetInfo *FileInfo
 
+	// NewPath is where a rename or move takes Path to, on the endpoint the
+	// direction writes to (empty for other actions)
+	NewPath string
+
 	// Reason explains why this action was chosen
 	Reason string
 }
 
+// OriginInfo returns the metadata on the endpoint the action reads from
+func (a SyncAction) OriginInfo() *FileInfo {
+	if a.Direction == DirTargetToSource {
+		return a.TargetInfo
+	}
+	return a.SourceInfo
+}
+
+// DestinationInfo returns the metadata on the endpoint the action writes to
+func (a SyncAction) DestinationInfo() *FileInfo {
+	if a.Direction == DirTargetToSource {
+		return a.SourceInfo
+	}
+	return a.TargetInfo
+}
+
 // ActionType represents the type of sync action
 type ActionType string
 
@@ -77,6 +127,8 @@ const (
 	ActionCopy     ActionType = "copy"
 	ActionDelete   ActionType = "delete"
 	ActionMkdir    ActionType = "mkdir"
+	ActionRename   ActionType = "rename"
+	ActionMove     ActionType = "move"
 	ActionConflict ActionType = "conflict"
 	ActionSkip     ActionType = "skip"
 )
@@ -104,6 +156,17 @@ type SyncPlan struct {
 
 	// Stats summary
 	Stats SyncPlanStats
+
+	// Baseline is the synced tree expected after the plan executes successfully
+	// Only populated for two-way plans; persisted so the next run can detect deletions
+	Baseline map[string]FileInfo
+
+	// Resolved lists the recorded conflicts whose resolution this plan applies, by path
+	Resolved map[string]ConflictResolution
+
+	// Filtered lists the paths the rule's include, size and age filters
+	// leave alone, with the reason for each
+	Filtered []FilteredPath
 }
 
 // SyncPlanStats provides summary statistics for a sync plan
@@ -111,7 +174,15 @@ type SyncPlanStats struct {
 	TotalFiles   int
 	FilesToCopy  int
 	FilesToDelete int
+	FilesToMove  int
 	DirsToCreate int
 	Conflicts    int
 	BytesToSync  int64
+
+	// TrackedPaths counts the non-ignored files and directories compared on
+	// either side, while TotalFiles counts actions
+	TrackedPaths int
+
+	// Filtered counts the paths in SyncPlan.Filtered
+	Filtered int
 }
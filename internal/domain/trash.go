@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// TrashDir is the directory, relative to an endpoint root, that holds files
+// removed by syncs of rules with a trash; it is never synced itself
+const TrashDir = ".syncrules-trash"
+
+// TrashStampLayout formats the timestamp directory of a run in TrashDir
+// Names sort in chronological order
+const TrashStampLayout = "20060102T150405.000Z"
+
+// TrashEntry is a file kept in the trash of an endpoint
+type TrashEntry struct {
+	// Endpoint is the name of the endpoint the file was removed from
+	Endpoint string
+
+	// Stamp is the timestamp directory of the run that removed the file
+	Stamp string
+
+	// TrashedAt is the time of that run
+	TrashedAt time.Time
+
+	// Path is the file's original path relative to the endpoint root
+	Path string
+
+	// Size and ModTime describe the kept version
+	Size    int64
+	ModTime time.Time
+}
+
+// TrashPath returns where the entry is kept, relative to the endpoint root
+func (e TrashEntry) TrashPath() string {
+	return TrashDir + "/" + e.Stamp + "/" + e.Path
+}
@@ -0,0 +1,143 @@
+package lock
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/testutil"
+)
+
+func TestHeartbeat_RefreshedWhileHeld(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	lock, err := NewFileLock(dir)
+	if err != nil {
+		t.Fatalf("NewFileLock failed: %v", err)
+	}
+	lock.SetStaleTimeout(300 * time.Millisecond) // Heartbeat every 100ms
+
+	if err := lock.Acquire("long-sync"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer lock.Release()
+
+	// Run well past the stale timeout
+	time.Sleep(700 * time.Millisecond)
+
+	info, err := lock.readLockInfo()
+	if err != nil {
+		t.Fatalf("failed to read lock info: %v", err)
+	}
+	if time.Since(info.Heartbeat) > 300*time.Millisecond {
+		t.Errorf("heartbeat not refreshed: last %v ago", time.Since(info.Heartbeat))
+	}
+	if !info.Heartbeat.After(info.StartTime) {
+		t.Error("heartbeat should advance past start time")
+	}
+
+	// A competitor judging by heartbeat must not steal the lock
+	competitor, _ := NewFileLock(dir)
+	competitor.SetStaleTimeout(300 * time.Millisecond)
+	if err := competitor.Acquire("competing"); !IsLockError(err) {
+		t.Fatalf("expected LockError for lock with live heartbeat, got %v", err)
+	}
+}
+
+func TestHeartbeat_StopsOnRelease(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	lock, _ := NewFileLock(dir)
+	lock.SetStaleTimeout(90 * time.Millisecond) // Heartbeat every 30ms
+
+	if err := lock.Acquire("rule"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	// A late heartbeat must not resurrect the lock file
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(lock.lockPath); !os.IsNotExist(err) {
+		t.Error("lock file reappeared after release")
+	}
+}
+
+func TestStaleDetection_Heartbeat(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	lock, _ := NewFileLock(dir)
+	lock.SetStaleTimeout(time.Minute)
+
+	hostname, _ := os.Hostname()
+	foreign := "foreign-host-" + testutil.RandomString(8)
+	longAgo := time.Now().Add(-1 * time.Hour)
+
+	tests := []struct {
+		name  string
+		info  LockInfo
+		stale bool
+	}{
+		{
+			name:  "other host, long-running with fresh heartbeat",
+			info:  LockInfo{PID: 12345, Hostname: foreign, StartTime: longAgo, Heartbeat: time.Now()},
+			stale: false,
+		},
+		{
+			name:  "other host, missed heartbeat",
+			info:  LockInfo{PID: 12345, Hostname: foreign, StartTime: longAgo, Heartbeat: longAgo.Add(time.Minute)},
+			stale: true,
+		},
+		{
+			name:  "other host, legacy lock without heartbeat",
+			info:  LockInfo{PID: 12345, Hostname: foreign, StartTime: longAgo},
+			stale: true,
+		},
+		{
+			name:  "same host, live PID with missed heartbeat (PID reused)",
+			info:  LockInfo{PID: os.Getpid(), Hostname: hostname, StartTime: longAgo, Heartbeat: longAgo},
+			stale: true,
+		},
+		{
+			name:  "same host, live PID without heartbeat",
+			info:  LockInfo{PID: os.Getpid(), Hostname: hostname, StartTime: longAgo},
+			stale: false,
+		},
+		{
+			name:  "same host, dead PID with fresh heartbeat",
+			info:  LockInfo{PID: 999999, Hostname: hostname, StartTime: time.Now(), Heartbeat: time.Now()},
+			stale: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := lock.isStale(&tt.info); got != tt.stale {
+			t.Errorf("%s: isStale = %v, want %v", tt.name, got, tt.stale)
+		}
+	}
+}
+
+func TestEffectiveHeartbeatInterval(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	lock, _ := NewFileLock(dir)
+	if got := lock.effectiveHeartbeatInterval(); got != DefaultHeartbeatInterval {
+		t.Errorf("expected default interval %v, got %v", DefaultHeartbeatInterval, got)
+	}
+
+	// Never beat less often than three times per stale timeout
+	lock.SetStaleTimeout(30 * time.Second)
+	if got := lock.effectiveHeartbeatInterval(); got != 10*time.Second {
+		t.Errorf("expected interval capped to 10s, got %v", got)
+	}
+
+	lock.SetHeartbeatInterval(time.Second)
+	if got := lock.effectiveHeartbeatInterval(); got != time.Second {
+		t.Errorf("expected custom interval 1s, got %v", got)
+	}
+}
@@ -1,18 +1,29 @@
 package lock
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
+
+	"github.com/Ning0612/Syncrules/internal/logger"
 )
 
 const (
-	// LockFileName is the name of the lock file
+	// LockFileName is the name of the global lock file
 	LockFileName = ".syncrules.lock"
-	// DefaultStaleTimeout is the default duration after which a lock is considered stale
-	DefaultStaleTimeout = 30 * time.Minute
+	// keyedLockPrefix is the file name prefix of keyed lock files
+	keyedLockPrefix = ".syncrules-"
+	// DefaultStaleTimeout is the default duration without a heartbeat after which a lock is considered stale
+	DefaultStaleTimeout = 2 * time.Minute
+	// DefaultHeartbeatInterval is how often the holder refreshes its heartbeat
+	DefaultHeartbeatInterval = 30 * time.Second
 )
 
 // LockInfo contains metadata about the lock holder
@@ -21,45 +32,127 @@ type LockInfo struct {
 	Hostname  string    `json:"hostname"`
 	StartTime time.Time `json:"start_time"`
 	RuleName  string    `json:"rule_name,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	Heartbeat time.Time `json:"heartbeat,omitempty"`
+}
+
+// lastSeen returns the last time the holder was known to be alive
+// Locks written without a heartbeat fall back to their start time
+func (i *LockInfo) lastSeen() time.Time {
+	if i.Heartbeat.After(i.StartTime) {
+		return i.Heartbeat
+	}
+	return i.StartTime
 }
 
 // FileLock represents a file-based lock for preventing concurrent sync operations
 type FileLock struct {
-	lockPath     string
-	staleTimeout time.Duration
-	info         *LockInfo
+	lockPath          string
+	key               string
+	staleTimeout      time.Duration
+	heartbeatInterval time.Duration
+
+	mu   sync.Mutex // Guards info and read-modify-write of the lock file
+	info *LockInfo
+
+	// Heartbeat goroutine control (nil when not holding the lock)
+	stopHeartbeat chan struct{}
+	heartbeatDone chan struct{}
 }
 
-// NewFileLock creates a new file lock instance
+// NewFileLock creates a new file lock instance guarding the global lock file
 func NewFileLock(lockDir string) (*FileLock, error) {
+	lockDir, err := ensureLockDir(lockDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileLock{
+		lockPath:          filepath.Join(lockDir, LockFileName),
+		staleTimeout:      DefaultStaleTimeout,
+		heartbeatInterval: DefaultHeartbeatInterval,
+	}, nil
+}
+
+// NewKeyedFileLock creates a file lock scoped to a key (e.g., an endpoint)
+// Locks with different keys never block each other
+func NewKeyedFileLock(lockDir, key string) (*FileLock, error) {
+	if key == "" {
+		return nil, fmt.Errorf("lock key cannot be empty")
+	}
+
+	lockDir, err := ensureLockDir(lockDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileLock{
+		lockPath:          filepath.Join(lockDir, KeyFileName(key)),
+		key:               key,
+		staleTimeout:      DefaultStaleTimeout,
+		heartbeatInterval: DefaultHeartbeatInterval,
+	}, nil
+}
+
+// KeyFileName returns the lock file name for a key
+// Keys are hashed so any string maps to a safe file name
+func KeyFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return keyedLockPrefix + hex.EncodeToString(sum[:8]) + ".lock"
+}
+
+// ensureLockDir resolves the lock directory and makes sure it exists
+func ensureLockDir(lockDir string) (string, error) {
 	if lockDir == "" {
 		// Default to user config directory
 		configDir, err := os.UserConfigDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get config dir: %w", err)
+			return "", fmt.Errorf("failed to get config dir: %w", err)
 		}
 		lockDir = filepath.Join(configDir, "syncrules")
 	}
 
 	// Ensure lock directory exists
 	if err := os.MkdirAll(lockDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+		return "", fmt.Errorf("failed to create lock directory: %w", err)
 	}
 
-	return &FileLock{
-		lockPath:     filepath.Join(lockDir, LockFileName),
-		staleTimeout: DefaultStaleTimeout,
-	}, nil
+	return lockDir, nil
+}
+
+// Key returns the key of the lock ("" for the global lock)
+func (l *FileLock) Key() string {
+	return l.key
 }
 
-// SetStaleTimeout sets the duration after which a lock is considered stale
+// SetStaleTimeout sets the duration without a heartbeat after which a lock is considered stale
 func (l *FileLock) SetStaleTimeout(d time.Duration) {
 	l.staleTimeout = d
 }
 
+// SetHeartbeatInterval sets how often the heartbeat is refreshed while holding the lock
+// The effective interval never exceeds a third of the stale timeout
+func (l *FileLock) SetHeartbeatInterval(d time.Duration) {
+	l.heartbeatInterval = d
+}
+
+// effectiveHeartbeatInterval returns the heartbeat period, leaving room for
+// a couple of missed beats before the lock turns stale
+func (l *FileLock) effectiveHeartbeatInterval() time.Duration {
+	interval := l.heartbeatInterval
+	if max := l.staleTimeout / 3; max > 0 && (interval <= 0 || interval > max) {
+		interval = max
+	}
+	return interval
+}
+
 // Acquire attempts to acquire the lock
 // Returns error if lock is already held by another process
+// While held, the lock's heartbeat is refreshed in the background until Release
 func (l *FileLock) Acquire(ruleName string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	// Check if this instance already holds the lock
 	if l.info != nil {
 		// This instance already holds the lock, just update rule name
@@ -77,13 +170,12 @@ func (l *FileLock) Acquire(ruleName string) error {
 	}
 
 	// Check for existing lock
-	existingInfo, err := l.readLockInfo()
+	data, existingInfo, err := l.readLockFile()
 	if err == nil {
 		// Lock file exists, check if it's stale
 		if l.isStale(existingInfo) {
-			// Remove stale lock
-			if err := os.Remove(l.lockPath); err != nil {
-				return fmt.Errorf("failed to remove stale lock: %w", err)
+			if err := l.removeStale(data); err != nil {
+				return err
 			}
 		} else {
 			// Lock is held by another process/instance
@@ -96,11 +188,14 @@ func (l *FileLock) Acquire(ruleName string) error {
 
 	// Create lock info
 	hostname, _ := os.Hostname()
+	now := time.Now()
 	info := &LockInfo{
 		PID:       os.Getpid(),
 		Hostname:  hostname,
-		StartTime: time.Now(),
+		StartTime: now,
 		RuleName:  ruleName,
+		Key:       l.key,
+		Heartbeat: now,
 	}
 
 	// Try to create lock file atomically using O_CREATE|O_EXCL
@@ -130,11 +225,123 @@ func (l *FileLock) Acquire(ruleName string) error {
 	}
 
 	l.info = info
+	l.startHeartbeat()
+	return nil
+}
+
+// removeStale removes a lock file judged stale from its contents data
+// Another process may have taken the stale lock over since it was read, so the
+// file is first moved aside under a name of our own, which only one process
+// can do, and put back if it is no longer the lock that was judged stale
+func (l *FileLock) removeStale(data []byte) error {
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	claimPath := l.lockPath + ".stale-" + hex.EncodeToString(suffix)
+
+	if err := os.Rename(l.lockPath, claimPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil // Someone else removed it; the create below decides
+		}
+		return fmt.Errorf("failed to remove stale lock: %w", err)
+	}
+
+	claimed, err := os.ReadFile(claimPath)
+	if err == nil && !bytes.Equal(claimed, data) {
+		// A fresh lock: restore it unless yet another one was created meanwhile
+		if linkErr := os.Link(claimPath, l.lockPath); linkErr != nil && !os.IsExist(linkErr) {
+			os.Rename(claimPath, l.lockPath)
+		}
+		os.Remove(claimPath)
+		holder, _ := l.readLockInfo()
+		return &LockError{
+			Holder: holder,
+			Reason: "lock acquired by another process during acquisition",
+		}
+	}
+
+	os.Remove(claimPath)
 	return nil
 }
 
+// startHeartbeat launches the goroutine refreshing the heartbeat
+// Caller must hold l.mu
+func (l *FileLock) startHeartbeat() {
+	interval := l.effectiveHeartbeatInterval()
+	if interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	l.stopHeartbeat = stop
+	l.heartbeatDone = done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !l.beat() {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// beat refreshes the heartbeat in the lock file
+// Returns false once the lock is no longer held by this instance
+func (l *FileLock) beat() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	existingInfo, err := l.readLockInfo()
+	if err != nil {
+		// Transient read errors must not kill the heartbeat; a removed file ends it
+		return !os.IsNotExist(err)
+	}
+	if !l.isHeldByThisInstance(existingInfo) {
+		return false
+	}
+
+	existingInfo.Heartbeat = time.Now()
+	if err := l.writeLockInfo(existingInfo); err != nil {
+		// Keep beating; the lock turns stale only after several misses
+		logger.Get().Warn("failed to refresh lock heartbeat",
+			"path", l.lockPath,
+			"error", err)
+	}
+	return true
+}
+
+// stopHeartbeatLoop stops the heartbeat goroutine and waits for it to exit
+// Must be called without holding l.mu, since the goroutine takes it
+func (l *FileLock) stopHeartbeatLoop() {
+	l.mu.Lock()
+	stop, done := l.stopHeartbeat, l.heartbeatDone
+	l.stopHeartbeat, l.heartbeatDone = nil, nil
+	l.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
 // Release releases the lock
 func (l *FileLock) Release() error {
+	l.stopHeartbeatLoop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if l.info == nil {
 		return nil // Not holding lock
 	}
@@ -183,6 +390,11 @@ func (l *FileLock) GetHolder() (*LockInfo, error) {
 // ForceRelease forcibly removes the lock file
 // Use with caution - only when certain the lock holder has crashed
 func (l *FileLock) ForceRelease() error {
+	l.stopHeartbeatLoop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if err := os.Remove(l.lockPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to force remove lock: %w", err)
 	}
@@ -192,50 +404,79 @@ func (l *FileLock) ForceRelease() error {
 
 // readLockInfo reads the lock information from file
 func (l *FileLock) readLockInfo() (*LockInfo, error) {
+	_, info, err := l.readLockFile()
+	return info, err
+}
+
+// readLockFile reads the lock file, returning its raw contents along with
+// the parsed lock information
+func (l *FileLock) readLockFile() ([]byte, *LockInfo, error) {
 	data, err := os.ReadFile(l.lockPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var info LockInfo
 	if err := json.Unmarshal(data, &info); err != nil {
-		return nil, fmt.Errorf("invalid lock file format: %w", err)
+		return nil, nil, fmt.Errorf("invalid lock file format: %w", err)
 	}
 
-	return &info, nil
+	return data, &info, nil
 }
 
 // writeLockInfo writes lock information to file
+// The file is replaced atomically so readers never see a partial write
 func (l *FileLock) writeLockInfo(info *LockInfo) error {
 	data, err := json.MarshalIndent(info, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(l.lockPath, data, 0644)
+
+	// A temp file of its own, so concurrent writers never rename each other's
+	tmp, err := os.CreateTemp(filepath.Dir(l.lockPath), filepath.Base(l.lockPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, l.lockPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
 }
 
-// isStale checks if a lock is stale (process dead)
-// Note: We only consider a lock stale if the process is dead, not based on timeout alone.
-// Timeout is only used as a fallback for cross-host scenarios where we can't check process.
+// isStale checks if a lock is stale
+// Same host: stale if the holder process is dead, or if it missed its heartbeat
+// (the PID may have been reused by an unrelated process).
+// Different host: the process can't be checked, so a missed heartbeat decides.
+// Locks written without heartbeats are judged by process liveness only on the same host,
+// and by start time across hosts.
 func (l *FileLock) isStale(info *LockInfo) bool {
 	hostname, _ := os.Hostname()
+	missedHeartbeat := time.Since(info.lastSeen()) > l.staleTimeout
 
 	// Same host: check if process is still running
 	if info.Hostname == hostname {
 		if !processExists(info.PID) {
 			return true
 		}
-		// Process is alive, lock is NOT stale regardless of timeout
-		return false
-	}
-
-	// Different host: can't check process, use timeout as fallback
-	// This is a conservative approach - only consider stale after timeout
-	if time.Since(info.StartTime) > l.staleTimeout {
-		return true
+		return !info.Heartbeat.IsZero() && missedHeartbeat
 	}
 
-	return false
+	// Different host: rely on the heartbeat
+	return missedHeartbeat
 }
 
 // isHeldByCurrentProcess checks if the lock is held by the current process
@@ -262,13 +503,20 @@ type LockError struct {
 
 func (e *LockError) Error() string {
 	if e.Holder != nil {
-		return fmt.Sprintf("cannot acquire lock: %s (held by PID %d on %s since %s, rule: %s)",
+		msg := fmt.Sprintf("cannot acquire lock: %s (held by PID %d on %s since %s, rule: %s",
 			e.Reason,
 			e.Holder.PID,
 			e.Holder.Hostname,
 			e.Holder.StartTime.Format(time.RFC3339),
 			e.Holder.RuleName,
 		)
+		if e.Holder.Key != "" {
+			msg += ", key: " + e.Holder.Key
+		}
+		if !e.Holder.Heartbeat.IsZero() {
+			msg += ", last heartbeat: " + e.Holder.Heartbeat.Format(time.RFC3339)
+		}
+		return msg + ")"
 	}
 	return fmt.Sprintf("cannot acquire lock: %s", e.Reason)
 }
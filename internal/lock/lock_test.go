@@ -353,6 +353,92 @@ func TestStaleDetection_ProcessDead(t *testing.T) {
 	}
 }
 
+// writeStaleLock writes a lock held by a dead process and returns its contents
+func writeStaleLock(t *testing.T, lock *FileLock) []byte {
+	t.Helper()
+	hostname, _ := os.Hostname()
+	staleInfo := &LockInfo{
+		PID:       999999, // Unlikely to exist
+		Hostname:  hostname,
+		StartTime: time.Now().Add(-1 * time.Hour),
+		RuleName:  "stale-test",
+	}
+	if err := lock.writeLockInfo(staleInfo); err != nil {
+		t.Fatalf("failed to write stale lock info: %v", err)
+	}
+	data, err := os.ReadFile(lock.lockPath)
+	if err != nil {
+		t.Fatalf("failed to read stale lock: %v", err)
+	}
+	return data
+}
+
+func TestStaleTakeover_KeepsFreshLock(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	first, _ := NewFileLock(dir)
+	second, _ := NewFileLock(dir)
+	stale := writeStaleLock(t, first)
+
+	// Both saw the stale lock; the first takes it over before the second acts
+	if err := first.Acquire("first"); err != nil {
+		t.Fatalf("first Acquire failed: %v", err)
+	}
+	if err := second.removeStale(stale); !IsLockError(err) {
+		t.Fatalf("expected LockError for a lock taken over meanwhile, got %v", err)
+	}
+
+	holder, err := first.GetHolder()
+	if err != nil || holder.RuleName != "first" {
+		t.Fatalf("expected the first lock to survive, got %+v (%v)", holder, err)
+	}
+	if err := first.Release(); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, LockFileName+".*")); len(matches) != 0 {
+		t.Errorf("expected no leftover files, got %v", matches)
+	}
+}
+
+func TestStaleTakeover_Concurrent(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	setup, _ := NewFileLock(dir)
+	writeStaleLock(t, setup)
+
+	const goroutines = 10
+	var wg sync.WaitGroup
+	locks := make([]*FileLock, goroutines)
+	acquired := make([]bool, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			lock, err := NewFileLock(dir)
+			if err != nil {
+				return
+			}
+			locks[idx] = lock
+			acquired[idx] = lock.Acquire("concurrent-test") == nil
+		}(i)
+	}
+	wg.Wait()
+
+	// Holders release only after everyone tried, so exactly one may win
+	count := 0
+	for i, ok := range acquired {
+		if ok {
+			count++
+			locks[i].Release()
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected exactly 1 acquire of the stale lock, got %d", count)
+	}
+}
+
 func TestStaleDetection_LongRunning(t *testing.T) {
 	dir, cleanup := testutil.TempDir(t)
 	defer cleanup()
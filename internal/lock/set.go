@@ -0,0 +1,121 @@
+package lock
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LockSet holds several keyed locks as one unit
+// Keys are always acquired in sorted order, so two sets sharing
+// keys cannot deadlock; a partial acquisition is rolled back
+type LockSet struct {
+	locks []*FileLock
+}
+
+// NewLockSet creates a lock set for the given keys (duplicates are ignored)
+func NewLockSet(lockDir string, keys []string) (*LockSet, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("lock set requires at least one key")
+	}
+
+	unique := make(map[string]bool, len(keys))
+	var sorted []string
+	for _, key := range keys {
+		if !unique[key] {
+			unique[key] = true
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+
+	set := &LockSet{}
+	for _, key := range sorted {
+		l, err := NewKeyedFileLock(lockDir, key)
+		if err != nil {
+			return nil, err
+		}
+		set.locks = append(set.locks, l)
+	}
+	return set, nil
+}
+
+// SetStaleTimeout sets the stale timeout of every lock in the set
+func (s *LockSet) SetStaleTimeout(d time.Duration) {
+	for _, l := range s.locks {
+		l.SetStaleTimeout(d)
+	}
+}
+
+// SetHeartbeatInterval sets the heartbeat interval of every lock in the set
+func (s *LockSet) SetHeartbeatInterval(d time.Duration) {
+	for _, l := range s.locks {
+		l.SetHeartbeatInterval(d)
+	}
+}
+
+// Keys returns the keys of the set in acquisition order
+func (s *LockSet) Keys() []string {
+	keys := make([]string, len(s.locks))
+	for i, l := range s.locks {
+		keys[i] = l.key
+	}
+	return keys
+}
+
+// Acquire acquires every lock in the set or none of them
+func (s *LockSet) Acquire(ruleName string) error {
+	for i, l := range s.locks {
+		if err := l.Acquire(ruleName); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				s.locks[j].Release()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Release releases every lock in the set, returning the last error
+func (s *LockSet) Release() error {
+	var lastErr error
+	for i := len(s.locks) - 1; i >= 0; i-- {
+		if err := s.locks[i].Release(); err != nil {
+			lastErr = fmt.Errorf("key %s: %w", s.locks[i].key, err)
+		}
+	}
+	return lastErr
+}
+
+// IsLocked checks if any lock in the set is currently held
+func (s *LockSet) IsLocked() bool {
+	for _, l := range s.locks {
+		if l.IsLocked() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetHolders returns the holders of the locks in the set that are currently held
+func (s *LockSet) GetHolders() []*LockInfo {
+	var holders []*LockInfo
+	for _, l := range s.locks {
+		if info, err := l.GetHolder(); err == nil {
+			holders = append(holders, info)
+		}
+	}
+	return holders
+}
+
+// ForceRelease forcibly removes every lock file of the set
+// Use with caution - only when certain the lock holders have crashed
+func (s *LockSet) ForceRelease() error {
+	var lastErr error
+	for _, l := range s.locks {
+		if err := l.ForceRelease(); err != nil {
+			lastErr = fmt.Errorf("key %s: %w", l.key, err)
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,179 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/testutil"
+)
+
+func TestKeyedLocks_Independent(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	lockA, err := NewKeyedFileLock(dir, "local:/data/a")
+	if err != nil {
+		t.Fatalf("NewKeyedFileLock failed: %v", err)
+	}
+	lockB, err := NewKeyedFileLock(dir, "local:/data/b")
+	if err != nil {
+		t.Fatalf("NewKeyedFileLock failed: %v", err)
+	}
+
+	if err := lockA.Acquire("rule-a"); err != nil {
+		t.Fatalf("Acquire A failed: %v", err)
+	}
+	defer lockA.Release()
+
+	// A different key must not be blocked
+	if err := lockB.Acquire("rule-b"); err != nil {
+		t.Fatalf("Acquire B should not be blocked by A: %v", err)
+	}
+	defer lockB.Release()
+
+	// The same key must be blocked
+	lockA2, _ := NewKeyedFileLock(dir, "local:/data/a")
+	err = lockA2.Acquire("rule-c")
+	if !IsLockError(err) {
+		t.Fatalf("expected LockError for same key, got %v", err)
+	}
+
+	holder, err := lockA.GetHolder()
+	if err != nil {
+		t.Fatalf("GetHolder failed: %v", err)
+	}
+	if holder.Key != "local:/data/a" {
+		t.Errorf("expected key recorded in lock info, got %q", holder.Key)
+	}
+}
+
+func TestNewKeyedFileLock_EmptyKey(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	if _, err := NewKeyedFileLock(dir, ""); err == nil {
+		t.Error("expected error for empty key")
+	}
+}
+
+func TestKeyFileName(t *testing.T) {
+	name := KeyFileName("gdrive:drive:/backup/../etc")
+	if filepath.Base(name) != name {
+		t.Errorf("key file name must not contain path separators: %q", name)
+	}
+	if name == LockFileName {
+		t.Error("keyed lock must not share the global lock file")
+	}
+	if KeyFileName("a") == KeyFileName("b") {
+		t.Error("different keys must map to different files")
+	}
+}
+
+func TestLockSet_AllOrNothing(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	// Another process holds key "b"
+	other, _ := NewKeyedFileLock(dir, "b")
+	if err := other.Acquire("other-rule"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer other.Release()
+
+	set, err := NewLockSet(dir, []string{"c", "a", "b", "a"})
+	if err != nil {
+		t.Fatalf("NewLockSet failed: %v", err)
+	}
+	if keys := set.Keys(); len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Fatalf("expected sorted unique keys, got %v", keys)
+	}
+
+	if err := set.Acquire("rule"); !IsLockError(err) {
+		t.Fatalf("expected LockError, got %v", err)
+	}
+
+	// The already-acquired key "a" must have been rolled back
+	lockA, _ := NewKeyedFileLock(dir, "a")
+	if lockA.IsLocked() {
+		t.Error("partial acquisition was not rolled back")
+	}
+
+	holders := set.GetHolders()
+	if len(holders) != 1 || holders[0].RuleName != "other-rule" {
+		t.Errorf("expected only the other rule as holder, got %+v", holders)
+	}
+}
+
+func TestLockSet_AcquireRelease(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	set, err := NewLockSet(dir, []string{"src", "dst"})
+	if err != nil {
+		t.Fatalf("NewLockSet failed: %v", err)
+	}
+	if err := set.Acquire("rule"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if !set.IsLocked() {
+		t.Error("set should be locked after acquire")
+	}
+	if err := set.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if set.IsLocked() {
+		t.Error("set should not be locked after release")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected no lock files after release, found %d", len(entries))
+	}
+}
+
+func TestLockSet_StaleAndForceReleasePerKey(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	// Dead holder on "a", living holder on "b"
+	hostname, _ := os.Hostname()
+	stale, _ := NewKeyedFileLock(dir, "a")
+	if err := stale.writeLockInfo(&LockInfo{
+		PID:       999999, // Unlikely to exist
+		Hostname:  hostname,
+		StartTime: time.Now().Add(-1 * time.Hour),
+		RuleName:  "crashed",
+		Key:       "a",
+	}); err != nil {
+		t.Fatalf("failed to write stale lock info: %v", err)
+	}
+	living, _ := NewKeyedFileLock(dir, "b")
+	if err := living.Acquire("running"); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// The stale key alone can be taken over
+	setA, _ := NewLockSet(dir, []string{"a"})
+	if err := setA.Acquire("new"); err != nil {
+		t.Fatalf("stale key should be acquirable: %v", err)
+	}
+	setA.Release()
+
+	// Force releasing "b" leaves other keys alone
+	setB, _ := NewLockSet(dir, []string{"b"})
+	other, _ := NewKeyedFileLock(dir, "c")
+	other.Acquire("unrelated")
+	defer other.Release()
+
+	if err := setB.ForceRelease(); err != nil {
+		t.Fatalf("ForceRelease failed: %v", err)
+	}
+	if living.IsLocked() {
+		t.Error("key b should be released")
+	}
+	if !other.IsLocked() {
+		t.Error("key c should be unaffected by force release of b")
+	}
+}
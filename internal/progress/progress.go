@@ -23,6 +23,38 @@ type Reporter interface {
 	OverallProgress(filesCompleted int, bytesCompleted int64)
 }
 
+// Updater receives the byte count of an ongoing transfer
+type Updater interface {
+	Update(bytesTransferred int64)
+}
+
+// TransferReporter reports progress of a single transfer among several in flight
+type TransferReporter interface {
+	Updater
+	// Complete marks the transfer as complete
+	Complete()
+	// Error reports an error on the transfer
+	Error(err error)
+}
+
+// ConcurrentReporter is implemented by reporters that can track several transfers at once
+type ConcurrentReporter interface {
+	Reporter
+	// StartTransfer begins tracking a transfer independently of others in flight
+	StartTransfer(path string, totalBytes int64) TransferReporter
+}
+
+// StartTransfer begins tracking a transfer on any reporter
+// Reporters without concurrent support are driven through their single current transfer,
+// so callers must not run transfers concurrently on them
+func StartTransfer(r Reporter, path string, totalBytes int64) TransferReporter {
+	if cr, ok := r.(ConcurrentReporter); ok {
+		return cr.StartTransfer(path, totalBytes)
+	}
+	r.Start(path, totalBytes)
+	return r
+}
+
 // Callback is a function that receives progress updates
 type Callback func(update Update)
 
@@ -62,6 +94,7 @@ type CallbackReporter struct {
 	bytesTotal     int64
 	filesCompleted int
 	bytesCompleted int64
+	inFlightBytes  int64 // Bytes transferred so far by concurrent transfers
 	startTime      time.Time
 }
 
@@ -199,15 +232,134 @@ func (r *CallbackReporter) OverallProgress(filesCompleted int, bytesCompleted in
 	}
 }
 
+// StartTransfer begins tracking one of several concurrent transfers
+func (r *CallbackReporter) StartTransfer(path string, totalBytes int64) TransferReporter {
+	t := &callbackTransfer{
+		reporter:  r,
+		path:      path,
+		total:     totalBytes,
+		startTime: time.Now(),
+	}
+
+	r.mu.Lock()
+	update := Update{
+		Type:           UpdateStart,
+		CurrentFile:    path,
+		CurrentTotal:   totalBytes,
+		FilesCompleted: r.filesCompleted,
+		FilesTotal:     r.filesTotal,
+		BytesCompleted: r.bytesCompleted + r.inFlightBytes,
+		BytesTotal:     r.bytesTotal,
+	}
+	callback := r.callback
+	r.mu.Unlock()
+
+	if callback != nil {
+		callback(update)
+	}
+	return t
+}
+
+// callbackTransfer tracks a single transfer started with StartTransfer
+type callbackTransfer struct {
+	reporter    *CallbackReporter
+	path        string
+	total       int64
+	transferred int64
+	startTime   time.Time
+}
+
+// Update reports progress on this transfer
+func (t *callbackTransfer) Update(bytesTransferred int64) {
+	r := t.reporter
+	r.mu.Lock()
+	r.inFlightBytes += bytesTransferred - t.transferred
+	t.transferred = bytesTransferred
+
+	var bytesPerSecond float64
+	elapsed := time.Since(t.startTime).Seconds()
+	if elapsed > 0 {
+		bytesPerSecond = float64(bytesTransferred) / elapsed
+	}
+
+	update := Update{
+		Type:           UpdateProgress,
+		CurrentFile:    t.path,
+		CurrentBytes:   bytesTransferred,
+		CurrentTotal:   t.total,
+		FilesCompleted: r.filesCompleted,
+		FilesTotal:     r.filesTotal,
+		BytesCompleted: r.bytesCompleted + r.inFlightBytes,
+		BytesTotal:     r.bytesTotal,
+		BytesPerSecond: bytesPerSecond,
+	}
+	callback := r.callback
+	r.mu.Unlock()
+
+	if callback != nil {
+		callback(update)
+	}
+}
+
+// Complete marks this transfer as complete
+func (t *callbackTransfer) Complete() {
+	r := t.reporter
+	r.mu.Lock()
+	r.inFlightBytes -= t.transferred
+	r.filesCompleted++
+	r.bytesCompleted += t.total
+
+	update := Update{
+		Type:           UpdateComplete,
+		CurrentFile:    t.path,
+		CurrentBytes:   t.total,
+		CurrentTotal:   t.total,
+		FilesCompleted: r.filesCompleted,
+		FilesTotal:     r.filesTotal,
+		BytesCompleted: r.bytesCompleted + r.inFlightBytes,
+		BytesTotal:     r.bytesTotal,
+	}
+	callback := r.callback
+	r.mu.Unlock()
+
+	if callback != nil {
+		callback(update)
+	}
+}
+
+// Error reports an error on this transfer
+func (t *callbackTransfer) Error(err error) {
+	r := t.reporter
+	r.mu.Lock()
+	r.inFlightBytes -= t.transferred
+	t.transferred = 0
+
+	update := Update{
+		Type:           UpdateError,
+		CurrentFile:    t.path,
+		FilesCompleted: r.filesCompleted,
+		FilesTotal:     r.filesTotal,
+		BytesCompleted: r.bytesCompleted + r.inFlightBytes,
+		BytesTotal:     r.bytesTotal,
+		Error:          err,
+	}
+	callback := r.callback
+	r.mu.Unlock()
+
+	if callback != nil {
+		callback(update)
+	}
+}
+
 // ProgressReader wraps an io.Reader to track read progress
 type ProgressReader struct {
 	reader      io.Reader
-	reporter    Reporter
+	reporter    Updater
 	transferred int64
 }
 
 // NewProgressReader creates a new progress-tracking reader
-func NewProgressReader(r io.Reader, reporter Reporter) *ProgressReader {
+func NewProgressReader(r io.Reader, reporter Updater) *ProgressReader {
 	return &ProgressReader{
 		reader:   r,
 		reporter: reporter,
@@ -229,12 +381,12 @@ func (pr *ProgressReader) Read(p []byte) (n int, err error) {
 // ProgressWriter wraps an io.Writer to track write progress
 type ProgressWriter struct {
 	writer      io.Writer
-	reporter    Reporter
+	reporter    Updater
 	transferred int64
 }
 
 // NewProgressWriter creates a new progress-tracking writer
-func NewProgressWriter(w io.Writer, reporter Reporter) *ProgressWriter {
+func NewProgressWriter(w io.Writer, reporter Updater) *ProgressWriter {
 	return &ProgressWriter{
 		writer:   w,
 		reporter: reporter,
@@ -262,6 +414,9 @@ func (NullReporter) Complete()                                            {}
 func (NullReporter) Error(err error)                                      {}
 func (NullReporter) SetTotal(totalFiles int, totalBytes int64)            {}
 func (NullReporter) OverallProgress(filesCompleted int, bytesCompleted int64) {}
+func (NullReporter) StartTransfer(path string, totalBytes int64) TransferReporter {
+	return NullReporter{}
+}
 
 // FormatBytes formats bytes into human-readable string
 func FormatBytes(bytes int64) string {
@@ -362,3 +362,81 @@ func TestNullReporter(t *testing.T) {
 	nr.Error(io.EOF)
 	nr.OverallProgress(5, 500)
 }
+
+// TestCallbackReporter_ConcurrentTransfers tests that interleaved transfers are tracked independently
+func TestCallbackReporter_ConcurrentTransfers(t *testing.T) {
+	var mu sync.Mutex
+	var updates []Update
+	reporter := NewCallbackReporter(func(u Update) {
+		mu.Lock()
+		updates = append(updates, u)
+		mu.Unlock()
+	})
+	reporter.SetTotal(2, 300)
+
+	a := reporter.StartTransfer("a.txt", 100)
+	b := reporter.StartTransfer("b.txt", 200)
+
+	a.Update(50)
+	b.Update(120)
+	a.Update(100)
+	a.Complete()
+	b.Update(200)
+	b.Complete()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Each update must name its own transfer
+	for _, u := range updates {
+		if u.Type == UpdateProgress {
+			if (u.CurrentFile == "a.txt" && u.CurrentTotal != 100) || (u.CurrentFile == "b.txt" && u.CurrentTotal != 200) {
+				t.Errorf("update mixed up transfers: %+v", u)
+			}
+		}
+	}
+
+	// Overall bytes count every in-flight transfer exactly once
+	expected := []int64{0, 0, 50, 170, 220, 220, 300, 300}
+	if len(updates) != len(expected) {
+		t.Fatalf("expected %d updates, got %d", len(expected), len(updates))
+	}
+	for i, u := range updates {
+		if u.BytesCompleted != expected[i] {
+			t.Errorf("update %d (%s %v): BytesCompleted = %d, want %d", i, u.CurrentFile, u.Type, u.BytesCompleted, expected[i])
+		}
+	}
+
+	final := updates[len(updates)-1]
+	if final.FilesCompleted != 2 || final.BytesCompleted != 300 {
+		t.Errorf("unexpected final update: %+v", final)
+	}
+}
+
+// TestStartTransfer_FallbackReporter tests that plain reporters are driven through Start
+func TestStartTransfer_FallbackReporter(t *testing.T) {
+	var started string
+	reporter := &recordingReporter{onStart: func(path string) { started = path }}
+
+	transfer := StartTransfer(reporter, "file.txt", 10)
+	if started != "file.txt" {
+		t.Errorf("expected Start to be called for plain reporter, got %q", started)
+	}
+	if transfer != Reporter(reporter) {
+		t.Error("expected plain reporter to track the transfer itself")
+	}
+}
+
+// recordingReporter is a Reporter without concurrent transfer support
+type recordingReporter struct {
+	onStart func(path string)
+}
+
+func (r *recordingReporter) Start(path string, totalBytes int64) {
+	r.onStart(path)
+}
+func (r *recordingReporter) Update(bytesTransferred int64)                            {}
+func (r *recordingReporter) Complete()                                                {}
+func (r *recordingReporter) Error(err error)                                          {}
+func (r *recordingReporter) SetTotal(totalFiles int, totalBytes int64)                {}
+func (r *recordingReporter) OverallProgress(filesCompleted int, bytesCompleted int64) {}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule computes when a rule runs next
+type Schedule interface {
+	// Next returns the next run time after t
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule runs a rule at a fixed interval
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// cronParser accepts exactly the five standard fields; descriptors such as
+// "@daily" or "@every 1h" are not part of the documented syntax
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// CronSchedule runs a rule at the times matched by a cron expression
+type CronSchedule struct {
+	expr     string
+	location *time.Location
+	schedule cron.Schedule
+}
+
+// ParseCron parses a standard 5-field cron expression (minute, hour,
+// day of month, month, day of week) evaluated in the given IANA timezone
+// An empty timezone means the local timezone; the timezone cannot be given
+// inside the expression with a TZ= or CRON_TZ= prefix
+func ParseCron(expr, timezone string) (*CronSchedule, error) {
+	location := time.Local
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+	}
+
+	trimmed := strings.TrimSpace(expr)
+	if strings.HasPrefix(trimmed, "TZ=") || strings.HasPrefix(trimmed, "CRON_TZ=") {
+		return nil, fmt.Errorf("invalid cron expression %q: set the timezone with schedule.timezone instead", expr)
+	}
+
+	schedule, err := cronParser.Parse(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+
+	return &CronSchedule{
+		expr:     expr,
+		location: location,
+		schedule: schedule,
+	}, nil
+}
+
+// Next returns the next fire time after t, in t's location
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.In(s.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.In(t.Location())
+}
+
+// String returns the cron expression
+func (s *CronSchedule) String() string {
+	return s.expr
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := []struct {
+		expr     string
+		timezone string
+	}{
+		{"", ""},
+		{"* * * *", ""},     // Too few fields
+		{"0 0 2 * * *", ""}, // Seconds field is not standard
+		{"61 * * * *", ""},  // Minute out of range
+		{"@daily", ""},      // Descriptors are not standard
+		{"@every 1h", ""},
+		{"TZ=UTC 0 2 * * *", ""}, // Timezone belongs in its own field
+		{"CRON_TZ=Asia/Taipei 0 2 * * *", ""},
+		{"0 2 * * *", "Mars/Olympus"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseCron(tt.expr, tt.timezone); err == nil {
+			t.Errorf("ParseCron(%q, %q) expected error", tt.expr, tt.timezone)
+		}
+	}
+}
+
+func TestCronSchedule_NightlyInTimezone(t *testing.T) {
+	sched, err := ParseCron("0 2 * * *", "Asia/Taipei")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+
+	// 2026-03-10 20:00 UTC is 2026-03-11 04:00 in Taipei (UTC+8)
+	now := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+	next := sched.Next(now)
+
+	// Next 02:00 Taipei is 2026-03-12 02:00 = 2026-03-11 18:00 UTC
+	expected := time.Date(2026, 3, 11, 18, 0, 0, 0, time.UTC)
+	if !next.Equal(expected) {
+		t.Errorf("Next = %v, want %v", next, expected)
+	}
+	if next.Location() != time.UTC {
+		t.Errorf("Expected next time in caller's location, got %v", next.Location())
+	}
+}
+
+func TestCronSchedule_WeekdayOfficeHours(t *testing.T) {
+	sched, err := ParseCron("*/15 9-17 * * 1-5", "UTC")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+
+	tests := []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		// Monday morning during office hours
+		{time.Date(2026, 3, 9, 9, 7, 0, 0, time.UTC), time.Date(2026, 3, 9, 9, 15, 0, 0, time.UTC)},
+		// Monday evening rolls over to Tuesday 09:00
+		{time.Date(2026, 3, 9, 17, 50, 0, 0, time.UTC), time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)},
+		// Friday evening skips the weekend
+		{time.Date(2026, 3, 13, 18, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if next := sched.Next(tt.now); !next.Equal(tt.expected) {
+			t.Errorf("Next(%v) = %v, want %v", tt.now, next, tt.expected)
+		}
+	}
+}
+
+func TestMultiScheduler_CronRule(t *testing.T) {
+	runner := &countingSyncRunner{}
+
+	scheduler, err := NewMultiScheduler(Config{
+		Rules:     []string{"nightly"},
+		Schedules: map[string]RuleSchedule{"nightly": {Cron: "0 2 * * *", Timezone: "UTC"}},
+	}, runner)
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+
+	before := time.Now()
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer scheduler.Stop()
+
+	status := scheduler.Status()
+	if len(status.Rules) != 1 {
+		t.Fatalf("Expected status for 1 rule, got %d", len(status.Rules))
+	}
+	next := status.Rules[0].NextRunTime.UTC()
+	if next.Hour() != 2 || next.Minute() != 0 || !next.After(before) || next.Sub(before) > 24*time.Hour {
+		t.Errorf("Unexpected next run for nightly rule: %v", next)
+	}
+
+	// Malformed expressions are rejected when the scheduler is created
+	_, err = NewMultiScheduler(Config{
+		Rules:     []string{"bad"},
+		Schedules: map[string]RuleSchedule{"bad": {Cron: "every night"}},
+	}, runner)
+	if err == nil {
+		t.Error("Expected error for malformed cron expression")
+	}
+}
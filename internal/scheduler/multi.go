@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MultiScheduler runs each rule on its own schedule
+// Every rule keeps a separate next-run time, using its entry in
+// Config.Schedules (interval or cron) or else Config.Interval
+type MultiScheduler struct {
+	config    Config
+	runner    SyncRunner
+	schedules map[string]Schedule
+	tracker   *ruleTracker
+
+	// Runtime state
+	mu          sync.RWMutex
+	running     bool
+	stopped     bool      // Track if stopped to prevent restart
+	stopOnce    sync.Once // Ensure Stop() is idempotent
+	closeOnce   sync.Once // Ensure stoppedChan is closed exactly once
+	stopChan    chan struct{}
+	stoppedChan chan struct{}
+}
+
+// NewMultiScheduler creates a new per-rule scheduler
+func NewMultiScheduler(config Config, runner SyncRunner) (*MultiScheduler, error) {
+	return newMultiScheduler(config, runner, newRuleTracker())
+}
+
+// newMultiScheduler creates a per-rule scheduler reporting into the given tracker
+func newMultiScheduler(config Config, runner SyncRunner, tracker *ruleTracker) (*MultiScheduler, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("sync runner cannot be nil")
+	}
+
+	rules := config.Rules
+	if len(rules) == 0 {
+		// No specific rules configured - runner should handle this
+		// by running all enabled rules
+		rules = []string{""}
+	}
+
+	schedules := make(map[string]Schedule, len(rules))
+	for _, name := range rules {
+		sched := config.Schedules[name]
+		if sched.Cron != "" {
+			cronSched, err := ParseCron(sched.Cron, sched.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", name, err)
+			}
+			schedules[name] = cronSched
+			continue
+		}
+
+		interval := config.Interval
+		if sched.Interval > 0 {
+			interval = sched.Interval
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval for rule %q must be positive, got %v", name, interval)
+		}
+		schedules[name] = intervalSchedule(interval)
+	}
+
+	return &MultiScheduler{
+		config:      config,
+		runner:      runner,
+		schedules:   schedules,
+		tracker:     tracker,
+		stopChan:    make(chan struct{}),
+		stoppedChan: make(chan struct{}),
+	}, nil
+}
+
+// Start begins the scheduling loop
+func (s *MultiScheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("scheduler is already running")
+	}
+
+	if s.stopped {
+		return fmt.Errorf("scheduler cannot be restarted after stop")
+	}
+
+	now := time.Now()
+	next := make(map[string]time.Time, len(s.schedules))
+	for name, sched := range s.schedules {
+		next[name] = sched.Next(now)
+		s.tracker.setNext(name, next[name])
+	}
+
+	s.running = true
+
+	go s.run(ctx, next)
+
+	return nil
+}
+
+// run is the main scheduling loop
+// next is owned by this goroutine; a rule's entry is zero while it runs
+func (s *MultiScheduler) run(ctx context.Context, next map[string]time.Time) {
+	// Ensure stoppedChan is closed exactly once and stopped flag is set
+	defer s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.stopped = true
+		s.running = false
+		s.mu.Unlock()
+		close(s.stoppedChan)
+	})
+
+	// Runs in flight finish before the scheduler reports it stopped
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+	done := make(chan string, len(next))
+
+	timer := time.NewTimer(time.Until(earliest(next)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-timer.C:
+			s.runDue(ctx, next, done, &inflight)
+			timer.Reset(time.Until(earliest(next)))
+		case name := <-done:
+			// Schedule from completion so a slow run never queues up back-to-back runs
+			next[name] = s.schedules[name].Next(time.Now())
+			s.tracker.setNext(name, next[name])
+			timer.Reset(time.Until(earliest(next)))
+		}
+	}
+}
+
+// runDue starts every rule whose next-run time has passed, each in its own
+// goroutine so a long sync does not hold up other rules' schedules
+// A started rule is not due again until it reports its completion on done
+func (s *MultiScheduler) runDue(ctx context.Context, next map[string]time.Time, done chan<- string, inflight *sync.WaitGroup) {
+	now := time.Now()
+
+	var due []string
+	for name, t := range next {
+		if !t.IsZero() && !t.After(now) {
+			due = append(due, name)
+		}
+	}
+	sort.Strings(due)
+
+	for _, name := range due {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		default:
+		}
+
+		next[name] = time.Time{}
+		inflight.Add(1)
+		go func(name string) {
+			defer inflight.Done()
+			start := time.Now()
+			err := s.runner.RunSync(ctx, name)
+			s.tracker.record(name, start, err)
+			done <- name
+		}(name)
+	}
+}
+
+// earliest returns the soonest time in next
+// Rules running or never firing again (zero time) are skipped; with none left,
+// a far-future time keeps the loop idle until stopped
+func earliest(next map[string]time.Time) time.Time {
+	var min time.Time
+	for _, t := range next {
+		if t.IsZero() {
+			continue
+		}
+		if min.IsZero() || t.Before(min) {
+			min = t
+		}
+	}
+	if min.IsZero() {
+		return time.Now().Add(24 * time.Hour)
+	}
+	return min
+}
+
+// Stop gracefully stops the scheduler
+func (s *MultiScheduler) Stop() error {
+	s.mu.RLock()
+	if !s.running {
+		s.mu.RUnlock()
+		return fmt.Errorf("scheduler is not running")
+	}
+	s.mu.RUnlock()
+
+	// Use sync.Once to ensure stop channel is closed only once
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+
+	// Wait for scheduler to stop
+	<-s.stoppedChan
+
+	// Mark as stopped to prevent restart
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Status returns the current scheduler status with per-rule details
+func (s *MultiScheduler) Status() *Status {
+	s.mu.RLock()
+	running := s.running
+	s.mu.RUnlock()
+
+	return s.tracker.status(running)
+}
@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func countCalls(calls []string, rule string) int {
+	n := 0
+	for _, c := range calls {
+		if c == rule {
+			n++
+		}
+	}
+	return n
+}
+
+func TestNewMultiScheduler_Validation(t *testing.T) {
+	runner := &countingSyncRunner{}
+
+	if _, err := NewMultiScheduler(Config{Interval: time.Second}, nil); err == nil {
+		t.Error("Expected error for nil runner")
+	}
+
+	// A rule without its own interval needs the default
+	config := Config{
+		Rules:     []string{"fast", "default"},
+		Schedules: map[string]RuleSchedule{"fast": {Interval: time.Second}},
+	}
+	if _, err := NewMultiScheduler(config, runner); err == nil {
+		t.Error("Expected error for rule without any interval")
+	}
+
+	config.Interval = time.Minute
+	if _, err := NewMultiScheduler(config, runner); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestMultiScheduler_PerRuleIntervals(t *testing.T) {
+	runner := &countingSyncRunner{}
+
+	scheduler, err := NewMultiScheduler(Config{
+		Interval:  time.Hour,
+		Rules:     []string{"fast", "slow"},
+		Schedules: map[string]RuleSchedule{"fast": {Interval: 50 * time.Millisecond}},
+	}, runner)
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer scheduler.Stop()
+
+	calls := waitForCalls(runner, 3, 2*time.Second)
+	if countCalls(calls, "fast") < 3 {
+		t.Errorf("Expected fast rule to run repeatedly, got %v", calls)
+	}
+	if countCalls(calls, "slow") != 0 {
+		t.Errorf("Expected slow rule to wait for its own interval, got %v", calls)
+	}
+
+	status := scheduler.Status()
+	if len(status.Rules) != 2 {
+		t.Fatalf("Expected status for 2 rules, got %d", len(status.Rules))
+	}
+	fast, slow := status.Rules[0], status.Rules[1]
+	if fast.Name != "fast" || slow.Name != "slow" {
+		t.Fatalf("Expected rules sorted by name, got %s, %s", fast.Name, slow.Name)
+	}
+	if fast.TotalRuns < 3 || fast.LastRunTime.IsZero() {
+		t.Errorf("Unexpected fast rule status: %+v", fast)
+	}
+	if slow.TotalRuns != 0 || !slow.LastRunTime.IsZero() {
+		t.Errorf("Unexpected slow rule status: %+v", slow)
+	}
+	if time.Until(slow.NextRunTime) < 59*time.Minute {
+		t.Errorf("Expected slow rule next run about an hour away, got %v", slow.NextRunTime)
+	}
+	if !status.NextRunTime.Equal(fast.NextRunTime) {
+		t.Errorf("Expected overall next run to be the earliest rule's, got %v", status.NextRunTime)
+	}
+}
+
+func TestMultiScheduler_PerRuleErrors(t *testing.T) {
+	runner := &countingSyncRunner{fail: map[string]bool{"broken": true}}
+
+	scheduler, err := NewMultiScheduler(Config{
+		Interval: 50 * time.Millisecond,
+		Rules:    []string{"broken", "healthy"},
+	}, runner)
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	waitForCalls(runner, 4, 2*time.Second)
+	scheduler.Stop()
+
+	status := scheduler.Status()
+	if status.Running {
+		t.Error("Scheduler should not be running after stop")
+	}
+	for _, rs := range status.Rules {
+		switch rs.Name {
+		case "broken":
+			if rs.FailedRuns == 0 || rs.SuccessfulRuns != 0 || rs.LastError == "" {
+				t.Errorf("Unexpected broken rule status: %+v", rs)
+			}
+		case "healthy":
+			if rs.SuccessfulRuns == 0 || rs.FailedRuns != 0 || rs.LastError != "" {
+				t.Errorf("Unexpected healthy rule status: %+v", rs)
+			}
+		}
+	}
+	if status.TotalRuns != status.SuccessfulRuns+status.FailedRuns {
+		t.Errorf("Aggregate counters inconsistent: %+v", status)
+	}
+}
+
+// blockingSyncRunner holds runs of the slow rule until released and records
+// whether any rule ever ran twice at once
+type blockingSyncRunner struct {
+	countingSyncRunner
+	release chan struct{}
+
+	mu      sync.Mutex
+	active  map[string]bool
+	overlap bool
+}
+
+func (r *blockingSyncRunner) RunSync(ctx context.Context, ruleName string) error {
+	r.mu.Lock()
+	if r.active[ruleName] {
+		r.overlap = true
+	}
+	r.active[ruleName] = true
+	r.mu.Unlock()
+
+	err := r.countingSyncRunner.RunSync(ctx, ruleName)
+	if ruleName == "slow" {
+		<-r.release
+	}
+
+	r.mu.Lock()
+	r.active[ruleName] = false
+	r.mu.Unlock()
+	return err
+}
+
+func TestMultiScheduler_RulesRunIndependently(t *testing.T) {
+	runner := &blockingSyncRunner{release: make(chan struct{}), active: make(map[string]bool)}
+
+	scheduler, err := NewMultiScheduler(Config{
+		Interval: 30 * time.Millisecond,
+		Rules:    []string{"fast", "slow"},
+	}, runner)
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	// fast keeps its schedule while slow's first run is still going
+	deadline := time.Now().Add(2 * time.Second)
+	for countCalls(runner.Calls(), "fast") < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	calls := runner.Calls()
+	if countCalls(calls, "fast") < 4 {
+		t.Errorf("Expected fast to keep running during slow's sync, got %v", calls)
+	}
+	if countCalls(calls, "slow") != 1 {
+		t.Errorf("Expected slow to start once while its run is in flight, got %v", calls)
+	}
+
+	close(runner.release)
+	scheduler.Stop()
+
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	if runner.overlap {
+		t.Error("A rule ran twice at once")
+	}
+}
@@ -18,6 +18,8 @@ type Scheduler interface {
 }
 
 // Status represents the current state of a scheduler
+// Schedulers that track rules individually report aggregates here
+// and per-rule details in Rules
 type Status struct {
 	Running        bool
 	LastRunTime    time.Time
@@ -26,6 +28,20 @@ type Status struct {
 	SuccessfulRuns int
 	FailedRuns     int
 	LastError      string
+
+	// Rules holds per-rule status, sorted by rule name
+	Rules []RuleStatus
+}
+
+// RuleStatus represents the scheduling state of a single rule
+type RuleStatus struct {
+	Name           string
+	LastRunTime    time.Time
+	NextRunTime    time.Time
+	TotalRuns      int
+	SuccessfulRuns int
+	FailedRuns     int
+	LastError      string
 }
 
 // Config contains scheduler configuration
@@ -34,10 +50,46 @@ type Config struct {
 	Mode string
 
 	// Interval specifies the duration between sync runs (for interval mode)
+	// Also the default for rules without their own schedule
 	Interval time.Duration
 
 	// Rules specifies which rules to run (empty = all enabled rules)
 	Rules []string
+
+	// Schedules overrides the schedule of individual rules, keyed by rule name
+	Schedules map[string]RuleSchedule
+
+	// Watch lists the rules triggered by filesystem events (for watch mode)
+	// Rules in Rules without a Watch entry are polled every Interval
+	Watch []WatchRule
+
+	// Debounce is the quiet period after the last event before a sync runs (for watch mode)
+	Debounce time.Duration
+}
+
+// RuleSchedule describes when a single rule runs
+// Cron takes precedence over Interval; with neither set, Config.Interval is used
+type RuleSchedule struct {
+	// Interval is the duration between runs of the rule (0 = Config.Interval)
+	Interval time.Duration
+
+	// Cron is a standard 5-field cron expression (e.g., "0 2 * * *")
+	Cron string
+
+	// Timezone is the IANA timezone Cron is evaluated in (empty = local)
+	Timezone string
+}
+
+// WatchRule describes the local directories whose changes trigger a rule
+type WatchRule struct {
+	// Name of the rule to run
+	Name string
+
+	// Paths are the absolute local roots of the rule's endpoints
+	Paths []string
+
+	// IgnorePatterns are the rule's ignore patterns; matching events are dropped
+	IgnorePatterns []string
 }
 
 // SyncRunner is the interface that schedulers use to execute sync operations
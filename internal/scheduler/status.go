@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ruleTracker records per-rule run statistics for schedulers
+// that run rules independently of each other
+type ruleTracker struct {
+	mu        sync.RWMutex
+	rules     map[string]*RuleStatus
+	lastError string
+}
+
+func newRuleTracker() *ruleTracker {
+	return &ruleTracker{
+		rules: make(map[string]*RuleStatus),
+	}
+}
+
+// get returns the status entry of a rule, creating it if needed
+// Caller must hold t.mu
+func (t *ruleTracker) get(name string) *RuleStatus {
+	rs, ok := t.rules[name]
+	if !ok {
+		rs = &RuleStatus{Name: name}
+		t.rules[name] = rs
+	}
+	return rs
+}
+
+// setNext records the next planned run of a rule
+func (t *ruleTracker) setNext(name string, next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(name).NextRunTime = next
+}
+
+// record records the outcome of a rule run started at start
+func (t *ruleTracker) record(name string, start time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rs := t.get(name)
+	rs.LastRunTime = start
+	rs.TotalRuns++
+	if err != nil {
+		rs.FailedRuns++
+		rs.LastError = err.Error()
+		t.lastError = rs.LastError
+	} else {
+		rs.SuccessfulRuns++
+		rs.LastError = ""
+		t.lastError = ""
+	}
+}
+
+// recordError records an error not tied to a rule run (e.g., watcher errors)
+func (t *ruleTracker) recordError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastError = err.Error()
+}
+
+// status builds a Status with per-rule details and aggregated counters
+func (t *ruleTracker) status(running bool) *Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	status := &Status{
+		Running:   running,
+		LastError: t.lastError,
+		Rules:     make([]RuleStatus, 0, len(t.rules)),
+	}
+
+	for _, rs := range t.rules {
+		status.Rules = append(status.Rules, *rs)
+		status.TotalRuns += rs.TotalRuns
+		status.SuccessfulRuns += rs.SuccessfulRuns
+		status.FailedRuns += rs.FailedRuns
+		if rs.LastRunTime.After(status.LastRunTime) {
+			status.LastRunTime = rs.LastRunTime
+		}
+		if !rs.NextRunTime.IsZero() && (status.NextRunTime.IsZero() || rs.NextRunTime.Before(status.NextRunTime)) {
+			status.NextRunTime = rs.NextRunTime
+		}
+	}
+
+	sort.Slice(status.Rules, func(i, j int) bool {
+		return status.Rules[i].Name < status.Rules[j].Name
+	})
+
+	return status
+}
@@ -0,0 +1,351 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/Ning0612/Syncrules/internal/core/planner"
+)
+
+const (
+	// DefaultDebounce is the default quiet period before a watched rule is synced
+	DefaultDebounce = 2 * time.Second
+
+	// tempFileSuffix marks in-flight writes of the local adapter, which never trigger syncs
+	tempFileSuffix = ".syncrules.tmp"
+)
+
+// WatchScheduler runs rules when their local directories change
+// Bursts of events are debounced, and only the rules whose roots changed are run.
+// Rules without watchable paths fall back to per-rule interval polling.
+type WatchScheduler struct {
+	config  Config
+	runner  SyncRunner
+	watcher *fsnotify.Watcher
+	tracker *ruleTracker
+
+	// poller runs unwatched rules on their own schedules (nil if all rules are watched)
+	poller *MultiScheduler
+
+	// Runtime state
+	mu          sync.RWMutex
+	running     bool
+	stopped     bool      // Track if stopped to prevent restart
+	stopOnce    sync.Once // Ensure Stop() is idempotent
+	closeOnce   sync.Once // Ensure stoppedChan is closed exactly once
+	stopChan    chan struct{}
+	stoppedChan chan struct{}
+}
+
+// NewWatchScheduler creates a new filesystem watch scheduler
+func NewWatchScheduler(config Config, runner SyncRunner) (*WatchScheduler, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("sync runner cannot be nil")
+	}
+
+	if config.Debounce <= 0 {
+		config.Debounce = DefaultDebounce
+	}
+
+	watched := make(map[string]bool)
+	for i, rule := range config.Watch {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("watch rule name cannot be empty")
+		}
+		if len(rule.Paths) == 0 {
+			return nil, fmt.Errorf("watch rule %s has no paths", rule.Name)
+		}
+		for j, p := range rule.Paths {
+			if !filepath.IsAbs(p) {
+				return nil, fmt.Errorf("watch path must be absolute, got %s", p)
+			}
+			config.Watch[i].Paths[j] = filepath.Clean(p)
+		}
+		watched[rule.Name] = true
+	}
+
+	var pollRules []string
+	for _, name := range config.Rules {
+		if !watched[name] {
+			pollRules = append(pollRules, name)
+		}
+	}
+
+	tracker := newRuleTracker()
+
+	var poller *MultiScheduler
+	if len(pollRules) > 0 {
+		pollConfig := config
+		pollConfig.Mode = "interval"
+		pollConfig.Rules = pollRules
+		pollConfig.Watch = nil
+
+		var err error
+		poller, err = newMultiScheduler(pollConfig, runner, tracker)
+		if err != nil {
+			return nil, fmt.Errorf("cannot poll unwatched rules: %w", err)
+		}
+	}
+
+	return &WatchScheduler{
+		config:      config,
+		runner:      runner,
+		tracker:     tracker,
+		poller:      poller,
+		stopChan:    make(chan struct{}),
+		stoppedChan: make(chan struct{}),
+	}, nil
+}
+
+// Start registers the filesystem watches and begins the event loop
+func (s *WatchScheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("scheduler is already running")
+	}
+
+	if s.stopped {
+		return fmt.Errorf("scheduler cannot be restarted after stop")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	for _, rule := range s.config.Watch {
+		for _, root := range rule.Paths {
+			if err := s.addRecursive(watcher, root, rule); err != nil {
+				watcher.Close()
+				return fmt.Errorf("failed to watch %s for rule %s: %w", root, rule.Name, err)
+			}
+		}
+	}
+
+	if s.poller != nil {
+		if err := s.poller.Start(ctx); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to start poller: %w", err)
+		}
+	}
+
+	s.watcher = watcher
+	s.running = true
+
+	go s.run(ctx)
+
+	return nil
+}
+
+// run is the main event loop
+// Syncs run in goroutines of their own so events keep being read. A rule that
+// changes while it is syncing runs once more after it finishes, as the sync may
+// have missed the change; its own writes then plan nothing
+func (s *WatchScheduler) run(ctx context.Context) {
+	// Ensure stoppedChan is closed exactly once and stopped flag is set
+	defer s.closeOnce.Do(func() {
+		s.watcher.Close()
+		s.mu.Lock()
+		s.stopped = true
+		s.running = false
+		s.mu.Unlock()
+		close(s.stoppedChan)
+	})
+
+	// Syncs in flight finish before the scheduler reports it stopped
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+	done := make(chan string, len(s.config.Watch))
+
+	debounce := time.NewTimer(s.config.Debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	pending := make(map[string]bool)
+	syncing := make(map[string]bool)
+	changed := make(map[string]bool) // Syncing rules that changed since they started
+
+	// schedule queues rules to run once the quiet period has passed
+	schedule := func(rules []string) {
+		// Restart the quiet period on every relevant event
+		debounce.Reset(s.config.Debounce)
+		next := time.Now().Add(s.config.Debounce)
+		for name := range pending {
+			s.tracker.setNext(name, next)
+		}
+		for _, name := range rules {
+			pending[name] = true
+			s.tracker.setNext(name, next)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			var rules []string
+			for _, name := range s.handleEvent(event) {
+				if syncing[name] {
+					changed[name] = true
+				} else {
+					rules = append(rules, name)
+				}
+			}
+			if len(rules) > 0 {
+				schedule(rules)
+			}
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+			s.tracker.recordError(err)
+		case <-debounce.C:
+			rules := make([]string, 0, len(pending))
+			for name := range pending {
+				rules = append(rules, name)
+			}
+			sort.Strings(rules)
+			pending = make(map[string]bool)
+			for _, name := range rules {
+				syncing[name] = true
+				inflight.Add(1)
+				go func(name string) {
+					defer inflight.Done()
+					s.executeSync(ctx, name)
+					done <- name
+				}(name)
+			}
+		case name := <-done:
+			delete(syncing, name)
+			if changed[name] {
+				delete(changed, name)
+				schedule([]string{name})
+			}
+		}
+	}
+}
+
+// handleEvent returns the rules affected by an event and keeps
+// watches in sync with newly created directories
+func (s *WatchScheduler) handleEvent(event fsnotify.Event) []string {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+		return nil
+	}
+
+	var rules []string
+	for _, rule := range s.config.Watch {
+		if !s.matches(rule, event.Name) {
+			continue
+		}
+		rules = append(rules, rule.Name)
+
+		// fsnotify is not recursive: watch directories created under a root
+		if event.Has(fsnotify.Create) {
+			s.addRecursive(s.watcher, event.Name, rule)
+		}
+	}
+	return rules
+}
+
+// matches reports whether a changed path belongs to one of the rule's roots
+// and is not excluded by its ignore patterns
+func (s *WatchScheduler) matches(rule WatchRule, name string) bool {
+	if strings.HasSuffix(name, tempFileSuffix) {
+		return false
+	}
+	for _, root := range rule.Paths {
+		rel, err := filepath.Rel(root, name)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			return true
+		}
+		if !planner.ShouldIgnore(filepath.ToSlash(rel), rule.IgnorePatterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// addRecursive watches dir and every non-ignored directory beneath it
+func (s *WatchScheduler) addRecursive(watcher *fsnotify.Watcher, dir string, rule WatchRule) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The root must exist; entries vanishing during the walk are fine
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && !s.matches(rule, path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// executeSync runs a rule and records its statistics
+func (s *WatchScheduler) executeSync(ctx context.Context, ruleName string) {
+	// Watched rules have no next run until the next change
+	s.tracker.setNext(ruleName, time.Time{})
+
+	start := time.Now()
+	err := s.runner.RunSync(ctx, ruleName)
+	s.tracker.record(ruleName, start, err)
+}
+
+// Stop gracefully stops the scheduler
+func (s *WatchScheduler) Stop() error {
+	s.mu.RLock()
+	if !s.running {
+		s.mu.RUnlock()
+		return fmt.Errorf("scheduler is not running")
+	}
+	s.mu.RUnlock()
+
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+
+	<-s.stoppedChan
+
+	// The poller may already have exited with a cancelled context
+	if s.poller != nil {
+		s.poller.Stop()
+	}
+
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Status returns the current scheduler status with per-rule details
+func (s *WatchScheduler) Status() *Status {
+	s.mu.RLock()
+	running := s.running
+	s.mu.RUnlock()
+
+	return s.tracker.status(running)
+}
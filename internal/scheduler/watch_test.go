@@ -0,0 +1,307 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingSyncRunner records calls safely from the scheduler goroutine
+type countingSyncRunner struct {
+	mu    sync.Mutex
+	calls []string
+	fail  map[string]bool // Rules whose runs fail
+}
+
+func (r *countingSyncRunner) RunSync(ctx context.Context, ruleName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, ruleName)
+	if r.fail[ruleName] {
+		return errors.New("sync failed")
+	}
+	return nil
+}
+
+func (r *countingSyncRunner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func startWatchScheduler(t *testing.T, config Config, runner SyncRunner) *WatchScheduler {
+	t.Helper()
+
+	scheduler, err := NewWatchScheduler(config, runner)
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	t.Cleanup(func() { scheduler.Stop() })
+	return scheduler
+}
+
+func waitForCalls(runner *countingSyncRunner, n int, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if calls := runner.Calls(); len(calls) >= n {
+			return calls
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runner.Calls()
+}
+
+func TestNewWatchScheduler_Validation(t *testing.T) {
+	runner := &countingSyncRunner{}
+
+	if _, err := NewWatchScheduler(Config{}, nil); err == nil {
+		t.Error("Expected error for nil runner")
+	}
+
+	relative := Config{Watch: []WatchRule{{Name: "r", Paths: []string{"relative/dir"}}}}
+	if _, err := NewWatchScheduler(relative, runner); err == nil {
+		t.Error("Expected error for relative watch path")
+	}
+
+	// Unwatched rules need an interval to be polled
+	poll := Config{Rules: []string{"remote"}}
+	if _, err := NewWatchScheduler(poll, runner); err == nil {
+		t.Error("Expected error for polled rule without interval")
+	}
+}
+
+func TestWatchScheduler_DebouncesBurst(t *testing.T) {
+	dir := t.TempDir()
+	runner := &countingSyncRunner{}
+
+	startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Debounce: 100 * time.Millisecond,
+		Rules:    []string{"docs"},
+		Watch:    []WatchRule{{Name: "docs", Paths: []string{dir}}},
+	}, runner)
+
+	for i := 0; i < 5; i++ {
+		name := filepath.Join(dir, "file"+string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	calls := waitForCalls(runner, 1, 2*time.Second)
+	if len(calls) != 1 || calls[0] != "docs" {
+		t.Fatalf("Expected a single run of docs, got %v", calls)
+	}
+
+	// No further runs once the burst is over
+	time.Sleep(300 * time.Millisecond)
+	if calls := runner.Calls(); len(calls) != 1 {
+		t.Errorf("Expected burst to be debounced into 1 run, got %d", len(calls))
+	}
+}
+
+func TestWatchScheduler_OnlyAffectedRules(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	runner := &countingSyncRunner{}
+
+	startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Debounce: 50 * time.Millisecond,
+		Rules:    []string{"a", "b"},
+		Watch: []WatchRule{
+			{Name: "a", Paths: []string{dirA}},
+			{Name: "b", Paths: []string{dirB}},
+		},
+	}, runner)
+
+	if err := os.WriteFile(filepath.Join(dirB, "x.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	waitForCalls(runner, 1, 2*time.Second)
+	time.Sleep(150 * time.Millisecond)
+	calls := runner.Calls()
+	if len(calls) != 1 || calls[0] != "b" {
+		t.Errorf("Expected only rule b to run, got %v", calls)
+	}
+}
+
+func TestWatchScheduler_IgnorePatterns(t *testing.T) {
+	dir := t.TempDir()
+	runner := &countingSyncRunner{}
+
+	startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Debounce: 50 * time.Millisecond,
+		Rules:    []string{"docs"},
+		Watch: []WatchRule{{
+			Name:           "docs",
+			Paths:          []string{dir},
+			IgnorePatterns: []string{"*.swp", "*~"},
+		}},
+	}, runner)
+
+	for _, name := range []string{".notes.md.swp", "notes.md~", "data.syncrules.tmp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("tmp"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if calls := runner.Calls(); len(calls) != 0 {
+		t.Errorf("Expected ignored files not to trigger sync, got %v", calls)
+	}
+}
+
+func TestWatchScheduler_NewSubdirectory(t *testing.T) {
+	dir := t.TempDir()
+	runner := &countingSyncRunner{}
+
+	startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Debounce: 50 * time.Millisecond,
+		Rules:    []string{"docs"},
+		Watch:    []WatchRule{{Name: "docs", Paths: []string{dir}}},
+	}, runner)
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	waitForCalls(runner, 1, 2*time.Second)
+
+	// Changes inside the new directory are watched too
+	if err := os.WriteFile(filepath.Join(sub, "nested.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if calls := waitForCalls(runner, 2, 2*time.Second); len(calls) < 2 {
+		t.Errorf("Expected change in new subdirectory to trigger sync, got %v", calls)
+	}
+}
+
+// writingSyncRunner writes into the watched directory like a sync pulling
+// changes in; only the first run finds anything to pull
+type writingSyncRunner struct {
+	countingSyncRunner
+	dir string
+}
+
+func (r *writingSyncRunner) RunSync(ctx context.Context, ruleName string) error {
+	r.countingSyncRunner.RunSync(ctx, ruleName)
+	if len(r.Calls()) > 1 {
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		name := filepath.Join(r.dir, fmt.Sprintf("pulled-%d.txt", i))
+		if err := os.WriteFile(name, []byte("remote"), 0644); err != nil {
+			return err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestWatchScheduler_OwnWritesSettle(t *testing.T) {
+	dir := t.TempDir()
+	runner := &writingSyncRunner{dir: dir}
+
+	startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Debounce: 50 * time.Millisecond,
+		Rules:    []string{"docs"},
+		Watch:    []WatchRule{{Name: "docs", Paths: []string{dir}}},
+	}, runner)
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitForCalls(&runner.countingSyncRunner, 1, 2*time.Second)
+
+	// The writes bring one follow-up run, which writes nothing and ends it
+	time.Sleep(500 * time.Millisecond)
+	if calls := runner.Calls(); len(calls) != 2 {
+		t.Errorf("Expected a single follow-up run after the sync's own writes, got %v", calls)
+	}
+}
+
+// gatedSyncRunner holds its first run until release is closed
+type gatedSyncRunner struct {
+	countingSyncRunner
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *gatedSyncRunner) RunSync(ctx context.Context, ruleName string) error {
+	r.countingSyncRunner.RunSync(ctx, ruleName)
+	if len(r.Calls()) == 1 {
+		close(r.started)
+		<-r.release
+	}
+	return nil
+}
+
+func TestWatchScheduler_ChangeDuringSync(t *testing.T) {
+	dir := t.TempDir()
+	runner := &gatedSyncRunner{started: make(chan struct{}), release: make(chan struct{})}
+
+	startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Debounce: 50 * time.Millisecond,
+		Rules:    []string{"docs"},
+		Watch:    []WatchRule{{Name: "docs", Paths: []string{dir}}},
+	}, runner)
+
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	select {
+	case <-runner.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the first change to start a sync")
+	}
+
+	// A save while the sync runs may be missed by it, so the rule runs again
+	if err := os.WriteFile(filepath.Join(dir, "b.md"), []byte("b"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if calls := runner.Calls(); len(calls) != 1 {
+		t.Errorf("Expected no second run while the first is in flight, got %v", calls)
+	}
+	close(runner.release)
+
+	if calls := waitForCalls(&runner.countingSyncRunner, 2, 2*time.Second); len(calls) != 2 {
+		t.Errorf("Expected the change during the sync to run the rule again, got %v", calls)
+	}
+}
+
+func TestWatchScheduler_PollsUnwatchedRules(t *testing.T) {
+	runner := &countingSyncRunner{}
+
+	scheduler := startWatchScheduler(t, Config{
+		Mode:     "watch",
+		Interval: 50 * time.Millisecond,
+		Rules:    []string{"remote"},
+	}, runner)
+
+	calls := waitForCalls(runner, 2, 2*time.Second)
+	if len(calls) < 2 || calls[0] != "remote" {
+		t.Errorf("Expected remote rule to be polled, got %v", calls)
+	}
+
+	if err := scheduler.Stop(); err != nil {
+		t.Fatalf("Failed to stop scheduler: %v", err)
+	}
+	if scheduler.Status().Running {
+		t.Error("Scheduler should not be running after stop")
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Ning0612/Syncrules/internal/core/planner"
+	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/logger"
+	"github.com/Ning0612/Syncrules/internal/state"
+)
+
+// ListConflicts returns the recorded manual conflicts of a rule ("" for all rules)
+// Conflicts are recorded when a sync is planned; resolved ones are applied by the next sync
+func (s *SyncService) ListConflicts(ruleName string) ([]state.ConflictRecord, error) {
+	if ruleName != "" {
+		if _, err := s.config.GetRule(ruleName); err != nil {
+			return nil, err
+		}
+	}
+	return s.stateMgr.ListConflicts(ruleName)
+}
+
+// GetConflict returns a recorded conflict by ID
+func (s *SyncService) GetConflict(id int64) (*state.ConflictRecord, error) {
+	return s.stateMgr.GetConflict(id)
+}
+
+// ResolveConflict records how a conflict should be resolved by the next sync
+// Returns domain.ErrInvalidResolution if the resolution cannot be applied to it
+func (s *SyncService) ResolveConflict(id int64, resolution domain.ConflictResolution) error {
+	if !resolution.IsValid() {
+		return fmt.Errorf("%w: %q", domain.ErrInvalidResolution, resolution)
+	}
+
+	record, err := s.stateMgr.GetConflict(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.resolver.ApplyResolution(resolution, record.Action()); err != nil {
+		return err
+	}
+
+	if err := s.stateMgr.ResolveConflict(id, resolution); err != nil {
+		return err
+	}
+	logger.Get().Info("conflict resolved",
+		"rule", record.RuleName,
+		"path", record.Path,
+		"resolution", resolution,
+	)
+	return nil
+}
+
+// applyResolutions replaces the plan's conflicts that the user has resolved
+// with the actions carrying out the resolution, then records the remaining ones
+// A resolution only applies to the versions it was made for; if either side
+// changed since, the conflict is reported again
+func (s *SyncService) applyResolutions(plan *domain.SyncPlan) error {
+	records, err := s.stateMgr.ListConflicts(plan.RuleName)
+	if err != nil {
+		return err
+	}
+	byPath := make(map[string]state.ConflictRecord, len(records))
+	for _, record := range records {
+		byPath[record.Path] = record
+	}
+
+	resolved := make(map[string][]domain.SyncAction)
+	keep := make(map[string]bool)
+	for _, action := range plan.Conflicts {
+		record, ok := byPath[action.Path]
+		if !ok || !record.Resolved() {
+			continue
+		}
+
+		if !sameVersion(record.Source, action.SourceInfo) || !sameVersion(record.Target, action.TargetInfo) {
+			logger.Get().Info("conflict changed since it was resolved, reopening",
+				"rule", plan.RuleName,
+				"path", action.Path,
+			)
+			if err := s.stateMgr.ReopenConflict(record.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		actions, err := s.resolver.ApplyResolution(record.Resolution, action)
+		if err != nil {
+			logger.Get().Warn("cannot apply conflict resolution",
+				"rule", plan.RuleName,
+				"path", action.Path,
+				"error", err,
+			)
+			continue
+		}
+		resolved[action.Path] = actions
+		keep[action.Path] = true
+
+		if plan.Resolved == nil {
+			plan.Resolved = make(map[string]domain.ConflictResolution)
+		}
+		plan.Resolved[action.Path] = record.Resolution
+	}
+
+	if len(resolved) > 0 {
+		planner.ApplyResolutions(plan, resolved)
+	}
+
+	return s.stateMgr.RecordConflicts(plan.RuleName, plan.Conflicts, keep)
+}
+
+// sameVersion reports whether a recorded side of a conflict is still the current one
+func sameVersion(recorded, current *domain.FileInfo) bool {
+	if recorded == nil || current == nil {
+		return recorded == current
+	}
+	return recorded.Type == current.Type &&
+		recorded.Size == current.Size &&
+		recorded.ModTime.Equal(current.ModTime) &&
+		recorded.Checksum == current.Checksum &&
+		recorded.IsDeleted == current.IsDeleted
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/config"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// newConflictFixture creates a two-way manual rule where both sides edited app.conf
+func newConflictFixture(t *testing.T) (*SyncService, string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "dst")
+	for _, dir := range []string{src, dst} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(src, "app.conf"), "source edit")
+	writeFile(t, filepath.Join(dst, "app.conf"), "target edit, longer")
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "local", Type: domain.TransportLocal}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "local", Root: src},
+			{Name: "dst", Transport: "local", Root: dst},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "manual", Mode: domain.SyncModeTwoWay, SourceEndpoint: "src", TargetEndpoint: "dst",
+				ConflictStrategy: domain.ConflictManual, Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(root, "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	t.Cleanup(func() { svc.Close() })
+	return svc, src, dst
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// syncOnce plans and executes the rule, returning the executed plan
+func syncOnce(t *testing.T, svc *SyncService, ruleName string) *domain.SyncPlan {
+	t.Helper()
+	plan, err := svc.PlanSync(context.Background(), ruleName)
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if err := svc.ExecuteSync(context.Background(), plan); err != nil {
+		t.Fatalf("ExecuteSync failed: %v", err)
+	}
+	return plan
+}
+
+func TestSyncService_ResolveConflict(t *testing.T) {
+	svc, src, dst := newConflictFixture(t)
+
+	plan := syncOnce(t, svc, "manual")
+	if plan.Stats.Conflicts != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", plan.Stats.Conflicts)
+	}
+
+	// The conflict outlives the run and is recorded only once
+	syncOnce(t, svc, "manual")
+	conflicts, err := svc.ListConflicts("manual")
+	if err != nil {
+		t.Fatalf("ListConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Path != "app.conf" || conflicts[0].Source == nil || conflicts[0].Target == nil {
+		t.Fatalf("Expected the app.conf conflict with both sides, got %+v", conflicts)
+	}
+	id := conflicts[0].ID
+
+	if err := svc.ResolveConflict(id, domain.ConflictResolution("merge")); !errors.Is(err, domain.ErrInvalidResolution) {
+		t.Errorf("Expected ErrInvalidResolution, got %v", err)
+	}
+	if err := svc.ResolveConflict(id, domain.ResolveTakeTarget); err != nil {
+		t.Fatalf("ResolveConflict failed: %v", err)
+	}
+	if record, err := svc.GetConflict(id); err != nil || record.Resolution != domain.ResolveTakeTarget {
+		t.Fatalf("Expected the resolution to be stored, got %+v (%v)", record, err)
+	}
+
+	// A dry run shows the resolution without consuming it
+	dryRun, err := svc.PlanSync(context.Background(), "manual")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if dryRun.Stats.Conflicts != 0 || dryRun.Resolved["app.conf"] != domain.ResolveTakeTarget {
+		t.Errorf("Expected the resolution to be applied to the plan, got %+v", dryRun)
+	}
+
+	plan = syncOnce(t, svc, "manual")
+	if plan.Stats.Conflicts != 0 {
+		t.Errorf("Resolved conflict should not be reported again")
+	}
+	data, _ := os.ReadFile(filepath.Join(src, "app.conf"))
+	if string(data) != "target edit, longer" {
+		t.Errorf("Expected the target version on the source, got %q", data)
+	}
+	if conflicts, _ := svc.ListConflicts(""); len(conflicts) != 0 {
+		t.Errorf("Expected the applied conflict to be cleared, got %+v", conflicts)
+	}
+
+	// Both sides are now in sync
+	writeFile(t, filepath.Join(dst, "other.txt"), "x")
+	if plan := syncOnce(t, svc, "manual"); plan.Stats.Conflicts != 0 {
+		t.Errorf("Expected no conflicts after resolving, got %d", plan.Stats.Conflicts)
+	}
+}
+
+func TestSyncService_OneSidedEditsAreNotConflicts(t *testing.T) {
+	svc, src, dst := newConflictFixture(t)
+
+	// Start in sync: the first run copies app.conf to the target
+	if err := os.Remove(filepath.Join(dst, "app.conf")); err != nil {
+		t.Fatal(err)
+	}
+	syncOnce(t, svc, "manual")
+
+	edits := []struct {
+		edited, other, content string
+	}{
+		{src, dst, "edited on the source"},
+		{dst, src, "edited on the target, later"},
+	}
+	for _, edit := range edits {
+		writeFile(t, filepath.Join(edit.edited, "app.conf"), edit.content)
+
+		plan := syncOnce(t, svc, "manual")
+		if plan.Stats.Conflicts != 0 {
+			t.Errorf("Expected no conflict for an edit in %s, got %d", edit.edited, plan.Stats.Conflicts)
+		}
+		if data, _ := os.ReadFile(filepath.Join(edit.other, "app.conf")); string(data) != edit.content {
+			t.Errorf("Expected the edit to be copied to %s, got %q", edit.other, data)
+		}
+		if conflicts, _ := svc.ListConflicts("manual"); len(conflicts) != 0 {
+			t.Errorf("Expected no recorded conflicts, got %+v", conflicts)
+		}
+	}
+}
+
+func TestSyncService_IgnoreConflict(t *testing.T) {
+	svc, src, _ := newConflictFixture(t)
+
+	syncOnce(t, svc, "manual")
+	conflicts, _ := svc.ListConflicts("manual")
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(conflicts))
+	}
+	id := conflicts[0].ID
+
+	if err := svc.ResolveConflict(id, domain.ResolveIgnore); err != nil {
+		t.Fatalf("ResolveConflict failed: %v", err)
+	}
+
+	// Ignored conflicts stay recorded but are no longer reported
+	for i := 0; i < 2; i++ {
+		if plan := syncOnce(t, svc, "manual"); plan.Stats.Conflicts != 0 {
+			t.Errorf("Ignored conflict should not be reported, got %d", plan.Stats.Conflicts)
+		}
+	}
+	if record, err := svc.GetConflict(id); err != nil || record.Resolution != domain.ResolveIgnore {
+		t.Fatalf("Expected the ignored conflict to be kept, got %+v (%v)", record, err)
+	}
+
+	// A new edit invalidates the decision
+	writeFile(t, filepath.Join(src, "app.conf"), "another source edit")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(src, "app.conf"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if plan := syncOnce(t, svc, "manual"); plan.Stats.Conflicts != 1 {
+		t.Errorf("Expected the changed conflict to be reported again, got %d", plan.Stats.Conflicts)
+	}
+	if record, _ := svc.GetConflict(id); record == nil || record.Resolved() {
+		t.Errorf("Expected the conflict to be reopened, got %+v", record)
+	}
+}
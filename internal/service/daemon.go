@@ -3,11 +3,13 @@ package service
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/Ning0612/Syncrules/internal/config"
 	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/logger"
 	"github.com/Ning0612/Syncrules/internal/scheduler"
 	"github.com/Ning0612/Syncrules/internal/state"
 )
@@ -40,16 +42,12 @@ func NewDaemonService(cfg *config.Config) (*DaemonService, error) {
 		return nil, fmt.Errorf("failed to create sync service: %w", err)
 	}
 
-	// Create state manager
-	stateMgr, err := state.NewManager(cfg.GetLockPath())
-	if err != nil {
-		return nil, fmt.Errorf("failed to create state manager: %w", err)
-	}
-
+	// Share the sync service's state manager rather than opening the
+	// database a second time; the sync service closes it
 	daemon := &DaemonService{
 		config:   cfg,
 		syncSvc:  syncSvc,
-		stateMgr: stateMgr,
+		stateMgr: syncSvc.stateMgr,
 	}
 
 	// Create scheduler (if configured)
@@ -59,6 +57,8 @@ func NewDaemonService(cfg *config.Config) (*DaemonService, error) {
 }
 
 // Start starts the daemon in the background
+// interval is the default for rules without their own schedule.interval;
+// if it is zero, scheduler.default_interval is used
 func (d *DaemonService) Start(ctx context.Context, interval time.Duration) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -74,15 +74,31 @@ func (d *DaemonService) Start(ctx context.Context, interval time.Duration) error
 		ruleNames = append(ruleNames, rule.Name)
 	}
 
+	if interval <= 0 && d.config.Scheduler.DefaultInterval != "" {
+		interval, _ = time.ParseDuration(d.config.Scheduler.DefaultInterval) // validated in config.Validate
+	}
+
 	// Create scheduler config
 	schedConfig := scheduler.Config{
-		Mode:     "interval",
-		Interval: interval,
-		Rules:    ruleNames,
+		Mode:      "interval",
+		Interval:  interval,
+		Rules:     ruleNames,
+		Schedules: ruleSchedules(scheduledRules),
 	}
 
 	// Create scheduler
-	sched, err := scheduler.NewIntervalScheduler(schedConfig, d.newSyncRunner())
+	var sched scheduler.Scheduler
+	var err error
+	if d.config.Scheduler.Mode == "watch" {
+		schedConfig.Mode = "watch"
+		schedConfig.Watch = d.watchRules(scheduledRules)
+		if d.config.Scheduler.Debounce != "" {
+			schedConfig.Debounce, _ = time.ParseDuration(d.config.Scheduler.Debounce) // validated in config.Validate
+		}
+		sched, err = scheduler.NewWatchScheduler(schedConfig, d.newSyncRunner())
+	} else {
+		sched, err = scheduler.NewMultiScheduler(schedConfig, d.newSyncRunner())
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create scheduler: %w", err)
 	}
@@ -96,6 +112,69 @@ func (d *DaemonService) Start(ctx context.Context, interval time.Duration) error
 	return nil
 }
 
+// ruleSchedules returns the per-rule schedule overrides of the given rules
+func ruleSchedules(rules []domain.SyncRule) map[string]scheduler.RuleSchedule {
+	schedules := make(map[string]scheduler.RuleSchedule)
+	for _, rule := range rules {
+		if rule.Schedule == nil {
+			continue
+		}
+		if rule.Schedule.Cron != "" {
+			schedules[rule.Name] = scheduler.RuleSchedule{
+				Cron:     rule.Schedule.Cron,
+				Timezone: rule.Schedule.Timezone,
+			}
+			continue
+		}
+		if rule.Schedule.Interval == "" {
+			continue
+		}
+		interval, err := time.ParseDuration(rule.Schedule.Interval) // validated in config.Validate
+		if err != nil {
+			continue
+		}
+		schedules[rule.Name] = scheduler.RuleSchedule{Interval: interval}
+	}
+	return schedules
+}
+
+// watchRules returns the rules that can be triggered by filesystem events
+// Only rules whose endpoints are all local can be watched; the rest are polled
+func (d *DaemonService) watchRules(rules []domain.SyncRule) []scheduler.WatchRule {
+	var watch []scheduler.WatchRule
+	for _, rule := range rules {
+		var paths []string
+		for _, name := range []string{rule.SourceEndpoint, rule.TargetEndpoint} {
+			endpoint, err := d.config.GetEndpoint(name)
+			if err != nil {
+				paths = nil
+				break
+			}
+			transport, err := d.config.GetTransport(endpoint.Transport)
+			if err != nil || transport.Type != domain.TransportLocal {
+				paths = nil
+				break
+			}
+			root, err := filepath.Abs(config.ExpandPath(endpoint.Root))
+			if err != nil {
+				paths = nil
+				break
+			}
+			paths = append(paths, root)
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		// Changes to the trash are made by syncs and never need one
+		watch = append(watch, scheduler.WatchRule{
+			Name:           rule.Name,
+			Paths:          paths,
+			IgnorePatterns: append([]string{domain.TrashDir}, rule.IgnorePatterns...),
+		})
+	}
+	return watch
+}
+
 // Stop stops the daemon
 func (d *DaemonService) Stop() error {
 	d.mu.Lock()
@@ -151,18 +230,13 @@ func (d *DaemonService) Close() error {
 		d.scheduler = nil
 	}
 
+	// Also closes the state manager
 	if d.syncSvc != nil {
 		if err := d.syncSvc.Close(); err != nil {
 			lastErr = err
 		}
 	}
 
-	if d.stateMgr != nil {
-		if err := d.stateMgr.Close(); err != nil {
-			lastErr = err
-		}
-	}
-
 	return lastErr
 }
 
@@ -200,43 +274,82 @@ func (r *syncRunner) RunSync(ctx context.Context, ruleName string) error {
 	// Execute each rule and collect errors
 	var errors []error
 	for _, rule := range rules {
-		record := state.ExecutionRecord{
-			RuleName:  rule.Name,
-			StartTime: time.Now(),
-			Status:    "success",
+		if err := r.runRule(ctx, rule); err != nil {
+			errors = append(errors, err)
 		}
+	}
 
-		// Generate plan
-		plan, err := r.syncSvc.PlanSync(ctx, rule.Name)
-		if err != nil {
-			record.EndTime = time.Now()
-			record.Status = "failed"
-			record.Error = err.Error()
-			r.stateMgr.SaveExecution(record)
-			errors = append(errors, fmt.Errorf("rule %s: plan failed: %w", rule.Name, err))
-			continue // Continue to next rule instead of returning
-		}
+	// Return aggregated errors if any
+	if len(errors) > 0 {
+		return fmt.Errorf("sync completed with %d error(s): %v", len(errors), errors)
+	}
+	return nil
+}
 
-		// Execute sync
-		if err := r.syncSvc.ExecuteSync(ctx, plan); err != nil {
-			record.EndTime = time.Now()
-			record.Status = "failed"
-			record.Error = err.Error()
-			r.stateMgr.SaveExecution(record)
-			errors = append(errors, fmt.Errorf("rule %s: execution failed: %w", rule.Name, err))
-			continue // Continue to next rule
-		}
+// runRule plans and executes one rule and records the run in state
+func (r *syncRunner) runRule(ctx context.Context, rule domain.SyncRule) error {
+	record := state.ExecutionRecord{
+		RuleName:  rule.Name,
+		StartTime: time.Now(),
+		Status:    "success",
+	}
 
-		// Record successful execution
+	// Rules of this daemon sharing an endpoint take turns, from plan to execution
+	release, err := r.syncSvc.holdEndpoints(ctx, rule.Name)
+	if err != nil {
 		record.EndTime = time.Now()
-		record.FilesSynced = plan.Stats.FilesToCopy
-		record.BytesSynced = plan.Stats.BytesToSync
+		record.Status = "failed"
+		record.Error = err.Error()
 		r.stateMgr.SaveExecution(record)
+		return fmt.Errorf("rule %s: %w", rule.Name, err)
 	}
+	defer release()
+	record.StartTime = time.Now()
 
-	// Return aggregated errors if any
-	if len(errors) > 0 {
-		return fmt.Errorf("sync completed with %d error(s): %v", len(errors), errors)
+	// Generate plan
+	plan, err := r.syncSvc.PlanSync(ctx, rule.Name)
+	if err != nil {
+		record.EndTime = time.Now()
+		record.Status = "failed"
+		record.Error = err.Error()
+		r.stateMgr.SaveExecution(record)
+		return fmt.Errorf("rule %s: plan failed: %w", rule.Name, err)
+	}
+
+	// Resolved conflicts are no longer in the plan, so only open ones are reported
+	if len(plan.Conflicts) > 0 {
+		paths := make([]string, 0, len(plan.Conflicts))
+		for _, action := range plan.Conflicts {
+			paths = append(paths, action.Path)
+		}
+		logger.Get().Warn("unresolved conflicts",
+			"rule", rule.Name,
+			"count", len(paths),
+			"paths", paths,
+		)
+	}
+
+	// Execute sync, keeping going past individual failed actions
+	result, err := r.syncSvc.ExecuteSyncWithOptions(ctx, plan, ExecuteOptions{ContinueOnError: true})
+	record.EndTime = time.Now()
+	if result != nil {
+		record.FilesSynced = result.FilesSynced
+		record.BytesSynced = result.BytesSynced
+	}
+	if err != nil {
+		record.Status = "failed"
+		record.Error = err.Error()
+		r.stateMgr.SaveExecution(record)
+		return fmt.Errorf("rule %s: execution failed: %w", rule.Name, err)
+	}
+
+	// Record the actual outcome: success, partial or failed
+	record.Status = result.Status()
+	record.Error = result.Summary()
+	r.stateMgr.SaveExecution(record)
+	if result.HasFailures() {
+		return fmt.Errorf("rule %s: %s", rule.Name, record.Error)
 	}
 	return nil
+
 }
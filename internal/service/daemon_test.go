@@ -70,6 +70,10 @@ func TestNewDaemonService(t *testing.T) {
 	if daemon.stateMgr == nil {
 		t.Error("State manager is nil")
 	}
+
+	if daemon.stateMgr != daemon.syncSvc.stateMgr {
+		t.Error("Daemon should share the sync service's state manager")
+	}
 }
 
 func TestNewDaemonService_NilConfig(t *testing.T) {
@@ -209,3 +213,63 @@ func TestDaemonService_Status(t *testing.T) {
 	cancel()
 	time.Sleep(50 * time.Millisecond)
 }
+
+func TestDaemonService_WatchRules(t *testing.T) {
+	t.Setenv("WATCH_ROOT", "/tmp/watch-root")
+	cfg := &config.Config{
+		Transports: []domain.Transport{
+			{Name: "local", Type: domain.TransportLocal},
+			{Name: "drive", Type: domain.TransportGDrive},
+		},
+		Endpoints: []domain.Endpoint{
+			{Name: "a", Transport: "local", Root: "/tmp/watch-a"},
+			{Name: "b", Transport: "local", Root: "$WATCH_ROOT/b"},
+			{Name: "cloud", Transport: "drive", Root: "/backup"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "local-rule", SourceEndpoint: "a", TargetEndpoint: "b", IgnorePatterns: []string{"*.tmp"}},
+			{Name: "cloud-rule", SourceEndpoint: "a", TargetEndpoint: "cloud"},
+		},
+		Settings: config.Settings{LockPath: t.TempDir()},
+	}
+
+	daemon, err := NewDaemonService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create daemon service: %v", err)
+	}
+	defer daemon.Close()
+
+	watch := daemon.watchRules(cfg.Rules)
+	if len(watch) != 1 {
+		t.Fatalf("Expected only the all-local rule to be watched, got %d", len(watch))
+	}
+	if watch[0].Name != "local-rule" || len(watch[0].Paths) != 2 {
+		t.Errorf("Unexpected watch rule: %+v", watch[0])
+	} else if watch[0].Paths[1] != "/tmp/watch-root/b" {
+		t.Errorf("Expected the target root to be expanded, got %q", watch[0].Paths[1])
+	}
+	patterns := watch[0].IgnorePatterns
+	if len(patterns) != 2 || patterns[0] != domain.TrashDir || patterns[1] != "*.tmp" {
+		t.Errorf("Expected the trash and the rule's ignore patterns, got %v", patterns)
+	}
+}
+
+func TestRuleSchedules(t *testing.T) {
+	rules := []domain.SyncRule{
+		{Name: "fast", Schedule: &domain.ScheduleConfig{Enabled: true, Interval: "1m"}},
+		{Name: "default", Schedule: &domain.ScheduleConfig{Enabled: true}},
+		{Name: "unscheduled"},
+		{Name: "nightly", Schedule: &domain.ScheduleConfig{Enabled: true, Cron: "0 2 * * *", Timezone: "UTC"}},
+	}
+
+	schedules := ruleSchedules(rules)
+	if len(schedules) != 2 {
+		t.Fatalf("Expected only rules with an interval or cron to be overridden, got %v", schedules)
+	}
+	if schedules["fast"].Interval != time.Minute {
+		t.Errorf("Expected fast interval 1m, got %v", schedules["fast"].Interval)
+	}
+	if nightly := schedules["nightly"]; nightly.Cron != "0 2 * * *" || nightly.Timezone != "UTC" {
+		t.Errorf("Unexpected nightly schedule: %+v", nightly)
+	}
+}
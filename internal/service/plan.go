@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// ExportPlan returns a document of a plan to review, and apply later with ApplyPlan
+func (s *SyncService) ExportPlan(plan *domain.SyncPlan) (*domain.PlanDocument, error) {
+	rule, err := s.config.GetRule(plan.RuleName)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewPlanDocument(plan, rule.SourceEndpoint, rule.TargetEndpoint, time.Now()), nil
+}
+
+// ApplyPlan executes a saved plan
+// The document must match its fingerprint and its rule's current endpoints.
+// Right before each action runs, the files it reads or changes are compared
+// with their state when the plan was made; actions whose files changed are
+// refused and reported as skipped with domain.ErrPlanStale. Planning the rule
+// again picks them up with their current state
+func (s *SyncService) ApplyPlan(ctx context.Context, doc *domain.PlanDocument, opts ExecuteOptions) (*domain.SyncResult, error) {
+	if err := s.checkDocument(doc); err != nil {
+		return nil, err
+	}
+	plan := doc.Plan
+	opts.VerifyPreconditions = true
+	return s.ExecuteSyncWithOptions(ctx, &plan, opts)
+}
+
+// CheckPlan returns the actions of a saved plan that ApplyPlan would refuse
+// now, without executing anything
+func (s *SyncService) CheckPlan(ctx context.Context, doc *domain.PlanDocument) ([]domain.ActionOutcome, error) {
+	if err := s.checkDocument(doc); err != nil {
+		return nil, err
+	}
+	rule, err := s.config.GetRule(doc.Plan.RuleName)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.getAdapter(rule.SourceEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.getAdapter(rule.TargetEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	verify := newPreconditions(&doc.Plan, source, target)
+	var stale []domain.ActionOutcome
+	for _, action := range doc.Plan.Actions {
+		if action.Type == domain.ActionSkip || action.Type == domain.ActionConflict {
+			continue
+		}
+		if err := verify.check(ctx, action); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			stale = append(stale, domain.ActionOutcome{Action: action, Err: err})
+		}
+	}
+	return stale, nil
+}
+
+// checkDocument verifies that a document is intact and fits the configuration
+func (s *SyncService) checkDocument(doc *domain.PlanDocument) error {
+	if doc.Version != domain.PlanFormatVersion {
+		return fmt.Errorf("%w: unsupported version %d", domain.ErrInvalidPlan, doc.Version)
+	}
+	if doc.ComputeFingerprint() != doc.Fingerprint {
+		return fmt.Errorf("%w: content does not match fingerprint", domain.ErrInvalidPlan)
+	}
+	rule, err := s.config.GetRule(doc.Plan.RuleName)
+	if err != nil {
+		return err
+	}
+	if rule.SourceEndpoint != doc.Source.Endpoint || rule.TargetEndpoint != doc.Target.Endpoint {
+		return fmt.Errorf("%w: rule %s now syncs %s to %s, plan was made for %s to %s",
+			domain.ErrInvalidPlan, rule.Name, rule.SourceEndpoint, rule.TargetEndpoint,
+			doc.Source.Endpoint, doc.Target.Endpoint)
+	}
+	return nil
+}
+
+// preconditions checks actions against the file infos recorded in their plan
+type preconditions struct {
+	source, target adapter.Adapter
+
+	// movedAside holds paths a keep-both rename clears for the copy after it
+	movedAside map[domain.SyncDirection]map[string]bool
+}
+
+func newPreconditions(plan *domain.SyncPlan, source, target adapter.Adapter) *preconditions {
+	p := &preconditions{
+		source: source,
+		target: target,
+		movedAside: map[domain.SyncDirection]map[string]bool{
+			domain.DirSourceToTarget: make(map[string]bool),
+			domain.DirTargetToSource: make(map[string]bool),
+		},
+	}
+	for _, action := range plan.Actions {
+		if action.Type == domain.ActionRename {
+			p.movedAside[action.Direction][action.Path] = true
+		}
+	}
+	return p
+}
+
+// check returns an error wrapping domain.ErrPlanStale if a file the action
+// reads or changes is not in the state the plan recorded for it
+func (p *preconditions) check(ctx context.Context, action domain.SyncAction) error {
+	from := endpointState{name: "source", adapter: p.source}
+	to := endpointState{name: "target", adapter: p.target}
+	if action.Direction == domain.DirTargetToSource {
+		from, to = to, from
+	}
+	origin, dest := action.OriginInfo(), action.DestinationInfo()
+
+	switch action.Type {
+	case domain.ActionCopy:
+		if p.movedAside[action.Direction][action.Path] {
+			dest = nil
+		}
+		return firstError(
+			from.expect(ctx, action.Path, origin),
+			to.expect(ctx, action.Path, dest),
+		)
+
+	case domain.ActionMkdir:
+		// Creating a directory that appeared since is harmless
+		info, err := to.adapter.Stat(ctx, action.Path)
+		if errors.Is(err, domain.ErrNotFound) || (err == nil && info.IsDir()) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s was created on %s", domain.ErrPlanStale, action.Path, to.name)
+
+	case domain.ActionDelete:
+		return firstError(
+			from.expect(ctx, action.Path, nil),
+			to.expect(ctx, action.Path, dest),
+		)
+
+	case domain.ActionMove:
+		return firstError(
+			from.expect(ctx, action.NewPath, origin),
+			from.expect(ctx, action.Path, nil),
+			to.expect(ctx, action.Path, dest),
+			to.expect(ctx, action.NewPath, nil),
+		)
+
+	case domain.ActionRename:
+		return firstError(
+			to.expect(ctx, action.Path, dest),
+			to.expect(ctx, action.NewPath, nil),
+		)
+	}
+	return nil
+}
+
+// endpointState checks paths of one endpoint against recorded infos
+type endpointState struct {
+	name    string
+	adapter adapter.Adapter
+}
+
+// expect returns nil if path is in the recorded state: matching want, or
+// missing if want is nil or a tombstone
+func (e endpointState) expect(ctx context.Context, path string, want *domain.FileInfo) error {
+	current, err := e.adapter.Stat(ctx, path)
+	if want == nil || want.IsDeleted {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s was created on %s", domain.ErrPlanStale, path, e.name)
+	}
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("%w: %s was removed from %s", domain.ErrPlanStale, path, e.name)
+	}
+	if err != nil {
+		return err
+	}
+	if !sameState(*want, current) {
+		return fmt.Errorf("%w: %s changed on %s", domain.ErrPlanStale, path, e.name)
+	}
+	return nil
+}
+
+// sameState reports whether a file is unchanged from its recorded info
+// Directories only need to keep their type, since their mtime follows their children
+func sameState(recorded, current domain.FileInfo) bool {
+	if recorded.Type != current.Type {
+		return false
+	}
+	if recorded.IsDir() {
+		return true
+	}
+	if recorded.Size != current.Size || !recorded.ModTime.Equal(current.ModTime) {
+		return false
+	}
+	if recorded.ChecksumComparable(current) && recorded.Checksum != current.Checksum {
+		return false
+	}
+	if recorded.ETag != "" && current.ETag != "" && recorded.ETag != current.ETag {
+		return false
+	}
+	return true
+}
+
+// firstError returns the first non-nil error
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
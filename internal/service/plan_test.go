@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter/memory"
+	"github.com/Ning0612/Syncrules/internal/config"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+func TestSyncService_ApplySavedPlan(t *testing.T) {
+	const store = "service-plan-test"
+	defer memory.DropStore(store)
+
+	src, _ := memory.Store(store).Adapter("src")
+	dst, _ := memory.Store(store).Adapter("dst")
+	ctx := context.Background()
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	src.WriteFile("a.txt", []byte("alpha"), mtime)
+	src.WriteFile("b.txt", []byte("beta"), mtime)
+	src.WriteFile("renamed.txt", []byte("moved content"), mtime)
+	dst.WriteFile("orig.txt", []byte("moved content"), mtime)
+	dst.WriteFile("old.txt", []byte("obsolete"), mtime)
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "dst", Transport: "sim", Root: "dst"},
+			{Name: "other", Transport: "sim", Root: "other"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "push", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks")},
+	}
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	plan, err := svc.PlanSync(ctx, "push")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	plan.Resolved = map[string]domain.ConflictResolution{"x.txt": domain.ResolveKeepBoth}
+	exported, err := svc.ExportPlan(plan)
+	if err != nil {
+		t.Fatalf("ExportPlan failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := exported.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	saved := buf.String()
+
+	// Any edit of the reviewed document is detected
+	tampered := strings.Replace(saved, `"old.txt"`, `"new.txt"`, 1)
+	if _, err := domain.DecodePlanDocument(strings.NewReader(tampered)); !errors.Is(err, domain.ErrInvalidPlan) {
+		t.Errorf("Decode of tampered plan = %v, want ErrInvalidPlan", err)
+	}
+
+	doc, err := domain.DecodePlanDocument(strings.NewReader(saved))
+	if err != nil {
+		t.Fatalf("DecodePlanDocument failed: %v", err)
+	}
+	if doc.Fingerprint != exported.Fingerprint || doc.Source.Endpoint != "src" || len(doc.Source.Files) != 3 ||
+		doc.Plan.Resolved["x.txt"] != domain.ResolveKeepBoth {
+		t.Errorf("Decoded document = %+v", doc)
+	}
+	var move *domain.SyncAction
+	for i, action := range doc.Plan.Actions {
+		if action.Type == domain.ActionMove {
+			move = &doc.Plan.Actions[i]
+		}
+	}
+	if move == nil || move.Path != "orig.txt" || move.NewPath != "renamed.txt" || !move.SourceInfo.ModTime.Equal(mtime) {
+		t.Fatalf("Expected the move to survive the round trip, got %+v", doc.Plan.Actions)
+	}
+
+	// b.txt changes after the plan was approved
+	src.WriteFile("b.txt", []byte("beta, edited"), time.Now())
+
+	stale, err := svc.CheckPlan(ctx, doc)
+	if err != nil {
+		t.Fatalf("CheckPlan failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].Action.Path != "b.txt" || !errors.Is(stale[0].Err, domain.ErrPlanStale) {
+		t.Fatalf("Expected only b.txt to be stale, got %+v", stale)
+	}
+
+	result, err := svc.ApplyPlan(ctx, doc, ExecuteOptions{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Action.Path != "b.txt" || len(result.Succeeded) != 3 {
+		t.Errorf("Result = %+v", result)
+	}
+	got := make(map[string]string)
+	for _, e := range dst.Snapshot() {
+		got[e.Path] = string(e.Content)
+	}
+	if len(got) != 2 || got["a.txt"] != "alpha" || got["renamed.txt"] != "moved content" {
+		t.Errorf("dst snapshot = %v", got)
+	}
+
+	// Plans only apply to the endpoints they were made for
+	cfg.Rules[0].TargetEndpoint = "other"
+	if _, err := svc.ApplyPlan(ctx, doc, ExecuteOptions{}); !errors.Is(err, domain.ErrInvalidPlan) {
+		t.Errorf("ApplyPlan after retargeting = %v, want ErrInvalidPlan", err)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// stageKey identifies the stage an action belongs to
+type stageKey struct {
+	actionType domain.ActionType
+	depth      int
+}
+
+// executionStages splits sorted plan actions into stages that must run in order
+// Actions within a stage are independent and may run concurrently:
+// mkdirs are staged by depth so parents exist first, copies share one stage,
+// and deletes are staged by depth so children go before their parents.
+// Only consecutive actions are grouped, so the plan's order is never violated.
+func executionStages(actions []domain.SyncAction) [][]domain.SyncAction {
+	var stages [][]domain.SyncAction
+	var current []domain.SyncAction
+	var currentKey stageKey
+
+	for _, action := range actions {
+		key := stageKey{actionType: action.Type}
+		if action.Type == domain.ActionMkdir || action.Type == domain.ActionDelete {
+			key.depth = pathDepth(action.Path)
+		}
+
+		if len(current) > 0 && key != currentKey {
+			stages = append(stages, current)
+			current = nil
+		}
+		current = append(current, action)
+		currentKey = key
+	}
+	if len(current) > 0 {
+		stages = append(stages, current)
+	}
+	return stages
+}
+
+// pathDepth returns the nesting depth of a path, matching the planner's ordering
+func pathDepth(path string) int {
+	return strings.Count(path, "/") + strings.Count(path, "\\")
+}
+
+// runStage executes the actions of one stage with at most workers in flight
+// With stopOnError, the context passed to fn is cancelled after the first failure
+// so remaining actions can bail out; the first error is returned once every
+// action has finished
+func runStage(
+	ctx context.Context,
+	actions []domain.SyncAction,
+	workers int,
+	stopOnError bool,
+	fn func(context.Context, domain.SyncAction) error,
+) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, workers)
+
+	for _, action := range actions {
+		sem <- struct{}{}
+
+		wg.Add(1)
+		go func(action domain.SyncAction) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, action); err != nil {
+				once.Do(func() {
+					firstErr = err
+					if stopOnError {
+						cancel()
+					}
+				})
+			}
+		}(action)
+	}
+
+	wg.Wait()
+	return firstErr
+}
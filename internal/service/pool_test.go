@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+func TestExecutionStages_Ordering(t *testing.T) {
+	// Already sorted the way the planner emits them
+	actions := []domain.SyncAction{
+		{Type: domain.ActionMkdir, Path: "a"},
+		{Type: domain.ActionMkdir, Path: "b"},
+		{Type: domain.ActionMkdir, Path: "a/c"},
+		{Type: domain.ActionCopy, Path: "x.txt"},
+		{Type: domain.ActionCopy, Path: "a/c/y.txt"},
+		{Type: domain.ActionDelete, Path: "d/e/z.txt"},
+		{Type: domain.ActionDelete, Path: "d/e"},
+		{Type: domain.ActionDelete, Path: "f/g"},
+		{Type: domain.ActionDelete, Path: "d"},
+		{Type: domain.ActionConflict, Path: "conflict.txt"},
+	}
+
+	stages := executionStages(actions)
+
+	expected := [][]string{
+		{"a", "b"},
+		{"a/c"},
+		{"x.txt", "a/c/y.txt"},
+		{"d/e/z.txt"},
+		{"d/e", "f/g"},
+		{"d"},
+		{"conflict.txt"},
+	}
+	if len(stages) != len(expected) {
+		t.Fatalf("Expected %d stages, got %d: %v", len(expected), len(stages), stages)
+	}
+	for i, stage := range stages {
+		if len(stage) != len(expected[i]) {
+			t.Fatalf("Stage %d: expected %v, got %v", i, expected[i], stage)
+		}
+		for j, action := range stage {
+			if action.Path != expected[i][j] {
+				t.Errorf("Stage %d action %d: expected %s, got %s", i, j, expected[i][j], action.Path)
+			}
+		}
+	}
+}
+
+func TestRunStage_BoundedConcurrency(t *testing.T) {
+	actions := make([]domain.SyncAction, 20)
+	for i := range actions {
+		actions[i] = domain.SyncAction{Type: domain.ActionCopy}
+	}
+
+	var inFlight, maxInFlight, done int32
+	err := runStage(context.Background(), actions, 4, true, func(ctx context.Context, action domain.SyncAction) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		atomic.AddInt32(&done, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runStage failed: %v", err)
+	}
+	if done != 20 {
+		t.Errorf("Expected all 20 actions to run, got %d", done)
+	}
+	if maxInFlight > 4 {
+		t.Errorf("Expected at most 4 actions in flight, got %d", maxInFlight)
+	}
+	if maxInFlight < 2 {
+		t.Errorf("Expected actions to overlap, max in flight was %d", maxInFlight)
+	}
+}
+
+func TestRunStage_StopsOnError(t *testing.T) {
+	actions := make([]domain.SyncAction, 50)
+	for i := range actions {
+		actions[i] = domain.SyncAction{Type: domain.ActionCopy}
+	}
+
+	failure := errors.New("upload failed")
+	var mu sync.Mutex
+	started, cancelled := 0, 0
+	err := runStage(context.Background(), actions, 2, true, func(ctx context.Context, action domain.SyncAction) error {
+		mu.Lock()
+		if ctx.Err() != nil {
+			cancelled++
+			mu.Unlock()
+			return nil
+		}
+		started++
+		n := started
+		mu.Unlock()
+		if n == 3 {
+			return failure
+		}
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected first error to be returned, got %v", err)
+	}
+	if cancelled == 0 || started+cancelled != len(actions) {
+		t.Errorf("Expected remaining actions to see a cancelled context, started=%d cancelled=%d", started, cancelled)
+	}
+}
+
+func TestRunStage_ContinueOnError(t *testing.T) {
+	actions := make([]domain.SyncAction, 10)
+	for i := range actions {
+		actions[i] = domain.SyncAction{Type: domain.ActionCopy}
+	}
+
+	var ran int32
+	err := runStage(context.Background(), actions, 3, false, func(ctx context.Context, action domain.SyncAction) error {
+		if ctx.Err() != nil {
+			t.Error("Context must not be cancelled when continuing on error")
+		}
+		if atomic.AddInt32(&ran, 1) == 1 {
+			return errors.New("first fails")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected the failure to be returned")
+	}
+	if ran != 10 {
+		t.Errorf("Expected all actions to run, got %d", ran)
+	}
+}
@@ -2,27 +2,40 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Ning0612/Syncrules/internal/adapter"
-	"github.com/Ning0612/Syncrules/internal/adapter/gdrive"
-	"github.com/Ning0612/Syncrules/internal/adapter/local"
+	_ "github.com/Ning0612/Syncrules/internal/adapter/builtin"
 	"github.com/Ning0612/Syncrules/internal/config"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/core/conflict"
 	"github.com/Ning0612/Syncrules/internal/core/rule"
 	"github.com/Ning0612/Syncrules/internal/domain"
 	"github.com/Ning0612/Syncrules/internal/lock"
 	"github.com/Ning0612/Syncrules/internal/logger"
 	"github.com/Ning0612/Syncrules/internal/progress"
+	"github.com/Ning0612/Syncrules/internal/state"
 )
 
 // SyncService orchestrates sync operations
 type SyncService struct {
 	config   *config.Config
-	adapters map[string]adapter.Adapter
-	lock     *lock.FileLock
+	mu       sync.Mutex // Guards adapters, held and busy; rules may be planned and run concurrently
+	adapters map[adapterKey]adapter.Adapter
+	lockDir  string
+	held     map[string]*lock.LockSet // Locks taken through AcquireLock, by rule
+	busy     map[string]chan struct{} // Endpoints in use by holdEndpoints, by lock key
 	reporter progress.Reporter
 	executor rule.Executor
+	resolver *conflict.DefaultResolver
+	stateMgr *state.Manager
 }
 
 // NewSyncService creates a new sync service
@@ -32,42 +45,195 @@ func NewSyncService(cfg *config.Config) (*SyncService, error) {
 	}
 
 	lockPath := cfg.GetLockPath()
-	fileLock, err := lock.NewFileLock(lockPath)
+
+	// State manager persists two-way baselines between runs
+	stateMgr, err := state.NewManager(lockPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file lock: %w", err)
+		return nil, fmt.Errorf("failed to create state manager: %w", err)
 	}
 
 	return &SyncService{
 		config:   cfg,
-		adapters: make(map[string]adapter.Adapter),
-		lock:     fileLock,
+		adapters: make(map[adapterKey]adapter.Adapter),
+		lockDir:  lockPath,
+		held:     make(map[string]*lock.LockSet),
+		busy:     make(map[string]chan struct{}),
 		executor: rule.NewDefaultExecutor(),
+		resolver: conflict.NewDefaultResolver(),
+		stateMgr: stateMgr,
 	}, nil
 }
 
-// AcquireLock acquires the sync lock for a specific rule
+// concurrency returns how many actions of a rule may run at once
+// The rule's setting wins; otherwise the stricter of its transports' settings applies
+func (s *SyncService) concurrency(rule *domain.SyncRule) int {
+	if rule.Concurrency > 0 {
+		return rule.Concurrency
+	}
+
+	workers := 0
+	for _, name := range []string{rule.SourceEndpoint, rule.TargetEndpoint} {
+		endpoint, err := s.config.GetEndpoint(name)
+		if err != nil {
+			continue
+		}
+		transport, err := s.config.GetTransport(endpoint.Transport)
+		if err != nil || transport.Concurrency <= 0 {
+			continue
+		}
+		if workers == 0 || transport.Concurrency < workers {
+			workers = transport.Concurrency
+		}
+	}
+
+	if workers == 0 {
+		return 1
+	}
+	return workers
+}
+
+// ruleLocks returns the lock set guarding the endpoints a rule touches
+// Rules over disjoint endpoints run concurrently; rules sharing an endpoint exclude each other
+func (s *SyncService) ruleLocks(ruleName string) (*lock.LockSet, error) {
+	rule, err := s.config.GetRule(ruleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, name := range []string{rule.SourceEndpoint, rule.TargetEndpoint} {
+		key, err := s.endpointLockKey(name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	locks, err := lock.NewLockSet(s.lockDir, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file lock: %w", err)
+	}
+	return locks, nil
+}
+
+// holdEndpoints waits until no other caller of this service holds the rule's
+// endpoints and holds them until the returned function is called
+// The file locks taken for execution turn a second sync of an endpoint away;
+// this lets syncs of one process queue for it instead
+func (s *SyncService) holdEndpoints(ctx context.Context, ruleName string) (func(), error) {
+	locks, err := s.ruleLocks(ruleName)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := locks.Keys()
+	slots := make([]chan struct{}, len(keys))
+	s.mu.Lock()
+	for i, key := range keys {
+		if s.busy[key] == nil {
+			s.busy[key] = make(chan struct{}, 1)
+		}
+		slots[i] = s.busy[key]
+	}
+	s.mu.Unlock()
+
+	release := func(held []chan struct{}) {
+		for i := len(held) - 1; i >= 0; i-- {
+			<-held[i]
+		}
+	}
+	// Keys are sorted, so callers sharing several endpoints cannot deadlock
+	for i, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+		case <-ctx.Done():
+			release(slots[:i])
+			return nil, ctx.Err()
+		}
+	}
+	return func() { release(slots) }, nil
+}
+
+// endpointLockKey identifies the storage location behind an endpoint
+// Endpoints that point at the same location share a key
+func (s *SyncService) endpointLockKey(endpointName string) (string, error) {
+	endpoint, err := s.config.GetEndpoint(endpointName)
+	if err != nil {
+		return "", err
+	}
+
+	transport, err := s.config.GetTransport(endpoint.Transport)
+	if err != nil {
+		return "", err
+	}
+
+	root := endpoint.Root
+	if transport.Type == domain.TransportLocal {
+		// Local endpoints are keyed by absolute path regardless of transport name
+		if abs, err := filepath.Abs(config.ExpandPath(root)); err == nil {
+			root = abs
+		}
+		return fmt.Sprintf("%s:%s", transport.Type, root), nil
+	}
+
+	return fmt.Sprintf("%s:%s:%s", transport.Type, transport.Name, path.Clean("/"+root)), nil
+}
+
+// AcquireLock acquires the sync locks of the endpoints a rule touches
 func (s *SyncService) AcquireLock(ruleName string) error {
-	return s.lock.Acquire(ruleName)
+	locks, err := s.ruleLocks(ruleName)
+	if err != nil {
+		return err
+	}
+	if err := locks.Acquire(ruleName); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.held[ruleName] = locks
+	s.mu.Unlock()
+	return nil
 }
 
-// ReleaseLock releases the sync lock
-func (s *SyncService) ReleaseLock() error {
-	return s.lock.Release()
+// ReleaseLock releases the sync locks acquired for a rule
+func (s *SyncService) ReleaseLock(ruleName string) error {
+	s.mu.Lock()
+	locks, ok := s.held[ruleName]
+	delete(s.held, ruleName)
+	s.mu.Unlock()
+	if !ok {
+		return nil // Not holding lock
+	}
+	return locks.Release()
 }
 
-// IsLocked checks if another sync operation is in progress
-func (s *SyncService) IsLocked() bool {
-	return s.lock.IsLocked()
+// IsLocked checks if a sync operation touching the rule's endpoints is in progress
+func (s *SyncService) IsLocked(ruleName string) bool {
+	locks, err := s.ruleLocks(ruleName)
+	if err != nil {
+		return false
+	}
+	return locks.IsLocked()
 }
 
-// GetLockHolder returns information about the current lock holder
-func (s *SyncService) GetLockHolder() (*lock.LockInfo, error) {
-	return s.lock.GetHolder()
+// GetLockHolders returns information about the holders of the rule's endpoint locks
+func (s *SyncService) GetLockHolders(ruleName string) ([]*lock.LockInfo, error) {
+	locks, err := s.ruleLocks(ruleName)
+	if err != nil {
+		return nil, err
+	}
+	return locks.GetHolders(), nil
 }
 
-// ForceUnlock forcibly releases the lock (use with caution)
-func (s *SyncService) ForceUnlock() error {
-	return s.lock.ForceRelease()
+// ForceUnlock forcibly releases the rule's endpoint locks (use with caution)
+func (s *SyncService) ForceUnlock(ruleName string) error {
+	locks, err := s.ruleLocks(ruleName)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.held, ruleName)
+	s.mu.Unlock()
+	return locks.ForceRelease()
 }
 
 // SetProgressReporter sets the progress reporter for sync operations
@@ -83,9 +249,53 @@ func (s *SyncService) getReporter() progress.Reporter {
 	return progress.NullReporter{}
 }
 
-// getAdapter returns or creates an adapter for the given endpoint
+// checksumConfigurable is implemented by adapters whose checksum algorithm can be chosen
+type checksumConfigurable interface {
+	SetChecksumAlgorithm(algo checksum.Algorithm) error
+}
+
+// pairChecksum picks the checksum algorithm for comparing two endpoints
+// The configured algorithm is kept if both can produce it; otherwise the
+// first algorithm both support wins, preferring those a server computes
+// without reading the content
+func pairChecksum(preferred checksum.Algorithm, src, tgt adapter.Capabilities) checksum.Algorithm {
+	if src.SupportsChecksum(preferred) && tgt.SupportsChecksum(preferred) {
+		return preferred
+	}
+
+	first, second := src, tgt
+	if tgt.ServerChecksums && !src.ServerChecksums {
+		first, second = tgt, src
+	}
+	for _, algo := range first.Checksums {
+		if second.SupportsChecksum(algo) {
+			return algo
+		}
+	}
+	return preferred
+}
+
+// adapterKey identifies a cached adapter: one per endpoint and checksum
+// algorithm, so rules comparing with different algorithms never share one
+type adapterKey struct {
+	endpoint  string
+	algorithm checksum.Algorithm
+}
+
+// getAdapter returns or creates an adapter for the given endpoint, using the
+// configured checksum algorithm
 func (s *SyncService) getAdapter(endpointName string) (adapter.Adapter, error) {
-	if a, ok := s.adapters[endpointName]; ok {
+	return s.getAdapterWith(endpointName, s.config.GetChecksumAlgorithm())
+}
+
+// getAdapterWith returns or creates an adapter for the given endpoint that
+// computes checksums with algo, if its backend lets the algorithm be chosen
+func (s *SyncService) getAdapterWith(endpointName string, algo checksum.Algorithm) (adapter.Adapter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := adapterKey{endpoint: endpointName, algorithm: algo}
+	if a, ok := s.adapters[key]; ok {
 		return a, nil
 	}
 
@@ -99,33 +309,20 @@ func (s *SyncService) getAdapter(endpointName string) (adapter.Adapter, error) {
 		return nil, err
 	}
 
-	var a adapter.Adapter
-	switch transport.Type {
-	case domain.TransportLocal:
-		a, err = local.New(endpoint.Root)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create local adapter for %s: %w", endpointName, err)
-		}
-	case domain.TransportGDrive:
-		// Get OAuth credentials from transport config
-		clientID := transport.Config["client_id"]
-		clientSecret := transport.Config["client_secret"]
-		tokenPath := transport.Config["token_path"]
-
-		if clientID == "" || clientSecret == "" {
-			return nil, fmt.Errorf("gdrive transport requires client_id and client_secret in config")
-		}
+	a, err := adapter.Default().Create(*transport, endpoint.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s adapter for %s: %w", transport.Type, endpointName, err)
+	}
 
-		ctx := context.Background()
-		a, err = gdrive.New(ctx, clientID, clientSecret, tokenPath, endpoint.Root)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gdrive adapter for %s: %w", endpointName, err)
+	// Backends that compute checksums use the requested algorithm
+	if cs, ok := a.(checksumConfigurable); ok {
+		if err := cs.SetChecksumAlgorithm(algo); err != nil {
+			a.Close()
+			return nil, fmt.Errorf("%s adapter for %s: %w", transport.Type, endpointName, err)
 		}
-	default:
-		return nil, fmt.Errorf("unknown transport type: %s", transport.Type)
 	}
 
-	s.adapters[endpointName] = a
+	s.adapters[key] = a
 	return a, nil
 }
 
@@ -159,107 +356,406 @@ func (s *SyncService) PlanSync(ctx context.Context, ruleName string) (*domain.Sy
 		return nil, fmt.Errorf("target endpoint: %w", err)
 	}
 
+	// Compare with a checksum both endpoints can produce, through adapters of
+	// that algorithm rather than by reconfiguring ones other rules may be using
+	algo := pairChecksum(s.config.GetChecksumAlgorithm(),
+		adapter.CapabilitiesOf(sourceAdapter), adapter.CapabilitiesOf(targetAdapter))
+	if algo != s.config.GetChecksumAlgorithm() {
+		if sourceAdapter, err = s.getAdapterWith(rule.SourceEndpoint, algo); err != nil {
+			return nil, fmt.Errorf("source endpoint: %w", err)
+		}
+		if targetAdapter, err = s.getAdapterWith(rule.TargetEndpoint, algo); err != nil {
+			return nil, fmt.Errorf("target endpoint: %w", err)
+		}
+	}
+	logger.Get().Debug("checksum strategy", "rule", ruleName, "algorithm", algo)
+
+	// Two-way sync needs the last synced tree to tell deletions from creations
+	var baseline map[string]domain.FileInfo
+	if rule.Mode == domain.SyncModeTwoWay {
+		baseline, err = s.stateMgr.GetBaseline(rule.Name)
+		if err != nil {
+			logger.Get().Error("failed to load baseline", "rule", ruleName, "error", err)
+			return nil, err
+		}
+	}
+
 	logger.Get().Debug("delegating to executor", "rule", ruleName)
 
 	// Delegate to core/rule executor
-	plan, err := s.executor.Plan(ctx, rule, sourceAdapter, targetAdapter)
+	plan, err := s.executor.PlanWithBaseline(ctx, rule, sourceAdapter, targetAdapter, baseline)
 	if err != nil {
 		logger.Get().Error("executor plan failed", "rule", ruleName, "error", err)
 		return nil, err
 	}
 
+	// Apply the user's conflict resolutions and remember what is still unresolved
+	if err := s.applyResolutions(plan); err != nil {
+		logger.Get().Error("failed to apply conflict resolutions", "rule", ruleName, "error", err)
+		return nil, err
+	}
+
 	logger.Get().Info("sync plan created",
 		"rule", ruleName,
 		"files_to_copy", plan.Stats.FilesToCopy,
 		"files_to_delete", plan.Stats.FilesToDelete,
 		"bytes_to_sync", plan.Stats.BytesToSync,
+		"conflicts", plan.Stats.Conflicts,
+		"resolved", len(plan.Resolved),
 	)
 
 	return plan, nil
 }
 
-// ExecuteSync executes a sync plan
+// ExecuteOptions controls how a sync plan is executed
+type ExecuteOptions struct {
+	// ContinueOnError keeps executing the remaining actions after one fails
+	// Actions that depend on a failed one (e.g., copies into a directory that
+	// could not be created) are skipped instead
+	ContinueOnError bool
+
+	// Force executes plans that delete more than the rule's max_deletes allows
+	Force bool
+
+	// VerifyPreconditions compares the files each action reads or changes with
+	// the infos recorded in the plan right before running it; actions whose
+	// files changed are skipped with domain.ErrPlanStale
+	VerifyPreconditions bool
+}
+
+var (
+	// errAborted marks actions not run because an earlier action failed
+	errAborted = errors.New("aborted after an earlier failure")
+)
+
+// ExecuteSync executes a sync plan, stopping at the first failed action
+// Plans over the rule's deletion limit are refused with domain.ErrTooManyDeletions
 func (s *SyncService) ExecuteSync(ctx context.Context, plan *domain.SyncPlan) error {
-	logger.Get().Debug("executing sync", "rule", plan.RuleName)
+	_, err := s.ExecuteSyncWithOptions(ctx, plan, ExecuteOptions{})
+	return err
+}
+
+// ExecuteSyncWithOptions executes a sync plan and reports the outcome of every action
+// The error is non-nil if the run could not start, was cancelled, or, without
+// ContinueOnError, an action failed; with ContinueOnError action failures are
+// only reported in the result
+func (s *SyncService) ExecuteSyncWithOptions(ctx context.Context, plan *domain.SyncPlan, opts ExecuteOptions) (*domain.SyncResult, error) {
+	logger.Get().Debug("executing sync", "rule", plan.RuleName, "continue_on_error", opts.ContinueOnError)
 
-	// Acquire lock before executing sync
-	logger.Get().Info("acquiring lock", "rule", plan.RuleName)
-	if err := s.lock.Acquire(plan.RuleName); err != nil {
+	// Acquire the endpoint locks before executing sync
+	locks, err := s.ruleLocks(plan.RuleName)
+	if err != nil {
+		return nil, err
+	}
+	logger.Get().Info("acquiring lock", "rule", plan.RuleName, "keys", locks.Keys())
+	if err := locks.Acquire(plan.RuleName); err != nil {
 		logger.Get().Error("failed to acquire sync lock", "rule", plan.RuleName, "error", err)
-		return fmt.Errorf("failed to acquire sync lock: %w", err)
+		return nil, fmt.Errorf("failed to acquire sync lock: %w", err)
 	}
 	defer func() {
-		if err := s.lock.Release(); err != nil {
+		if err := locks.Release(); err != nil {
 			logger.Get().Error("failed to release sync lock", "rule", plan.RuleName, "error", err)
 		}
 	}()
 
 	rule, err := s.config.GetRule(plan.RuleName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// An emptied or unmounted root looks like everything was deleted
+	if err := rule.CheckDeletions(plan.Stats); err != nil {
+		if !opts.Force {
+			logger.Get().Error("refusing plan over the deletion limit", "rule", plan.RuleName, "error", err)
+			return nil, err
+		}
+		logger.Get().Warn("forcing plan over the deletion limit", "rule", plan.RuleName, "error", err)
 	}
 
 	sourceAdapter, err := s.getAdapter(rule.SourceEndpoint)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	targetAdapter, err := s.getAdapter(rule.TargetEndpoint)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set total progress
 	reporter := s.getReporter()
 	reporter.SetTotal(plan.Stats.FilesToCopy, plan.Stats.BytesToSync)
 
-	filesCompleted := 0
-	bytesCompleted := int64(0)
+	workers := s.concurrency(rule)
+	if _, ok := reporter.(progress.ConcurrentReporter); !ok && workers > 1 {
+		// The reporter tracks a single current transfer; keep its view consistent
+		logger.Get().Debug("progress reporter is not concurrent, running sequentially",
+			"rule", plan.RuleName,
+		)
+		workers = 1
+	}
 
-	for _, action := range plan.Actions {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	var (
+		mu         sync.Mutex // Guards result, unfinished and overall progress
+		result     = &domain.SyncResult{RuleName: plan.RuleName}
+		unfinished []string // Paths of failed or skipped actions
+		parentCtx  = ctx
+		started    = time.Now()
+		trashDir   string // Where this run keeps removed files ("" = discard them)
+	)
+	if trashEnabled(rule) {
+		trashDir = trashRunDir(started)
+	}
+	var verify *preconditions
+	if opts.VerifyPreconditions {
+		verify = newPreconditions(plan, sourceAdapter, targetAdapter)
+	}
+
+	skip := func(action domain.SyncAction, reason error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Skipped = append(result.Skipped, domain.ActionOutcome{Action: action, Err: reason})
+		unfinished = append(unfinished, actionPaths(action)...)
+	}
+
+	// blockedPath returns the unfinished path an action depends on ("" if none)
+	// Children depend on their parent directory, a directory delete on its children,
+	// a copy over a conflict loser on the loser's rename, and a move on both of its paths
+	// Caller must hold mu
+	blockedPath := func(action domain.SyncAction) string {
+		for _, path := range actionPaths(action) {
+			for _, p := range unfinished {
+				if p == path || isWithin(path, p) || isWithin(p, path) {
+					return p
+				}
+			}
 		}
+		return ""
+	}
 
-		if err := s.executeAction(ctx, action, sourceAdapter, targetAdapter, reporter); err != nil {
-			reporter.Error(err)
-			return fmt.Errorf("action %s on %s: %w", action.Type, action.Path, err)
+	run := func(ctx context.Context, action domain.SyncAction) error {
+		if action.Type == domain.ActionSkip || action.Type == domain.ActionConflict {
+			return nil
 		}
 
-		// Update overall progress
-		if action.Type == domain.ActionCopy {
-			filesCompleted++
-			if action.SourceInfo != nil {
-				bytesCompleted += action.SourceInfo.Size
-			} else if action.TargetInfo != nil {
-				bytesCompleted += action.TargetInfo.Size
+		if ctx.Err() != nil {
+			if parentCtx.Err() != nil {
+				skip(action, parentCtx.Err())
+			} else {
+				skip(action, errAborted)
+			}
+			return nil
+		}
+
+		mu.Lock()
+		blocker := blockedPath(action)
+		mu.Unlock()
+		if blocker != "" {
+			skip(action, fmt.Errorf("depends on unfinished action on %s", blocker))
+			return nil
+		}
+
+		if verify != nil {
+			if err := verify.check(ctx, action); err != nil {
+				skip(action, err)
+				return nil
+			}
+		}
+
+		if err := s.executeAction(ctx, action, sourceAdapter, targetAdapter, trashDir, reporter); err != nil {
+			// Actions interrupted by cancellation were not really attempted
+			if ctx.Err() != nil {
+				if parentCtx.Err() != nil {
+					skip(action, parentCtx.Err())
+				} else {
+					skip(action, errAborted)
+				}
+				return nil
 			}
-			reporter.OverallProgress(filesCompleted, bytesCompleted)
 
-			logger.Get().Debug("action executed",
+			mu.Lock()
+			// Copies report their own failure on the transfer
+			if action.Type != domain.ActionCopy {
+				reporter.Error(err)
+			}
+			result.Failed = append(result.Failed, domain.ActionOutcome{Action: action, Err: err})
+			unfinished = append(unfinished, actionPaths(action)...)
+			mu.Unlock()
+
+			logger.Get().Warn("action failed",
 				"rule", plan.RuleName,
 				"action", action.Type,
 				"path", action.Path,
+				"error", err,
 			)
+
+			if opts.ContinueOnError {
+				return nil
+			}
+			return fmt.Errorf("action %s on %s: %w", action.Type, action.Path, err)
+		}
+
+		mu.Lock()
+		result.Succeeded = append(result.Succeeded, action)
+
+		// Update overall progress
+		if action.Type == domain.ActionCopy {
+			result.FilesSynced++
+			if info := action.OriginInfo(); info != nil {
+				result.BytesSynced += info.Size
+			}
+			reporter.OverallProgress(result.FilesSynced, result.BytesSynced)
+		}
+		mu.Unlock()
+
+		logger.Get().Debug("action executed",
+			"rule", plan.RuleName,
+			"action", action.Type,
+			"path", action.Path,
+		)
+		return nil
+	}
+
+	// Stages run in plan order; actions within a stage run on the worker pool
+	var runErr error
+	for _, stage := range executionStages(plan.Actions) {
+		if runErr != nil || ctx.Err() != nil {
+			// Nothing more is attempted; record the rest as skipped
+			for _, action := range stage {
+				if action.Type == domain.ActionSkip || action.Type == domain.ActionConflict {
+					continue
+				}
+				if ctx.Err() != nil {
+					skip(action, ctx.Err())
+				} else {
+					skip(action, errAborted)
+				}
+			}
+			continue
+		}
+		runErr = runStage(ctx, stage, workers, !opts.ContinueOnError, run)
+	}
+	if runErr == nil {
+		runErr = ctx.Err()
+	}
+
+	// Retention only removes whole runs; failing to apply it doesn't fail the sync
+	if trashEnabled(rule) && ctx.Err() == nil {
+		for _, a := range []adapter.Adapter{sourceAdapter, targetAdapter} {
+			if err := pruneTrash(ctx, a, rule.Trash, started); err != nil {
+				logger.Get().Warn("failed to prune trash", "rule", plan.RuleName, "error", err)
+			}
+		}
+	}
+
+	// Record the synced tree so the next two-way run can propagate deletions
+	// Unfinished paths keep their previous state so they are retried next run
+	if plan.Baseline != nil {
+		baseline := plan.Baseline
+		if len(unfinished) > 0 {
+			previous, err := s.stateMgr.GetBaseline(plan.RuleName)
+			if err != nil {
+				logger.Get().Error("failed to load baseline", "rule", plan.RuleName, "error", err)
+				return result, fmt.Errorf("failed to load baseline: %w", err)
+			}
+			baseline = mergeBaseline(plan.Baseline, previous, unfinished)
+		}
+		if err := s.stateMgr.SaveBaseline(plan.RuleName, baseline); err != nil {
+			logger.Get().Error("failed to save baseline", "rule", plan.RuleName, "error", err)
+			return result, fmt.Errorf("failed to save baseline: %w", err)
 		}
 	}
 
+	// Resolutions that were carried out are done with
+	for path, resolution := range plan.Resolved {
+		if resolution == domain.ResolveIgnore || isUnfinished(path, unfinished) {
+			continue
+		}
+		if err := s.stateMgr.DeleteConflict(plan.RuleName, path); err != nil {
+			logger.Get().Error("failed to clear resolved conflict", "rule", plan.RuleName, "path", path, "error", err)
+			return result, err
+		}
+	}
+
+	if runErr != nil {
+		return result, runErr
+	}
+
+	if result.HasFailures() {
+		logger.Get().Warn("sync execution completed with failures",
+			"rule", plan.RuleName,
+			"succeeded", len(result.Succeeded),
+			"failed", len(result.Failed),
+			"skipped", len(result.Skipped),
+			"files_synced", result.FilesSynced,
+			"bytes_synced", result.BytesSynced,
+		)
+		return result, nil
+	}
+
 	logger.Get().Info("sync execution completed",
 		"rule", plan.RuleName,
-		"files_synced", filesCompleted,
-		"bytes_synced", bytesCompleted,
+		"files_synced", result.FilesSynced,
+		"bytes_synced", result.BytesSynced,
 	)
 
-	return nil
+	return result, nil
+}
+
+// mergeBaseline returns the planned baseline with every unfinished path
+// (and everything beneath it) reverted to its previous baseline state
+func mergeBaseline(planned, previous map[string]domain.FileInfo, unfinished []string) map[string]domain.FileInfo {
+	merged := make(map[string]domain.FileInfo, len(planned))
+	for path, info := range planned {
+		merged[path] = info
+	}
+
+	for _, root := range unfinished {
+		for path := range merged {
+			if path == root || isWithin(path, root) {
+				delete(merged, path)
+			}
+		}
+		for path, info := range previous {
+			if path == root || isWithin(path, root) {
+				merged[path] = info
+			}
+		}
+	}
+	return merged
+}
+
+// actionPaths returns the paths an action touches: its path, and for
+// renames and moves also the new path
+func actionPaths(action domain.SyncAction) []string {
+	if action.NewPath != "" {
+		return []string{action.Path, action.NewPath}
+	}
+	return []string{action.Path}
+}
+
+// isUnfinished reports whether path or one of its parents is unfinished
+func isUnfinished(path string, unfinished []string) bool {
+	for _, p := range unfinished {
+		if p == path || isWithin(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin reports whether path lies strictly beneath dir
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path, dir+"/")
 }
 
 // executeAction performs a single sync action with correct direction
+// With a trashDir, files the action deletes or overwrites are moved there first
 func (s *SyncService) executeAction(
 	ctx context.Context,
 	action domain.SyncAction,
 	sourceAdapter, targetAdapter adapter.Adapter,
+	trashDir string,
 	reporter progress.Reporter,
 ) error {
 	// Determine actual from/to adapters based on direction
@@ -271,41 +767,81 @@ func (s *SyncService) executeAction(
 		fromAdapter = targetAdapter
 		toAdapter = sourceAdapter
 	}
+	fromInfo := action.OriginInfo()
 
 	switch action.Type {
 	case domain.ActionCopy:
 		// Get file size for progress reporting
 		var fileSize int64
-		if action.SourceInfo != nil {
-			fileSize = action.SourceInfo.Size
-		} else if action.TargetInfo != nil {
-			fileSize = action.TargetInfo.Size
+		if fromInfo != nil {
+			fileSize = fromInfo.Size
 		}
 
-		reporter.Start(action.Path, fileSize)
+		transfer := progress.StartTransfer(reporter, action.Path, fileSize)
 
 		reader, err := fromAdapter.Read(ctx, action.Path)
 		if err != nil {
-			reporter.Error(err)
+			transfer.Error(err)
 			return err
 		}
 		defer reader.Close()
 
+		// Keep the version about to be overwritten; it is put back if the
+		// new content cannot be written, so a failed copy loses nothing
+		trashed := false
+		if dest := action.DestinationInfo(); trashDir != "" && dest != nil && dest.IsFile() {
+			if err := moveToTrash(ctx, toAdapter, trashDir, action.Path); err != nil {
+				transfer.Error(err)
+				return err
+			}
+			trashed = true
+		}
+		fail := func(err error) error {
+			transfer.Error(err)
+			if trashed {
+				if restoreErr := restoreFromTrash(ctx, toAdapter, trashDir, action.Path); restoreErr != nil {
+					return fmt.Errorf("%w (previous version left in trash: %v)", err, restoreErr)
+				}
+			}
+			return err
+		}
+
 		// Wrap reader with progress tracking
-		progressReader := progress.NewProgressReader(reader, reporter)
+		progressReader := progress.NewProgressReader(reader, transfer)
 
 		if err := toAdapter.Write(ctx, action.Path, progressReader); err != nil {
-			reporter.Error(err)
-			return err
+			return fail(err)
+		}
+		if fromInfo != nil {
+			if err := preserveModTime(ctx, toAdapter, action.Path, fromInfo.ModTime); err != nil {
+				return fail(err)
+			}
 		}
 
-		reporter.Complete()
+		transfer.Complete()
 		return nil
 
 	case domain.ActionMkdir:
 		return toAdapter.Mkdir(ctx, action.Path)
 
+	case domain.ActionRename:
+		return renameFile(ctx, toAdapter, action.Path, action.NewPath)
+
+	case domain.ActionMove:
+		// The moved file has the origin's content; give it the origin's mtime too
+		if err := renameFile(ctx, toAdapter, action.Path, action.NewPath); err != nil {
+			return err
+		}
+		if fromInfo != nil {
+			return preserveModTime(ctx, toAdapter, action.NewPath, fromInfo.ModTime)
+		}
+		return nil
+
 	case domain.ActionDelete:
+		// Directories are empty by now; their files went to the trash already
+		if dest := action.DestinationInfo(); trashDir != "" && dest != nil && !dest.IsDir() {
+			return moveToTrash(ctx, toAdapter, trashDir, action.Path)
+		}
 		return toAdapter.Delete(ctx, action.Path)
 
 	case domain.ActionSkip, domain.ActionConflict:
@@ -316,14 +852,83 @@ func (s *SyncService) executeAction(
 	}
 }
 
-// Close releases all adapters
+// preserveModTime gives a copied file its source's mtime, if the adapter
+// supports setting it
+func preserveModTime(ctx context.Context, a adapter.Adapter, path string, modTime time.Time) error {
+	setter, ok := a.(adapter.ModTimeSetter)
+	if !ok || modTime.IsZero() {
+		return nil
+	}
+	if err := setter.SetModTime(ctx, path, modTime); err != nil {
+		return fmt.Errorf("failed to set mtime of %s: %w", path, err)
+	}
+	return nil
+}
+
+// renameFile moves a file within one adapter, server-side if the adapter
+// supports it and otherwise by copying it to its new path and removing the
+// original; an existing file at the new path is not replaced
+func renameFile(ctx context.Context, a adapter.Adapter, from, to string) error {
+	if to == "" || to == from {
+		return fmt.Errorf("invalid rename of %s to %q", from, to)
+	}
+	if adapter.CapabilitiesOf(a).Rename {
+		return a.(adapter.Renamer).Rename(ctx, from, to)
+	}
+	exists, err := a.Exists(ctx, to)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyExists, to)
+	}
+
+	info, err := a.Stat(ctx, from)
+	if err != nil {
+		return err
+	}
+	reader, err := a.Read(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := a.Write(ctx, to, reader); err != nil {
+		return err
+	}
+	if err := preserveModTime(ctx, a, to, info.ModTime); err != nil {
+		return err
+	}
+	return a.Delete(ctx, from)
+}
+
+// Close releases all adapters and the state manager
 func (s *SyncService) Close() error {
 	var lastErr error
+	s.mu.Lock()
+	held := make([]string, 0, len(s.held))
+	for ruleName := range s.held {
+		held = append(held, ruleName)
+	}
+	s.mu.Unlock()
+	for _, ruleName := range held {
+		if err := s.ReleaseLock(ruleName); err != nil {
+			lastErr = err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for _, a := range s.adapters {
 		if err := a.Close(); err != nil {
 			lastErr = err
 		}
 	}
+	if s.stateMgr != nil {
+		if err := s.stateMgr.Close(); err != nil {
+			lastErr = err
+		}
+	}
 	return lastErr
 }
 
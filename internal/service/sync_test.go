@@ -0,0 +1,918 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/adapter/memory"
+	"github.com/Ning0612/Syncrules/internal/config"
+	"github.com/Ning0612/Syncrules/internal/core/checksum"
+	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/lock"
+	"github.com/Ning0612/Syncrules/internal/progress"
+)
+
+func TestSyncService_EndpointLocks(t *testing.T) {
+	root := t.TempDir()
+	cfg := &config.Config{
+		Transports: []domain.Transport{
+			{Name: "local", Type: domain.TransportLocal},
+			{Name: "other-local", Type: domain.TransportLocal},
+		},
+		Endpoints: []domain.Endpoint{
+			{Name: "a", Transport: "local", Root: filepath.Join(root, "a")},
+			{Name: "b", Transport: "local", Root: filepath.Join(root, "b")},
+			{Name: "c", Transport: "local", Root: filepath.Join(root, "c")},
+			{Name: "d", Transport: "local", Root: filepath.Join(root, "d")},
+			{Name: "b-alias", Transport: "other-local", Root: filepath.Join(root, "b")},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "a-to-b", SourceEndpoint: "a", TargetEndpoint: "b"},
+			{Name: "c-to-d", SourceEndpoint: "c", TargetEndpoint: "d"},
+			{Name: "c-to-b-alias", SourceEndpoint: "c", TargetEndpoint: "b-alias"},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(root, "locks")},
+	}
+
+	// Separate services behave like separate processes
+	newService := func() *SyncService {
+		svc, err := NewSyncService(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create sync service: %v", err)
+		}
+		t.Cleanup(func() { svc.Close() })
+		return svc
+	}
+	svc1, svc2, svc3 := newService(), newService(), newService()
+
+	if err := svc1.AcquireLock("a-to-b"); err != nil {
+		t.Fatalf("AcquireLock failed: %v", err)
+	}
+
+	// Disjoint endpoints run concurrently
+	if err := svc2.AcquireLock("c-to-d"); err != nil {
+		t.Fatalf("Rule over disjoint endpoints should not be blocked: %v", err)
+	}
+	svc2.ReleaseLock("c-to-d")
+
+	// Writing the same directory through another endpoint is excluded
+	err := svc3.AcquireLock("c-to-b-alias")
+	if !lock.IsLockError(err) {
+		t.Fatalf("Expected lock error for shared endpoint, got %v", err)
+	}
+	if !svc3.IsLocked("c-to-b-alias") {
+		t.Error("Expected rule sharing an endpoint to report locked")
+	}
+
+	holders, err := svc3.GetLockHolders("c-to-b-alias")
+	if err != nil {
+		t.Fatalf("GetLockHolders failed: %v", err)
+	}
+	if len(holders) != 1 || holders[0].RuleName != "a-to-b" {
+		t.Errorf("Expected a-to-b as the only holder, got %+v", holders)
+	}
+
+	if err := svc1.ReleaseLock("a-to-b"); err != nil {
+		t.Fatalf("ReleaseLock failed: %v", err)
+	}
+	if err := svc3.AcquireLock("c-to-b-alias"); err != nil {
+		t.Fatalf("AcquireLock after release failed: %v", err)
+	}
+	svc3.ReleaseLock("c-to-b-alias")
+}
+
+func TestSyncService_ExecuteSyncConcurrent(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "dst")
+
+	// Nested tree so mkdirs must precede copies
+	for i := 0; i < 5; i++ {
+		dir := filepath.Join(src, fmt.Sprintf("dir%d", i), "nested")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 4; j++ {
+			content := strings.Repeat("x", 100*(j+1))
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", j)), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "local", Type: domain.TransportLocal, Concurrency: 4}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "local", Root: src},
+			{Name: "dst", Transport: "local", Root: dst},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "push", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(root, "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	var mu sync.Mutex
+	var last progress.Update
+	completed := 0
+	svc.SetProgressReporter(progress.NewCallbackReporter(func(u progress.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		if u.Type == progress.UpdateComplete {
+			completed++
+		}
+		if u.Type == progress.UpdateOverall {
+			last = u
+		}
+	}))
+
+	if w := svc.concurrency(&cfg.Rules[0]); w != 4 {
+		t.Fatalf("Expected transport concurrency 4, got %d", w)
+	}
+
+	plan, err := svc.PlanSync(context.Background(), "push")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if err := svc.ExecuteSync(context.Background(), plan); err != nil {
+		t.Fatalf("ExecuteSync failed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 4; j++ {
+			data, err := os.ReadFile(filepath.Join(dst, fmt.Sprintf("dir%d", i), "nested", fmt.Sprintf("f%d.txt", j)))
+			if err != nil {
+				t.Fatalf("Expected file to be synced: %v", err)
+			}
+			if len(data) != 100*(j+1) {
+				t.Errorf("Unexpected size %d for f%d.txt", len(data), j)
+			}
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if completed != 20 {
+		t.Errorf("Expected 20 completed transfers, got %d", completed)
+	}
+	if last.FilesCompleted != 20 || last.BytesCompleted != plan.Stats.BytesToSync {
+		t.Errorf("Unexpected final overall progress: %+v (planned %d bytes)", last, plan.Stats.BytesToSync)
+	}
+}
+
+// failingAdapter wraps an adapter and fails writes and mkdirs of chosen paths
+type failingAdapter struct {
+	adapter.Adapter
+	fail map[string]bool
+}
+
+func (a *failingAdapter) Write(ctx context.Context, path string, r io.Reader) error {
+	if a.fail[path] {
+		return errors.New("injected write failure")
+	}
+	return a.Adapter.Write(ctx, path, r)
+}
+
+func (a *failingAdapter) Mkdir(ctx context.Context, path string) error {
+	if a.fail[path] {
+		return errors.New("injected mkdir failure")
+	}
+	return a.Adapter.Mkdir(ctx, path)
+}
+
+// newPushFixture creates a one-way push config over a small tree:
+// ok1.txt, ok2.txt, bad.txt, dir/inner.txt
+func newPushFixture(t *testing.T) (*config.Config, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "dst")
+	for _, dir := range []string{filepath.Join(src, "dir"), dst} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{"ok1.txt": "one", "ok2.txt": "two!", "bad.txt": "bad", "dir/inner.txt": "inner"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "local", Type: domain.TransportLocal}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "local", Root: src},
+			{Name: "dst", Transport: "local", Root: dst},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "push", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(root, "locks")},
+	}
+	return cfg, dst
+}
+
+// injectFailures replaces the target adapter with one failing the given paths
+func injectFailures(t *testing.T, svc *SyncService, paths ...string) {
+	t.Helper()
+
+	target, err := svc.getAdapter("dst")
+	if err != nil {
+		t.Fatalf("getAdapter failed: %v", err)
+	}
+	fail := make(map[string]bool)
+	for _, p := range paths {
+		fail[p] = true
+	}
+	svc.adapters[adapterKey{endpoint: "dst", algorithm: svc.config.GetChecksumAlgorithm()}] = &failingAdapter{Adapter: target, fail: fail}
+}
+
+func TestSyncService_ExecuteContinueOnError(t *testing.T) {
+	cfg, dst := newPushFixture(t)
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+	injectFailures(t, svc, "bad.txt", "dir")
+
+	plan, err := svc.PlanSync(context.Background(), "push")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+
+	// Fail-fast execution reports the first failure
+	if err := svc.ExecuteSync(context.Background(), plan); err == nil {
+		t.Fatal("Expected ExecuteSync to fail")
+	}
+
+	result, err := svc.ExecuteSyncWithOptions(context.Background(), plan, ExecuteOptions{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("ExecuteSyncWithOptions failed: %v", err)
+	}
+
+	if len(result.Failed) != 2 {
+		t.Errorf("Expected mkdir dir and copy bad.txt to fail, got %+v", result.Failed)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Action.Path != "dir/inner.txt" || result.Skipped[0].Err == nil {
+		t.Errorf("Expected dir/inner.txt to be skipped with a reason, got %+v", result.Skipped)
+	}
+	if result.FilesSynced != 2 || result.BytesSynced != int64(len("one")+len("two!")) {
+		t.Errorf("Expected actual counts for the 2 copied files, got %d files / %d bytes",
+			result.FilesSynced, result.BytesSynced)
+	}
+	if result.Status() != "partial" {
+		t.Errorf("Expected partial status, got %s", result.Status())
+	}
+	if result.Summary() == "" {
+		t.Error("Expected a failure summary")
+	}
+
+	for _, name := range []string{"ok1.txt", "ok2.txt"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); err != nil {
+			t.Errorf("Expected %s to be synced despite failures: %v", name, err)
+		}
+	}
+}
+
+func TestSyncRunner_RecordsPartial(t *testing.T) {
+	cfg, _ := newPushFixture(t)
+
+	daemon, err := NewDaemonService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create daemon service: %v", err)
+	}
+	defer daemon.Close()
+	injectFailures(t, daemon.syncSvc, "bad.txt")
+
+	if err := daemon.newSyncRunner().RunSync(context.Background(), "push"); err == nil {
+		t.Error("Expected RunSync to report the failed action")
+	}
+
+	history, err := daemon.stateMgr.GetHistory("push", 1)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Expected one execution record, got %v (%v)", history, err)
+	}
+	record := history[0]
+	if record.Status != "partial" {
+		t.Errorf("Expected partial status, got %s", record.Status)
+	}
+	if record.FilesSynced != 3 || record.BytesSynced != int64(len("one")+len("two!")+len("inner")) {
+		t.Errorf("Expected actual counts, got %d files / %d bytes", record.FilesSynced, record.BytesSynced)
+	}
+	if record.Error == "" {
+		t.Error("Expected the failure to be recorded")
+	}
+}
+
+func TestMergeBaseline(t *testing.T) {
+	planned := map[string]domain.FileInfo{
+		"a.txt":     {Path: "a.txt", Size: 2},
+		"dir":       {Path: "dir", Type: domain.FileTypeDirectory},
+		"dir/b.txt": {Path: "dir/b.txt", Size: 2},
+		"new.txt":   {Path: "new.txt", Size: 1},
+	}
+	previous := map[string]domain.FileInfo{
+		"a.txt":     {Path: "a.txt", Size: 1},
+		"dir":       {Path: "dir", Type: domain.FileTypeDirectory},
+		"dir/b.txt": {Path: "dir/b.txt", Size: 1},
+	}
+
+	merged := mergeBaseline(planned, previous, []string{"dir", "new.txt"})
+
+	if merged["a.txt"].Size != 2 {
+		t.Error("Finished paths should take the planned state")
+	}
+	if merged["dir/b.txt"].Size != 1 {
+		t.Error("Paths beneath an unfinished directory should keep their previous state")
+	}
+	if _, ok := merged["new.txt"]; ok {
+		t.Error("Unfinished paths without previous state should be dropped")
+	}
+}
+
+func TestSyncService_KeepBoth(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "dst")
+	for _, dir := range []string{src, dst} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both sides edited; the target edit is newer
+	older := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.WriteFile(filepath.Join(src, "app.conf"), []byte("source edit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "app.conf"), older, older); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dst, "app.conf"), []byte("target edit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "local", Type: domain.TransportLocal}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "local", Root: src},
+			{Name: "dst", Transport: "local", Root: dst},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "both", Mode: domain.SyncModeTwoWay, SourceEndpoint: "src", TargetEndpoint: "dst",
+				ConflictStrategy: domain.ConflictKeepBoth, Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(root, "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	runSync := func() *domain.SyncPlan {
+		t.Helper()
+		plan, err := svc.PlanSync(context.Background(), "both")
+		if err != nil {
+			t.Fatalf("PlanSync failed: %v", err)
+		}
+		if err := svc.ExecuteSync(context.Background(), plan); err != nil {
+			t.Fatalf("ExecuteSync failed: %v", err)
+		}
+		return plan
+	}
+
+	plan := runSync()
+	if plan.Actions[0].Type != domain.ActionRename {
+		t.Fatalf("Expected the plan to start with a rename, got %+v", plan.Actions)
+	}
+	copyName := plan.Actions[0].NewPath
+
+	readFile := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", path, err)
+		}
+		return string(data)
+	}
+	if got := readFile(filepath.Join(src, "app.conf")); got != "target edit" {
+		t.Errorf("Expected the newer version on the source, got %q", got)
+	}
+	if got := readFile(filepath.Join(src, copyName)); got != "source edit" {
+		t.Errorf("Expected the older version kept as %s, got %q", copyName, got)
+	}
+
+	// The conflict copy is a new file to the next run and reaches the other side
+	runSync()
+	if got := readFile(filepath.Join(dst, copyName)); got != "source edit" {
+		t.Errorf("Expected the conflict copy on the target, got %q", got)
+	}
+
+	plan = runSync()
+	for _, action := range plan.Actions {
+		if action.Type != domain.ActionSkip {
+			t.Errorf("Expected nothing left to sync, got %+v", action)
+		}
+	}
+}
+
+func TestSyncService_MemoryTransport(t *testing.T) {
+	const store = "service-memory-test"
+	defer memory.DropStore(store)
+
+	src, err := memory.Store(store).Adapter("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	src.WriteFile("docs/a.txt", []byte("alpha"), mtime)
+	src.WriteFile("b.txt", []byte("beta"), mtime)
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "dst", Transport: "sim", Root: "dst"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "push", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	plan, err := svc.PlanSync(context.Background(), "push")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if err := svc.ExecuteSync(context.Background(), plan); err != nil {
+		t.Fatalf("ExecuteSync failed: %v", err)
+	}
+
+	dst, _ := memory.Store(store).Adapter("dst")
+	got := make(map[string]string)
+	for _, e := range dst.Snapshot() {
+		if !e.IsDir() {
+			got[e.Path] = string(e.Content)
+		}
+	}
+	if len(got) != 2 || got["docs/a.txt"] != "alpha" || got["b.txt"] != "beta" {
+		t.Errorf("dst snapshot = %v", got)
+	}
+}
+
+func TestSyncService_MoveDetection(t *testing.T) {
+	const store = "service-move-test"
+	defer memory.DropStore(store)
+
+	src, err := memory.Store(store).Adapter("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	src.WriteFile("old/a.txt", []byte("alpha"), mtime)
+	src.WriteFile("old/b.txt", []byte("beta"), mtime)
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "dst", Transport: "sim", Root: "dst"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "push", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	ctx := context.Background()
+	runSync := func() *domain.SyncPlan {
+		t.Helper()
+		plan, err := svc.PlanSync(ctx, "push")
+		if err != nil {
+			t.Fatalf("PlanSync failed: %v", err)
+		}
+		if err := svc.ExecuteSync(ctx, plan); err != nil {
+			t.Fatalf("ExecuteSync failed: %v", err)
+		}
+		return plan
+	}
+	runSync()
+
+	// Rename the folder; its content must not be transferred again
+	if err := src.Rename(ctx, "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	src.FailOn(memory.OpRead, "new/a.txt", errors.New("content read"))
+	src.FailOn(memory.OpRead, "new/b.txt", errors.New("content read"))
+
+	plan := runSync()
+	if plan.Stats.FilesToMove != 2 || plan.Stats.FilesToCopy != 0 {
+		t.Errorf("Expected 2 moves and no copies, got %+v", plan.Actions)
+	}
+
+	dst, _ := memory.Store(store).Adapter("dst")
+	got := make(map[string]string)
+	for _, e := range dst.Snapshot() {
+		if e.IsDir() {
+			got[e.Path] = "<dir>"
+		} else {
+			got[e.Path] = string(e.Content)
+		}
+	}
+	if len(got) != 3 || got["new"] != "<dir>" || got["new/a.txt"] != "alpha" || got["new/b.txt"] != "beta" {
+		t.Errorf("dst snapshot = %v", got)
+	}
+	if info, _ := dst.Stat(ctx, "new/a.txt"); !info.ModTime.Equal(mtime) {
+		t.Errorf("moved mtime = %v, want %v", info.ModTime, mtime)
+	}
+}
+
+func TestSyncService_PreservesModTime(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "dst")
+	for _, dir := range []string{src, dst} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	if err := os.WriteFile(filepath.Join(src, "old.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "old.txt"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "local", Type: domain.TransportLocal}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "local", Root: src},
+			{Name: "dst", Transport: "local", Root: dst},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "both", Mode: domain.SyncModeTwoWay, SourceEndpoint: "src", TargetEndpoint: "dst",
+				ConflictStrategy: domain.ConflictKeepNewest, Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(root, "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	plan, err := svc.PlanSync(context.Background(), "both")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if err := svc.ExecuteSync(context.Background(), plan); err != nil {
+		t.Fatalf("ExecuteSync failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dst, "old.txt"))
+	if err != nil {
+		t.Fatalf("Expected copy on target: %v", err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("Target mtime = %v, want source mtime %v", info.ModTime(), mtime)
+	}
+
+	// With matching mtimes the next run has nothing to do
+	plan, err = svc.PlanSync(context.Background(), "both")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	for _, action := range plan.Actions {
+		if action.Type != domain.ActionSkip {
+			t.Errorf("Expected no work on the second run, got %+v", action)
+		}
+	}
+}
+
+func TestPairChecksum(t *testing.T) {
+	local := adapter.Capabilities{Checksums: []checksum.Algorithm{checksum.SHA256, checksum.MD5}}
+	drive := adapter.Capabilities{Checksums: []checksum.Algorithm{checksum.MD5, checksum.SHA256}, ServerChecksums: true}
+	s3 := adapter.Capabilities{Checksums: []checksum.Algorithm{checksum.MD5}, ServerChecksums: true}
+	none := adapter.Capabilities{}
+
+	tests := []struct {
+		name      string
+		preferred checksum.Algorithm
+		src, tgt  adapter.Capabilities
+		want      checksum.Algorithm
+	}{
+		{"configured algorithm on both sides", checksum.SHA256, local, drive, checksum.SHA256},
+		{"server hash preferred over configured", checksum.SHA256, local, s3, checksum.MD5},
+		{"server hash on source", checksum.SHA256, s3, local, checksum.MD5},
+		{"server side order wins", checksum.Algorithm("other"), local, drive, checksum.MD5},
+		{"no common algorithm", checksum.SHA256, local, none, checksum.SHA256},
+	}
+	for _, tt := range tests {
+		if got := pairChecksum(tt.preferred, tt.src, tt.tgt); got != tt.want {
+			t.Errorf("%s: pairChecksum = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// md5OnlyAdapter reports a backend that can only produce MD5 checksums
+type md5OnlyAdapter struct {
+	*memory.Adapter
+}
+
+func (a md5OnlyAdapter) Capabilities() adapter.Capabilities {
+	caps := a.Adapter.Capabilities()
+	caps.Checksums = []checksum.Algorithm{checksum.MD5}
+	return caps
+}
+
+func TestSyncService_ChecksumAlgorithmPerRule(t *testing.T) {
+	const store = "service-checksum-per-rule-test"
+	defer memory.DropStore(store)
+
+	src, _ := memory.Store(store).Adapter("src")
+	src.WriteFile("a.txt", []byte("alpha"), time.Now())
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "dst", Transport: "sim", Root: "dst"},
+			{Name: "md5", Transport: "sim", Root: "md5"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "sha", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true},
+			{Name: "md5", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "md5", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks"), ChecksumAlgorithm: string(checksum.SHA256)},
+	}
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	md5Target, _ := memory.Store(store).Adapter("md5")
+	for _, algo := range []checksum.Algorithm{checksum.SHA256, checksum.MD5} {
+		md5Target.SetChecksumAlgorithm(algo)
+		svc.adapters[adapterKey{endpoint: "md5", algorithm: algo}] = md5OnlyAdapter{md5Target}
+		md5Target, _ = memory.Store(store).Adapter("md5")
+	}
+
+	plan, err := svc.PlanSync(context.Background(), "md5")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].SourceInfo.ChecksumAlgorithm != string(checksum.MD5) {
+		t.Fatalf("Expected the md5 rule to list with MD5, got %+v", plan.Actions)
+	}
+
+	// The adapters other rules use keep the configured algorithm
+	shared, err := svc.getAdapter("src")
+	if err != nil {
+		t.Fatalf("getAdapter failed: %v", err)
+	}
+	if info, _ := shared.Stat(context.Background(), "a.txt"); info.ChecksumAlgorithm != string(checksum.SHA256) {
+		t.Errorf("Shared source adapter switched to %q", info.ChecksumAlgorithm)
+	}
+	plan, err = svc.PlanSync(context.Background(), "sha")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].SourceInfo.ChecksumAlgorithm != string(checksum.SHA256) {
+		t.Errorf("Expected the sha rule to list with SHA-256, got %+v", plan.Actions)
+	}
+}
+
+// Run with -race: rules sharing a service are planned from several goroutines
+func TestSyncService_ConcurrentPlans(t *testing.T) {
+	const store = "service-concurrent-plans-test"
+	defer memory.DropStore(store)
+
+	src, _ := memory.Store(store).Adapter("src")
+	src.WriteFile("a.txt", []byte("alpha"), time.Now())
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "one", Transport: "sim", Root: "one"},
+			{Name: "two", Transport: "sim", Root: "two"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "one", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "one", Enabled: true},
+			{Name: "two", Mode: domain.SyncModeTwoWay, SourceEndpoint: "src", TargetEndpoint: "two", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks")},
+	}
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 4; i++ {
+		for _, name := range []string{"one", "two"} {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				if _, err := svc.PlanSync(context.Background(), name); err != nil {
+					errs <- err
+				}
+			}(name)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("PlanSync failed: %v", err)
+	}
+}
+
+func TestSyncService_HoldEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "local", Type: domain.TransportLocal}},
+		Endpoints: []domain.Endpoint{
+			{Name: "a", Transport: "local", Root: filepath.Join(dir, "a")},
+			{Name: "b", Transport: "local", Root: filepath.Join(dir, "b")},
+			{Name: "c", Transport: "local", Root: filepath.Join(dir, "c")},
+			{Name: "d", Transport: "local", Root: filepath.Join(dir, "d")},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "ab", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "a", TargetEndpoint: "b", Enabled: true},
+			{Name: "bc", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "b", TargetEndpoint: "c", Enabled: true},
+			{Name: "cd", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "c", TargetEndpoint: "d", Enabled: true},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(dir, "locks")},
+	}
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	defer svc.Close()
+
+	release, err := svc.holdEndpoints(context.Background(), "ab")
+	if err != nil {
+		t.Fatalf("holdEndpoints failed: %v", err)
+	}
+
+	// A rule without a shared endpoint goes ahead
+	other, err := svc.holdEndpoints(context.Background(), "cd")
+	if err != nil {
+		t.Fatalf("holdEndpoints of an unrelated rule failed: %v", err)
+	}
+	other()
+
+	// A rule sharing b waits until ab lets go
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := svc.holdEndpoints(ctx, "bc"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected bc to wait for ab, got %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		next, err := svc.holdEndpoints(context.Background(), "bc")
+		if err != nil {
+			t.Errorf("holdEndpoints failed: %v", err)
+		}
+		acquired <- next
+	}()
+	release()
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(2 * time.Second):
+		t.Fatal("bc still waiting after ab released its endpoints")
+	}
+}
+
+func TestSyncService_DeletionLimit(t *testing.T) {
+	const store = "service-delete-limit-test"
+	defer memory.DropStore(store)
+
+	src, _ := memory.Store(store).Adapter("src")
+	dst, _ := memory.Store(store).Adapter("dst")
+	ctx := context.Background()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		src.WriteFile(name, []byte(name), time.Now())
+	}
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "dst", Transport: "sim", Root: "dst"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "push", Mode: domain.SyncModeOneWayPush, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true, MaxDeletes: "50%"},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks")},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	daemon, err := NewDaemonService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create daemon service: %v", err)
+	}
+	defer daemon.Close()
+	svc := daemon.syncSvc
+	syncOnce(t, svc, "push")
+
+	// Half the files may go
+	src.Delete(ctx, "a.txt")
+	src.Delete(ctx, "b.txt")
+	syncOnce(t, svc, "push")
+
+	// The rest may not: the source looks emptied
+	src.Delete(ctx, "c.txt")
+	src.Delete(ctx, "d.txt")
+	if err := daemon.newSyncRunner().RunSync(ctx, "push"); err == nil {
+		t.Fatal("Expected the daemon to refuse the plan")
+	}
+	history, err := daemon.stateMgr.GetHistory("push", 1)
+	if err != nil || len(history) != 1 || history[0].Status != "failed" ||
+		!strings.Contains(history[0].Error, "plan deletes 2 of 2 paths") {
+		t.Errorf("Expected a failed record with the reason, got %+v (%v)", history, err)
+	}
+	if exists, _ := dst.Exists(ctx, "c.txt"); !exists {
+		t.Error("Refused plan must not delete anything")
+	}
+
+	plan, err := svc.PlanSync(ctx, "push")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if err := svc.ExecuteSync(ctx, plan); !errors.Is(err, domain.ErrTooManyDeletions) {
+		t.Fatalf("ExecuteSync error = %v, want ErrTooManyDeletions", err)
+	}
+	if _, err := svc.ExecuteSyncWithOptions(ctx, plan, ExecuteOptions{Force: true}); err != nil {
+		t.Fatalf("Forced execution failed: %v", err)
+	}
+	if snap := dst.Snapshot(); len(snap) != 0 {
+		t.Errorf("Forced plan should empty the target, got %+v", snap)
+	}
+
+	// Counts are absolute
+	cfg.Rules[0].MaxDeletes = "1"
+	for _, limit := range []struct {
+		stats domain.SyncPlanStats
+		ok    bool
+	}{
+		{domain.SyncPlanStats{FilesToDelete: 1, TrackedPaths: 1}, true},
+		{domain.SyncPlanStats{FilesToDelete: 2, TrackedPaths: 100}, false},
+	} {
+		if err := cfg.Rules[0].CheckDeletions(limit.stats); (err == nil) != limit.ok {
+			t.Errorf("CheckDeletions(%+v) = %v", limit.stats, err)
+		}
+	}
+
+	for _, invalid := range []string{"0%", "150%", "-1", "many"} {
+		cfg.Rules[0].MaxDeletes = invalid
+		if err := cfg.Validate(); !errors.Is(err, domain.ErrConfigInvalid) {
+			t.Errorf("Validate with max_deletes %q = %v, want ErrConfigInvalid", invalid, err)
+		}
+	}
+}
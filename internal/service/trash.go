@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter"
+	"github.com/Ning0612/Syncrules/internal/domain"
+	"github.com/Ning0612/Syncrules/internal/logger"
+)
+
+// trashEnabled reports whether a rule keeps removed files in a trash
+func trashEnabled(rule *domain.SyncRule) bool {
+	return rule.Trash != nil && rule.Trash.Enabled
+}
+
+// trashRunDir returns the trash directory of a run started at now
+func trashRunDir(now time.Time) string {
+	return domain.TrashDir + "/" + now.UTC().Format(domain.TrashStampLayout)
+}
+
+// moveToTrash moves a file into a run's trash directory on its endpoint
+// A file that is already gone has nothing to keep
+func moveToTrash(ctx context.Context, a adapter.Adapter, runDir, path string) error {
+	err := renameFile(ctx, a, path, runDir+"/"+path)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move %s to trash: %w", path, err)
+	}
+	return nil
+}
+
+// restoreFromTrash moves a file trashed by moveToTrash back to its path,
+// replacing whatever a failed write left there
+// It runs even if ctx is cancelled, since ctx may be why the write failed
+func restoreFromTrash(ctx context.Context, a adapter.Adapter, runDir, path string) error {
+	ctx = context.WithoutCancel(ctx)
+	if _, err := a.Stat(ctx, runDir+"/"+path); errors.Is(err, domain.ErrNotFound) {
+		// The file was already gone when it was to be trashed
+		return nil
+	}
+	if err := a.Delete(ctx, path); err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	return renameFile(ctx, a, runDir+"/"+path, path)
+}
+
+// ListTrash returns the files kept in the trash of both endpoints of a rule,
+// newest run first
+func (s *SyncService) ListTrash(ctx context.Context, ruleName string) ([]domain.TrashEntry, error) {
+	rule, err := s.config.GetRule(ruleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []domain.TrashEntry
+	for _, endpoint := range []string{rule.SourceEndpoint, rule.TargetEndpoint} {
+		a, err := s.getAdapter(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		runs, err := listTrashRuns(ctx, a)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", endpoint, err)
+		}
+		for _, run := range runs {
+			files, err := listFiles(ctx, a, domain.TrashDir+"/"+run.stamp)
+			if err != nil {
+				return nil, fmt.Errorf("endpoint %s: %w", endpoint, err)
+			}
+			for _, f := range files {
+				entries = append(entries, domain.TrashEntry{
+					Endpoint:  endpoint,
+					Stamp:     run.stamp,
+					TrashedAt: run.time,
+					Path:      strings.TrimPrefix(f.Path, domain.TrashDir+"/"+run.stamp+"/"),
+					Size:      f.Size,
+					ModTime:   f.ModTime,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Stamp != entries[j].Stamp {
+			return entries[i].Stamp > entries[j].Stamp
+		}
+		if entries[i].Endpoint != entries[j].Endpoint {
+			return entries[i].Endpoint < entries[j].Endpoint
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// RestoreTrash moves a trashed file back to its original path
+// A file now at that path is moved to the trash first, so a restore can be
+// undone the same way. The restored file is stamped with the current time
+// and leaves the two-way baseline, so the next sync propagates it instead of
+// reverting it
+func (s *SyncService) RestoreTrash(ctx context.Context, ruleName string, entry domain.TrashEntry) error {
+	rule, err := s.config.GetRule(ruleName)
+	if err != nil {
+		return err
+	}
+	if entry.Endpoint != rule.SourceEndpoint && entry.Endpoint != rule.TargetEndpoint {
+		return fmt.Errorf("%w: %s is not an endpoint of rule %s", domain.ErrEndpointNotFound, entry.Endpoint, ruleName)
+	}
+	if entry.Stamp == "" || entry.Path == "" {
+		return fmt.Errorf("%w: incomplete trash entry", domain.ErrNotFound)
+	}
+
+	locks, err := s.ruleLocks(ruleName)
+	if err != nil {
+		return err
+	}
+	if err := locks.Acquire(ruleName); err != nil {
+		return fmt.Errorf("failed to acquire sync lock: %w", err)
+	}
+	defer func() {
+		if err := locks.Release(); err != nil {
+			logger.Get().Error("failed to release sync lock", "rule", ruleName, "error", err)
+		}
+	}()
+
+	a, err := s.getAdapter(entry.Endpoint)
+	if err != nil {
+		return err
+	}
+	if _, err := a.Stat(ctx, entry.TrashPath()); err != nil {
+		return err
+	}
+
+	current, err := a.Stat(ctx, entry.Path)
+	switch {
+	case err == nil && current.IsDir():
+		return fmt.Errorf("%w: %s is a directory", domain.ErrAlreadyExists, entry.Path)
+	case err == nil:
+		// The current version goes to a run of its own, even within the same millisecond
+		runDir := trashRunDir(time.Now())
+		if runDir == domain.TrashDir+"/"+entry.Stamp {
+			runDir = trashRunDir(time.Now().Add(time.Millisecond))
+		}
+		if err := moveToTrash(ctx, a, runDir, entry.Path); err != nil {
+			return err
+		}
+	case !errors.Is(err, domain.ErrNotFound):
+		return err
+	}
+
+	if err := renameFile(ctx, a, entry.TrashPath(), entry.Path); err != nil {
+		return err
+	}
+	if err := preserveModTime(ctx, a, entry.Path, time.Now()); err != nil {
+		return err
+	}
+
+	if rule.Mode == domain.SyncModeTwoWay {
+		baseline, err := s.stateMgr.GetBaseline(ruleName)
+		if err != nil {
+			return err
+		}
+		if _, ok := baseline[entry.Path]; ok {
+			delete(baseline, entry.Path)
+			if err := s.stateMgr.SaveBaseline(ruleName, baseline); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Get().Info("restored from trash",
+		"rule", ruleName,
+		"endpoint", entry.Endpoint,
+		"stamp", entry.Stamp,
+		"path", entry.Path,
+	)
+	return nil
+}
+
+// trashRun is a timestamp directory in an endpoint's trash
+type trashRun struct {
+	stamp string
+	time  time.Time
+}
+
+// listTrashRuns returns the runs in an endpoint's trash, newest first
+// Directories not named by TrashStampLayout are not ours and are left out
+func listTrashRuns(ctx context.Context, a adapter.Adapter) ([]trashRun, error) {
+	items, err := a.List(ctx, domain.TrashDir)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []trashRun
+	for _, item := range items {
+		stamp := strings.TrimPrefix(item.Path, domain.TrashDir+"/")
+		t, err := time.Parse(domain.TrashStampLayout, stamp)
+		if err != nil || !item.IsDir() {
+			continue
+		}
+		runs = append(runs, trashRun{stamp: stamp, time: t})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].stamp > runs[j].stamp
+	})
+	return runs, nil
+}
+
+// pruneTrash removes the runs in an endpoint's trash that fall outside the
+// rule's retention: older than MaxAge, or beyond the newest MaxCount
+func pruneTrash(ctx context.Context, a adapter.Adapter, cfg *domain.TrashConfig, now time.Time) error {
+	var maxAge time.Duration
+	if cfg.MaxAge != "" {
+		d, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return err
+		}
+		maxAge = d
+	}
+	if maxAge == 0 && cfg.MaxCount == 0 {
+		return nil
+	}
+
+	runs, err := listTrashRuns(ctx, a)
+	if err != nil {
+		return err
+	}
+	for i, run := range runs {
+		expired := maxAge > 0 && now.Sub(run.time) > maxAge
+		if !expired && (cfg.MaxCount == 0 || i < cfg.MaxCount) {
+			continue
+		}
+		if err := removeTree(ctx, a, domain.TrashDir+"/"+run.stamp); err != nil {
+			return fmt.Errorf("failed to remove trash run %s: %w", run.stamp, err)
+		}
+	}
+	return nil
+}
+
+// listFiles returns every file beneath dir
+func listFiles(ctx context.Context, a adapter.Adapter, dir string) ([]domain.FileInfo, error) {
+	items, err := a.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []domain.FileInfo
+	for _, item := range items {
+		if !item.IsDir() {
+			files = append(files, item)
+			continue
+		}
+		sub, err := listFiles(ctx, a, item.Path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// removeTree deletes dir and everything beneath it
+func removeTree(ctx context.Context, a adapter.Adapter, dir string) error {
+	items, err := a.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.IsDir() {
+			err = removeTree(ctx, a, item.Path)
+		} else {
+			err = a.Delete(ctx, item.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return a.Delete(ctx, dir)
+}
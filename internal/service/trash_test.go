@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/adapter/memory"
+	"github.com/Ning0612/Syncrules/internal/config"
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// newTrashFixture creates a push rule with a trash between two memory endpoints
+func newTrashFixture(t *testing.T, mode domain.SyncMode) (*SyncService, *memory.Adapter, *memory.Adapter) {
+	t.Helper()
+
+	store := "trash-" + t.Name()
+	t.Cleanup(func() { memory.DropStore(store) })
+	src, _ := memory.Store(store).Adapter("src")
+	dst, _ := memory.Store(store).Adapter("dst")
+
+	cfg := &config.Config{
+		Transports: []domain.Transport{{Name: "sim", Type: memory.TransportType, Config: map[string]string{"store": store}}},
+		Endpoints: []domain.Endpoint{
+			{Name: "src", Transport: "sim", Root: "src"},
+			{Name: "dst", Transport: "sim", Root: "dst"},
+		},
+		Rules: []domain.SyncRule{
+			{Name: "safe", Mode: mode, SourceEndpoint: "src", TargetEndpoint: "dst", Enabled: true,
+				ConflictStrategy: domain.ConflictKeepNewest, Trash: &domain.TrashConfig{Enabled: true}},
+		},
+		Settings: config.Settings{LockPath: filepath.Join(t.TempDir(), "locks")},
+	}
+
+	svc, err := NewSyncService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sync service: %v", err)
+	}
+	t.Cleanup(func() { svc.Close() })
+	return svc, src, dst
+}
+
+func readMemory(t *testing.T, a *memory.Adapter, path string) string {
+	t.Helper()
+	r, err := a.Read(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Read %s failed: %v", path, err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
+
+func TestSyncService_TrashKeepsRemovedFiles(t *testing.T) {
+	svc, src, dst := newTrashFixture(t, domain.SyncModeOneWayPush)
+	ctx := context.Background()
+
+	old := time.Now().Add(-time.Hour)
+	src.WriteFile("docs/report.txt", []byte("first draft"), old)
+	src.WriteFile("notes.txt", []byte("notes"), old)
+	syncOnce(t, svc, "safe")
+
+	src.WriteFile("docs/report.txt", []byte("final version"), time.Now())
+	src.Delete(ctx, "notes.txt")
+	syncOnce(t, svc, "safe")
+
+	if got := readMemory(t, dst, "docs/report.txt"); got != "final version" {
+		t.Errorf("report = %q, want the new version", got)
+	}
+	if exists, _ := dst.Exists(ctx, "notes.txt"); exists {
+		t.Error("notes.txt should be gone from its original path")
+	}
+
+	entries, err := svc.ListTrash(ctx, "safe")
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "docs/report.txt" || entries[1].Path != "notes.txt" {
+		t.Fatalf("trash = %+v", entries)
+	}
+	if entries[0].Endpoint != "dst" || entries[0].Stamp != entries[1].Stamp || entries[0].TrashedAt.IsZero() {
+		t.Errorf("entry = %+v", entries[0])
+	}
+	if got := readMemory(t, dst, entries[0].TrashPath()); got != "first draft" {
+		t.Errorf("trashed report = %q, want the overwritten version", got)
+	}
+
+	// The trash is not part of the synced tree
+	plan := syncOnce(t, svc, "safe")
+	if len(plan.Actions) != 0 {
+		t.Errorf("Expected nothing to sync, got %+v", plan.Actions)
+	}
+
+	// Restoring over the current version keeps that version in the trash
+	if err := svc.RestoreTrash(ctx, "safe", entries[0]); err != nil {
+		t.Fatalf("RestoreTrash failed: %v", err)
+	}
+	if err := svc.RestoreTrash(ctx, "safe", entries[1]); err != nil {
+		t.Fatalf("RestoreTrash failed: %v", err)
+	}
+	if got := readMemory(t, dst, "docs/report.txt"); got != "first draft" {
+		t.Errorf("restored report = %q", got)
+	}
+	if got := readMemory(t, dst, "notes.txt"); got != "notes" {
+		t.Errorf("restored notes = %q", got)
+	}
+	entries, _ = svc.ListTrash(ctx, "safe")
+	if len(entries) != 1 || entries[0].Path != "docs/report.txt" || readMemory(t, dst, entries[0].TrashPath()) != "final version" {
+		t.Errorf("trash after restore = %+v", entries)
+	}
+	if err := svc.RestoreTrash(ctx, "safe", domain.TrashEntry{Endpoint: "src", Stamp: "x", Path: "y"}); err == nil {
+		t.Error("Restoring a missing entry should fail")
+	}
+}
+
+func TestSyncService_TrashFailedWriteKeepsOriginal(t *testing.T) {
+	svc, src, dst := newTrashFixture(t, domain.SyncModeOneWayPush)
+	ctx := context.Background()
+
+	src.WriteFile("report.txt", []byte("first draft"), time.Now().Add(-time.Hour))
+	syncOnce(t, svc, "safe")
+
+	src.WriteFile("report.txt", []byte("final version"), time.Now())
+	dst.FailOn(memory.OpWrite, "report.txt", errors.New("connection reset"))
+	plan, err := svc.PlanSync(ctx, "safe")
+	if err != nil {
+		t.Fatalf("PlanSync failed: %v", err)
+	}
+	if err := svc.ExecuteSync(ctx, plan); err == nil {
+		t.Fatal("Expected the failed write to fail the sync")
+	}
+
+	if got := readMemory(t, dst, "report.txt"); got != "first draft" {
+		t.Errorf("report = %q, want the original kept in place", got)
+	}
+	entries, err := svc.ListTrash(ctx, "safe")
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing left in trash, got %+v", entries)
+	}
+}
+
+func TestSyncService_RestoreTwoWay(t *testing.T) {
+	svc, src, dst := newTrashFixture(t, domain.SyncModeTwoWay)
+	ctx := context.Background()
+
+	src.WriteFile("keep.txt", []byte("precious"), time.Now().Add(-time.Hour))
+	syncOnce(t, svc, "safe")
+
+	// Deleted on target, propagated to source: both copies end up in a trash
+	dst.Delete(ctx, "keep.txt")
+	syncOnce(t, svc, "safe")
+	entries, err := svc.ListTrash(ctx, "safe")
+	if err != nil || len(entries) != 1 || entries[0].Endpoint != "src" {
+		t.Fatalf("trash = %+v, %v", entries, err)
+	}
+
+	// A restored file is synced again rather than deleted as before
+	if err := svc.RestoreTrash(ctx, "safe", entries[0]); err != nil {
+		t.Fatalf("RestoreTrash failed: %v", err)
+	}
+	syncOnce(t, svc, "safe")
+	if got := readMemory(t, dst, "keep.txt"); got != "precious" {
+		t.Errorf("target = %q, want the restored file", got)
+	}
+}
+
+func TestPruneTrash(t *testing.T) {
+	a := memory.New()
+	ctx := context.Background()
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 48 * time.Hour} {
+		dir := trashRunDir(now.Add(-age))
+		a.Write(ctx, dir+"/sub/file.txt", strings.NewReader("x"))
+	}
+	a.Write(ctx, domain.TrashDir+"/unrelated/file.txt", strings.NewReader("x"))
+
+	stamps := func() []string {
+		runs, err := listTrashRuns(ctx, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s []string
+		for _, run := range runs {
+			s = append(s, run.stamp)
+		}
+		return s
+	}
+
+	if err := pruneTrash(ctx, a, &domain.TrashConfig{Enabled: true, MaxAge: "24h"}, now); err != nil {
+		t.Fatalf("pruneTrash failed: %v", err)
+	}
+	if got := stamps(); len(got) != 2 {
+		t.Errorf("after max_age, runs = %v", got)
+	}
+
+	if err := pruneTrash(ctx, a, &domain.TrashConfig{Enabled: true, MaxCount: 1}, now); err != nil {
+		t.Fatalf("pruneTrash failed: %v", err)
+	}
+	if got := stamps(); len(got) != 1 || got[0] != now.Add(-time.Hour).Format(domain.TrashStampLayout) {
+		t.Errorf("after max_count, runs = %v", got)
+	}
+	if exists, _ := a.Exists(ctx, domain.TrashDir+"/unrelated/file.txt"); !exists {
+		t.Error("directories not created by syncs should be left alone")
+	}
+}
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// SaveBaseline replaces the stored baseline of a rule with the given tree
+// The baseline is the last synced state used by two-way planning to detect deletions
+func (m *Manager) SaveBaseline(ruleName string, files map[string]domain.FileInfo) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin baseline transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM baselines WHERE rule_name = ?`, ruleName); err != nil {
+		return fmt.Errorf("failed to clear baseline: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO baselines (rule_name, path, file_type, size, mod_time, checksum, checksum_algorithm, etag)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare baseline insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for path, f := range files {
+		if _, err := stmt.Exec(ruleName, path, int(f.Type), f.Size, f.ModTime, f.Checksum, f.ChecksumAlgorithm, f.ETag); err != nil {
+			return fmt.Errorf("failed to save baseline entry %s: %w", path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit baseline: %w", err)
+	}
+
+	return nil
+}
+
+// GetBaseline retrieves the stored baseline of a rule keyed by path
+// Returns an empty map if the rule has never been synced
+func (m *Manager) GetBaseline(ruleName string) (map[string]domain.FileInfo, error) {
+	query := `
+		SELECT path, file_type, size, mod_time, checksum, checksum_algorithm, etag
+		FROM baselines
+		WHERE rule_name = ?
+	`
+
+	rows, err := m.db.Query(query, ruleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query baseline: %w", err)
+	}
+	defer rows.Close()
+
+	baseline := make(map[string]domain.FileInfo)
+	for rows.Next() {
+		var (
+			f        domain.FileInfo
+			fileType int
+			modTime  time.Time
+		)
+		if err := rows.Scan(&f.Path, &fileType, &f.Size, &modTime, &f.Checksum, &f.ChecksumAlgorithm, &f.ETag); err != nil {
+			return nil, fmt.Errorf("failed to scan baseline entry: %w", err)
+		}
+		f.Type = domain.FileType(fileType)
+		f.ModTime = modTime
+		baseline[f.Path] = f
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating baseline: %w", err)
+	}
+
+	return baseline, nil
+}
+
+// DeleteBaseline removes the stored baseline of a rule
+// The next two-way sync of the rule behaves like a first sync
+func (m *Manager) DeleteBaseline(ruleName string) error {
+	if _, err := m.db.Exec(`DELETE FROM baselines WHERE rule_name = ?`, ruleName); err != nil {
+		return fmt.Errorf("failed to delete baseline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
+)
+
+// ConflictRecord is a manual conflict kept until the user resolves it
+type ConflictRecord struct {
+	ID        int64
+	RuleName  string
+	Path      string
+	Direction domain.SyncDirection
+	Reason    string
+
+	// Source and Target are the versions seen when the conflict was last detected
+	// A deleted side is a tombstone (IsDeleted)
+	Source *domain.FileInfo
+	Target *domain.FileInfo
+
+	// Resolution is empty while the conflict is unresolved
+	Resolution domain.ConflictResolution
+
+	DetectedAt time.Time
+	ResolvedAt time.Time
+}
+
+// Resolved reports whether the user has decided how to resolve the conflict
+func (r ConflictRecord) Resolved() bool {
+	return r.Resolution != ""
+}
+
+// Action returns the conflict as the plan action it was recorded from
+func (r ConflictRecord) Action() domain.SyncAction {
+	return domain.SyncAction{
+		Type:       domain.ActionConflict,
+		Direction:  r.Direction,
+		Path:       r.Path,
+		SourceInfo: r.Source,
+		TargetInfo: r.Target,
+		Reason:     r.Reason,
+	}
+}
+
+// RecordConflicts stores the conflicts of a rule's latest plan
+// Unresolved entries are refreshed with the latest versions, resolved ones are
+// left untouched, and entries of paths that are neither conflicting nor in keep
+// any more are removed
+func (m *Manager) RecordConflicts(ruleName string, conflicts []domain.SyncAction, keep map[string]bool) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin conflict transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current := make(map[string]bool, len(conflicts))
+	now := time.Now()
+	for _, action := range conflicts {
+		current[action.Path] = true
+
+		source, err := encodeFileInfo(action.SourceInfo)
+		if err != nil {
+			return err
+		}
+		target, err := encodeFileInfo(action.TargetInfo)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO conflicts (rule_name, path, direction, reason, source_info, target_info, detected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (rule_name, path) DO UPDATE SET
+				direction = excluded.direction,
+				reason = excluded.reason,
+				source_info = excluded.source_info,
+				target_info = excluded.target_info
+			WHERE resolution = ''
+		`, ruleName, action.Path, int(action.Direction), action.Reason, source, target, now)
+		if err != nil {
+			return fmt.Errorf("failed to record conflict %s: %w", action.Path, err)
+		}
+	}
+
+	rows, err := tx.Query(`SELECT path FROM conflicts WHERE rule_name = ?`, ruleName)
+	if err != nil {
+		return fmt.Errorf("failed to query conflicts: %w", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan conflict: %w", err)
+		}
+		if !current[path] && !keep[path] {
+			stale = append(stale, path)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating conflicts: %w", err)
+	}
+
+	for _, path := range stale {
+		if _, err := tx.Exec(`DELETE FROM conflicts WHERE rule_name = ? AND path = ?`, ruleName, path); err != nil {
+			return fmt.Errorf("failed to remove conflict %s: %w", path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit conflicts: %w", err)
+	}
+	return nil
+}
+
+// ListConflicts returns the recorded conflicts of a rule ("" for all rules)
+// ordered by rule and path
+func (m *Manager) ListConflicts(ruleName string) ([]ConflictRecord, error) {
+	query := conflictColumns + ` FROM conflicts`
+	var args []interface{}
+	if ruleName != "" {
+		query += ` WHERE rule_name = ?`
+		args = append(args, ruleName)
+	}
+	query += ` ORDER BY rule_name, path`
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var records []ConflictRecord
+	for rows.Next() {
+		record, err := scanConflict(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating conflicts: %w", err)
+	}
+
+	return records, nil
+}
+
+// GetConflict returns a recorded conflict by ID
+// Returns domain.ErrNotFound if there is no such conflict
+func (m *Manager) GetConflict(id int64) (*ConflictRecord, error) {
+	row := m.db.QueryRow(conflictColumns+` FROM conflicts WHERE id = ?`, id)
+	record, err := scanConflict(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: conflict %d", domain.ErrNotFound, id)
+	}
+	return record, err
+}
+
+// ResolveConflict records the user's resolution of a conflict
+// Returns domain.ErrNotFound if there is no such conflict
+func (m *Manager) ResolveConflict(id int64, resolution domain.ConflictResolution) error {
+	res, err := m.db.Exec(`UPDATE conflicts SET resolution = ?, resolved_at = ? WHERE id = ?`,
+		string(resolution), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve conflict: %w", err)
+	}
+	return requireAffected(res, id)
+}
+
+// ReopenConflict clears the resolution of a conflict so it is reported again
+// Returns domain.ErrNotFound if there is no such conflict
+func (m *Manager) ReopenConflict(id int64) error {
+	res, err := m.db.Exec(`UPDATE conflicts SET resolution = '', resolved_at = NULL WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to reopen conflict: %w", err)
+	}
+	return requireAffected(res, id)
+}
+
+// DeleteConflict removes the recorded conflict of a rule's path
+// No error if there is none
+func (m *Manager) DeleteConflict(ruleName, path string) error {
+	if _, err := m.db.Exec(`DELETE FROM conflicts WHERE rule_name = ? AND path = ?`, ruleName, path); err != nil {
+		return fmt.Errorf("failed to delete conflict: %w", err)
+	}
+	return nil
+}
+
+// conflictColumns selects the fields read by scanConflict
+const conflictColumns = `
+	SELECT id, rule_name, path, direction, reason, source_info, target_info, resolution, detected_at, resolved_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanConflict reads a conflict record selected with conflictColumns
+func scanConflict(row rowScanner) (*ConflictRecord, error) {
+	var (
+		record     ConflictRecord
+		direction  int
+		reason     sql.NullString
+		source     sql.NullString
+		target     sql.NullString
+		resolution string
+		resolvedAt sql.NullTime
+	)
+	err := row.Scan(&record.ID, &record.RuleName, &record.Path, &direction, &reason,
+		&source, &target, &resolution, &record.DetectedAt, &resolvedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan conflict: %w", err)
+	}
+
+	record.Direction = domain.SyncDirection(direction)
+	record.Reason = reason.String
+	record.Resolution = domain.ConflictResolution(resolution)
+	record.ResolvedAt = resolvedAt.Time
+	if record.Source, err = decodeFileInfo(source); err != nil {
+		return nil, err
+	}
+	if record.Target, err = decodeFileInfo(target); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// encodeFileInfo serializes one side of a conflict (NULL if absent)
+func encodeFileInfo(info *domain.FileInfo) (sql.NullString, error) {
+	if info == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode file info: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeFileInfo parses one side of a conflict stored by encodeFileInfo
+func decodeFileInfo(data sql.NullString) (*domain.FileInfo, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var info domain.FileInfo
+	if err := json.Unmarshal([]byte(data.String), &info); err != nil {
+		return nil, fmt.Errorf("failed to decode file info: %w", err)
+	}
+	return &info, nil
+}
+
+// requireAffected maps an update of no rows to domain.ErrNotFound
+func requireAffected(res sql.Result, id int64) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check update: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: conflict %d", domain.ErrNotFound, id)
+	}
+	return nil
+}
@@ -82,6 +82,32 @@ func (m *Manager) initSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_executions_rule_time ON executions(rule_name, start_time DESC);
 	CREATE INDEX IF NOT EXISTS idx_executions_status ON executions(status);
+
+	CREATE TABLE IF NOT EXISTS baselines (
+		rule_name TEXT NOT NULL,
+		path TEXT NOT NULL,
+		file_type INTEGER NOT NULL,
+		size INTEGER DEFAULT 0,
+		mod_time TIMESTAMP,
+		checksum TEXT,
+		checksum_algorithm TEXT,
+		etag TEXT,
+		PRIMARY KEY (rule_name, path)
+	);
+
+	CREATE TABLE IF NOT EXISTS conflicts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule_name TEXT NOT NULL,
+		path TEXT NOT NULL,
+		direction INTEGER NOT NULL DEFAULT 0,
+		reason TEXT,
+		source_info TEXT,
+		target_info TEXT,
+		resolution TEXT NOT NULL DEFAULT '',
+		detected_at TIMESTAMP NOT NULL,
+		resolved_at TIMESTAMP,
+		UNIQUE (rule_name, path)
+	);
 	`
 
 	_, err := m.db.Exec(schema)
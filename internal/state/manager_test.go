@@ -1,10 +1,13 @@
 package state
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/Ning0612/Syncrules/internal/domain"
 )
 
 func TestNewManager(t *testing.T) {
@@ -319,3 +322,142 @@ func TestGetAllHistory_InvalidLimit(t *testing.T) {
 		t.Error("Expected error for limit=-1, got nil")
 	}
 }
+
+func TestSaveAndGetBaseline(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	now := time.Now().Truncate(time.Second)
+	files := map[string]domain.FileInfo{
+		"dir":       {Path: "dir", Type: domain.FileTypeDirectory, ModTime: now},
+		"dir/a.txt": {Path: "dir/a.txt", Type: domain.FileTypeRegular, Size: 42, ModTime: now, Checksum: "abc"},
+	}
+
+	if err := manager.SaveBaseline("rule1", files); err != nil {
+		t.Fatalf("Failed to save baseline: %v", err)
+	}
+
+	baseline, err := manager.GetBaseline("rule1")
+	if err != nil {
+		t.Fatalf("Failed to get baseline: %v", err)
+	}
+	if len(baseline) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(baseline))
+	}
+	got := baseline["dir/a.txt"]
+	if got.Size != 42 || got.Checksum != "abc" || !got.IsFile() || !got.ModTime.Equal(now) {
+		t.Errorf("Unexpected baseline entry: %+v", got)
+	}
+	if !baseline["dir"].IsDir() {
+		t.Errorf("Expected dir entry to be a directory")
+	}
+
+	// Saving again replaces the previous baseline
+	if err := manager.SaveBaseline("rule1", map[string]domain.FileInfo{}); err != nil {
+		t.Fatalf("Failed to save empty baseline: %v", err)
+	}
+	baseline, err = manager.GetBaseline("rule1")
+	if err != nil {
+		t.Fatalf("Failed to get baseline: %v", err)
+	}
+	if len(baseline) != 0 {
+		t.Errorf("Expected empty baseline, got %d entries", len(baseline))
+	}
+}
+
+func TestGetBaseline_UnknownRule(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	baseline, err := manager.GetBaseline("missing")
+	if err != nil {
+		t.Fatalf("Failed to get baseline: %v", err)
+	}
+	if len(baseline) != 0 {
+		t.Errorf("Expected empty baseline, got %d entries", len(baseline))
+	}
+}
+
+func TestRecordAndResolveConflicts(t *testing.T) {
+	manager, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	now := time.Now().Truncate(time.Second)
+	src := domain.FileInfo{Path: "a.txt", Size: 1, ModTime: now}
+	deleted := domain.FileInfo{Path: "b.txt", Size: 2, ModTime: now}.Tombstone()
+	conflicts := []domain.SyncAction{
+		{Type: domain.ActionConflict, Path: "a.txt", SourceInfo: &src, TargetInfo: &src, Reason: "manual resolution required"},
+		{Type: domain.ActionConflict, Path: "b.txt", SourceInfo: &deleted, TargetInfo: &src, Reason: "deleted on source, modified on target"},
+	}
+
+	if err := manager.RecordConflicts("rule", conflicts, nil); err != nil {
+		t.Fatalf("RecordConflicts failed: %v", err)
+	}
+	// Recording again must not duplicate entries
+	if err := manager.RecordConflicts("rule", conflicts, nil); err != nil {
+		t.Fatalf("RecordConflicts failed: %v", err)
+	}
+
+	records, err := manager.ListConflicts("rule")
+	if err != nil {
+		t.Fatalf("ListConflicts failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %d", len(records))
+	}
+	b := records[1]
+	if b.Path != "b.txt" || b.Source == nil || !b.Source.IsDeleted || !b.Target.ModTime.Equal(now) {
+		t.Errorf("File infos not round-tripped: %+v", b)
+	}
+	if b.Resolved() {
+		t.Error("New conflicts should be unresolved")
+	}
+
+	if err := manager.ResolveConflict(b.ID, domain.ResolveTakeTarget); err != nil {
+		t.Fatalf("ResolveConflict failed: %v", err)
+	}
+	got, err := manager.GetConflict(b.ID)
+	if err != nil {
+		t.Fatalf("GetConflict failed: %v", err)
+	}
+	if got.Resolution != domain.ResolveTakeTarget || got.ResolvedAt.IsZero() {
+		t.Errorf("Expected take_target resolution, got %+v", got)
+	}
+
+	// Resolved entries are kept, entries no longer conflicting are dropped
+	if err := manager.RecordConflicts("rule", nil, map[string]bool{"b.txt": true}); err != nil {
+		t.Fatalf("RecordConflicts failed: %v", err)
+	}
+	records, _ = manager.ListConflicts("")
+	if len(records) != 1 || records[0].Path != "b.txt" || records[0].Resolution != domain.ResolveTakeTarget {
+		t.Errorf("Expected only the resolved conflict to remain, got %+v", records)
+	}
+
+	if err := manager.ReopenConflict(b.ID); err != nil {
+		t.Fatalf("ReopenConflict failed: %v", err)
+	}
+	if got, _ := manager.GetConflict(b.ID); got.Resolved() {
+		t.Error("Expected the conflict to be unresolved after reopening")
+	}
+
+	if err := manager.DeleteConflict("rule", "b.txt"); err != nil {
+		t.Fatalf("DeleteConflict failed: %v", err)
+	}
+	if _, err := manager.GetConflict(b.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := manager.ResolveConflict(b.ID, domain.ResolveIgnore); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound resolving a missing conflict, got %v", err)
+	}
+}